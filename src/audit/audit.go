@@ -0,0 +1,44 @@
+// Package audit records who did what. Refused requests go to an audit log,
+// kept separately from the request log so the entries can be shipped and kept
+// on their own, and like changes that went through become events in the
+// hash-chained audit trail.
+package audit
+
+import (
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	"finance_app/src/auth"
+	"finance_app/src/models"
+	"finance_app/src/repositories"
+)
+
+// Logger receives audit entries. Entries logged with a request context carry
+// its request and trace IDs when the logger has the tracing hook.
+var Logger = logrus.StandardLogger()
+
+// AccessDenied records that principal was refused permission for r, in the
+// audit log and in trail. The request is refused either way, so failing to
+// append the event is only logged.
+func AccessDenied(r *http.Request, trail repositories.AuditRepository, principal auth.Principal, permission auth.Permission) {
+	Logger.WithContext(r.Context()).WithFields(logrus.Fields{
+		"audit":       "access_denied",
+		"actor":       principal.Subject,
+		"roles":       principal.Roles,
+		"api_key_id":  principal.APIKeyID,
+		"permission":  string(permission),
+		"method":      r.Method,
+		"path":        r.URL.Path,
+		"remote_addr": r.RemoteAddr,
+	}).Warn("Access denied")
+
+	if trail == nil {
+		return
+	}
+	event := NewEvent(r, models.AuditAccessDenied)
+	event.Permission = string(permission)
+	if err := trail.Append(r.Context(), event); err != nil {
+		logrus.WithContext(r.Context()).Error("Failed to record access denied event: ", err)
+	}
+}
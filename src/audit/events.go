@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"finance_app/src/auth"
+	"finance_app/src/models"
+)
+
+// NewEvent starts the audit event of a change r made, filled in with who made
+// it, from where and through which endpoint. The caller adds what changed.
+func NewEvent(r *http.Request, action string, balances ...models.BalanceSnapshot) *models.AuditEvent {
+	principal, _ := auth.FromContext(r.Context())
+	actor := principal.Subject
+	if actor == "" {
+		actor = "anonymous"
+	}
+
+	return &models.AuditEvent{
+		Actor:     actor,
+		RequestID: middleware.GetReqID(r.Context()),
+		ClientIP:  ClientIP(r),
+		Endpoint:  r.Method + " " + endpoint(r),
+		Action:    action,
+		Balances:  balances,
+	}
+}
+
+// ClientIP returns the address r came from, without its port
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// endpoint returns the route pattern r was routed to, or its path when it
+// did not go through a chi router or has only been routed as far as a
+// subrouter's middleware
+func endpoint(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return r.URL.Path
+	}
+	pattern := rctx.RoutePattern()
+	if pattern == "" || strings.HasSuffix(pattern, "/*") {
+		return r.URL.Path
+	}
+	if len(pattern) > 1 {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	return pattern
+}
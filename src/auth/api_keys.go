@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// Scopes an API key can be granted
+const (
+	// ScopeReadAccounts reads accounts and their transactions
+	ScopeReadAccounts = "read:accounts"
+	// ScopeWriteTransactions creates deposits, withdrawals and transfers
+	ScopeWriteTransactions = "write:transactions"
+	// ScopeAdmin grants everything, as the admin role does
+	ScopeAdmin = "admin"
+)
+
+// apiKeyPrefix marks API keys so secret scanners can spot leaked ones
+const apiKeyPrefix = "fin_"
+
+// APIKeyPrefixLength is how much of a key is kept in the clear to identify it
+const APIKeyPrefixLength = len(apiKeyPrefix) + 8
+
+// ValidScope reports whether scope is one an API key can be granted
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeReadAccounts, ScopeWriteTransactions, ScopeAdmin:
+		return true
+	default:
+		return false
+	}
+}
+
+// GenerateAPIKey returns a new random API key
+func GenerateAPIKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// HashAPIKey returns the hash an API key is stored and looked up by. Keys
+// are random, so a fast hash is enough to keep a leaked table useless.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms a Verifier accepts
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+)
+
+// clockSkew is how far the clocks of the issuer and this server may drift
+// apart when checking exp and nbf
+const clockSkew = 30 * time.Second
+
+// ErrInvalidToken is returned for every token that must be rejected; the
+// wrapped message says why
+var ErrInvalidToken = errors.New("invalid token")
+
+// Options are the keys and expected claims of a Verifier. At least one key
+// is required; a token is only accepted with the algorithm of a configured
+// key, so an RS256 public key can never be used as an HS256 secret.
+type Options struct {
+	// HMACSecret verifies HS256 tokens
+	HMACSecret []byte
+	// RSAPublicKey verifies RS256 tokens
+	RSAPublicKey *rsa.PublicKey
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string
+	Audience string
+}
+
+// Verifier checks JWT bearer tokens
+type Verifier struct {
+	opts   Options
+	parser *jwt.Parser
+}
+
+// NewVerifier returns a Verifier for opts
+func NewVerifier(opts Options) (*Verifier, error) {
+	var methods []string
+	if len(opts.HMACSecret) > 0 {
+		methods = append(methods, AlgHS256)
+	}
+	if opts.RSAPublicKey != nil {
+		methods = append(methods, AlgRS256)
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("an HMAC secret or RSA public key is required")
+	}
+
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithLeeway(clockSkew),
+		jwt.WithExpirationRequired(),
+	}
+	if opts.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(opts.Audience))
+	}
+	return &Verifier{opts: opts, parser: jwt.NewParser(parserOptions...)}, nil
+}
+
+// LoadRSAPublicKey reads a PEM encoded RSA public key, in PKIX or PKCS #1
+// form, or the key of a PEM certificate
+func LoadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RSA public key: %w", err)
+	}
+
+	key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse RSA public key in %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// claims are the registered claims the Verifier checks, plus roles
+type claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles"`
+}
+
+// Verify checks the signature and claims of a compact JWT and returns the
+// principal it was issued for. Tokens must carry sub and exp.
+func (v *Verifier) Verify(token string) (Principal, error) {
+	var c claims
+	if _, err := v.parser.ParseWithClaims(token, &c, v.key); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if c.Subject == "" {
+		return Principal{}, fmt.Errorf("%w: missing sub", ErrInvalidToken)
+	}
+
+	return Principal{Subject: c.Subject, Roles: c.Roles}, nil
+}
+
+// key returns the key for the algorithm of token. The parser has already
+// checked the algorithm is one a key was configured for.
+func (v *Verifier) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case AlgHS256:
+		return v.opts.HMACSecret, nil
+	case AlgRS256:
+		return v.opts.RSAPublicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", token.Method.Alg())
+	}
+}
@@ -0,0 +1,112 @@
+package auth
+
+// Permission is an action a principal may be allowed to take
+type Permission string
+
+const (
+	PermissionReadAccounts        Permission = "accounts:read"
+	PermissionCreateAccounts      Permission = "accounts:create"
+	PermissionUpdateAccounts      Permission = "accounts:update"
+	PermissionFreezeAccounts      Permission = "accounts:freeze"
+	PermissionUnfreezeAccounts    Permission = "accounts:unfreeze"
+	PermissionCloseAccounts       Permission = "accounts:close"
+	PermissionReadTransactions    Permission = "transactions:read"
+	PermissionCreateTransactions  Permission = "transactions:create"
+	PermissionReverseTransactions Permission = "transactions:reverse"
+	PermissionCheckLedger         Permission = "ledger:check"
+	PermissionManageAPIKeys       Permission = "api_keys:manage"
+	PermissionReadAudit           Permission = "audit:read"
+	PermissionReadMetrics         Permission = "metrics:read"
+)
+
+// Staff roles. Callers with none of them are customers, who may only act on
+// the accounts they own.
+const (
+	// RoleSupport views every account but cannot move money or change them
+	RoleSupport = "support"
+	// RoleOperator is support that can also freeze and unfreeze accounts
+	RoleOperator = "operator"
+)
+
+// customerPermissions lets customers freeze their own accounts, but only
+// staff may unfreeze one, so a freeze by an operator sticks
+var customerPermissions = []Permission{
+	PermissionReadAccounts,
+	PermissionCreateAccounts,
+	PermissionUpdateAccounts,
+	PermissionFreezeAccounts,
+	PermissionCloseAccounts,
+	PermissionReadTransactions,
+	PermissionCreateTransactions,
+}
+
+var allPermissions = []Permission{
+	PermissionReadAccounts,
+	PermissionCreateAccounts,
+	PermissionUpdateAccounts,
+	PermissionFreezeAccounts,
+	PermissionUnfreezeAccounts,
+	PermissionCloseAccounts,
+	PermissionReadTransactions,
+	PermissionCreateTransactions,
+	PermissionReverseTransactions,
+	PermissionCheckLedger,
+	PermissionManageAPIKeys,
+	PermissionReadAudit,
+	PermissionReadMetrics,
+}
+
+// rolePermissions grants each staff role its permissions on every account
+var rolePermissions = map[string][]Permission{
+	RoleSupport:  {PermissionReadAccounts, PermissionReadTransactions},
+	RoleOperator: {PermissionReadAccounts, PermissionReadTransactions, PermissionFreezeAccounts, PermissionUnfreezeAccounts},
+	RoleAdmin:    allPermissions,
+}
+
+// scopePermissions grants each API key scope its permissions
+var scopePermissions = map[string][]Permission{
+	ScopeReadAccounts:      {PermissionReadAccounts, PermissionReadTransactions},
+	ScopeWriteTransactions: {PermissionCreateTransactions},
+	ScopeAdmin:             allPermissions,
+}
+
+// IsStaff reports whether the principal holds a staff role
+func (p Principal) IsStaff() bool {
+	for _, role := range p.Roles {
+		if _, ok := rolePermissions[role]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether the principal was granted permission: by its scopes
+// for an API key, by its staff roles, or as a customer when it has none
+func (p Principal) Can(permission Permission) bool {
+	if p.APIKeyID != "" {
+		return grants(scopePermissions, p.Scopes, permission)
+	}
+	if !p.IsStaff() {
+		return containsPermission(customerPermissions, permission)
+	}
+	return grants(rolePermissions, p.Roles, permission)
+}
+
+// grants reports whether any of names maps to permission in table
+func grants(table map[string][]Permission, names []string, permission Permission) bool {
+	for _, name := range names {
+		if containsPermission(table[name], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPermission(permissions []Permission, permission Permission) bool {
+	for _, granted := range permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
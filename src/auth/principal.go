@@ -0,0 +1,68 @@
+package auth
+
+import "context"
+
+// RoleAdmin may do everything, on every account
+const RoleAdmin = "admin"
+
+// Principal is the caller a request was authenticated as
+type Principal struct {
+	// Subject is the token's sub claim; accounts the caller creates are
+	// owned by it
+	Subject string
+	Roles   []string
+	// APIKeyID is set when the caller authenticated with an API key, whose
+	// Scopes limit the endpoints it may call
+	APIKeyID string
+	Scopes   []string
+}
+
+// HasRole reports whether the principal was granted role
+func (p Principal) HasRole(role string) bool {
+	for _, granted := range p.Roles {
+		if granted == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin reports whether the principal was granted the admin role
+func (p Principal) IsAdmin() bool {
+	return p.HasRole(RoleAdmin)
+}
+
+// HasScope reports whether an API key principal was granted scope; the admin
+// scope includes every other
+func (p Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// AllAccounts reports whether the principal may act on every account rather
+// than only the ones it owns. That is staff, and API keys, which run
+// back-office jobs. Both are limited by their permissions instead.
+func (p Principal) AllAccounts() bool {
+	return p.IsStaff() || p.APIKeyID != ""
+}
+
+// Anonymous is the principal of every request when authentication is
+// disabled. It may do everything, as the API did before authentication.
+var Anonymous = Principal{Roles: []string{RoleAdmin}}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of the request, if it was authenticated
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"finance_app/src/metrics"
+	"finance_app/src/models"
+	"finance_app/src/repositories"
+)
+
+type bypassKey struct{}
+
+// Bypass returns a copy of ctx whose account reads skip the cache. Reads that
+// decide whether money can move use it, so they never act on a stale
+// balance or status.
+func Bypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// Bypassed reports whether ctx skips the cache
+func Bypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+	return bypass
+}
+
+// Accounts caches FindOne of the account repository it wraps. Every write
+// through it drops the account's entry, again once its transaction has
+// committed when it runs in one made by Transactor.
+type Accounts struct {
+	repositories.AccountRepository
+	store Store
+	ttl   time.Duration
+}
+
+// CacheAccounts wraps next so FindOne reads through store, keeping entries
+// for ttl
+func CacheAccounts(next repositories.AccountRepository, store Store, ttl time.Duration) *Accounts {
+	return &Accounts{AccountRepository: next, store: store, ttl: ttl}
+}
+
+func accountKey(id string) string {
+	return "account:" + id
+}
+
+func (a *Accounts) FindOne(ctx context.Context, id string) (*models.Accounts, error) {
+	if Bypassed(ctx) || inTransaction(ctx) {
+		return a.AccountRepository.FindOne(ctx, id)
+	}
+
+	key := accountKey(id)
+	data, ok, err := a.store.Get(ctx, key)
+	switch {
+	case err != nil:
+		metrics.CacheRequests.WithLabelValues("accounts", metrics.CacheError).Inc()
+		logrus.WithContext(ctx).Warn("Failed to read account cache: ", err)
+	case ok:
+		var account models.Accounts
+		if err := bson.Unmarshal(data, &account); err == nil {
+			metrics.CacheRequests.WithLabelValues("accounts", metrics.CacheHit).Inc()
+			return &account, nil
+		}
+		metrics.CacheRequests.WithLabelValues("accounts", metrics.CacheError).Inc()
+		logrus.WithContext(ctx).Warn("Failed to decode cached account: ", err)
+	default:
+		metrics.CacheRequests.WithLabelValues("accounts", metrics.CacheMiss).Inc()
+	}
+
+	account, err := a.AccountRepository.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := bson.Marshal(account); err == nil {
+		if err := a.store.Set(ctx, key, data, a.ttl); err != nil {
+			logrus.WithContext(ctx).Warn("Failed to write account cache: ", err)
+		}
+	}
+	return account, nil
+}
+
+func (a *Accounts) Credit(ctx context.Context, id string, amount models.Money) (*models.Accounts, error) {
+	defer a.invalidate(ctx, id)
+	return a.AccountRepository.Credit(ctx, id, amount)
+}
+
+func (a *Accounts) Debit(ctx context.Context, id string, amount models.Money) (*models.Accounts, error) {
+	defer a.invalidate(ctx, id)
+	return a.AccountRepository.Debit(ctx, id, amount)
+}
+
+func (a *Accounts) UpdateDetails(ctx context.Context, id string, update models.AccountUpdate) (*models.Accounts, error) {
+	defer a.invalidate(ctx, id)
+	return a.AccountRepository.UpdateDetails(ctx, id, update)
+}
+
+func (a *Accounts) UpdateStatus(ctx context.Context, id string, status models.AccountStatus) (*models.Accounts, error) {
+	defer a.invalidate(ctx, id)
+	return a.AccountRepository.UpdateStatus(ctx, id, status)
+}
+
+// invalidate drops the entry of account id now and, inside a transaction,
+// once more after it commits, since a read in between may have cached the
+// balance from before the commit
+func (a *Accounts) invalidate(ctx context.Context, id string) {
+	key := accountKey(id)
+	if pending, ok := ctx.Value(pendingKey{}).(*pendingKeys); ok {
+		pending.add(key)
+	}
+	if err := a.store.Delete(ctx, key); err != nil {
+		logrus.WithContext(ctx).Error("Failed to invalidate account cache: ", err)
+	}
+}
+
+type pendingKey struct{}
+
+// pendingKeys collects the entries a transaction changed
+type pendingKeys struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (p *pendingKeys) add(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = append(p.keys, key)
+}
+
+func inTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(pendingKey{}).(*pendingKeys)
+	return ok
+}
+
+// Transactor wraps next so the entries of accounts changed in a transaction
+// are dropped after it ends. Reads inside a transaction skip the cache.
+func (a *Accounts) Transactor(next repositories.Transactor) repositories.Transactor {
+	return &invalidatingTransactor{next: next, store: a.store}
+}
+
+type invalidatingTransactor struct {
+	next  repositories.Transactor
+	store Store
+}
+
+func (t *invalidatingTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	pending := &pendingKeys{}
+	err := t.next.WithTransaction(ctx, func(ctx context.Context) error {
+		return fn(context.WithValue(ctx, pendingKey{}, pending))
+	})
+
+	// Rolled back changes are dropped too, which costs only a miss
+	if len(pending.keys) > 0 {
+		if err := t.store.Delete(context.WithoutCancel(ctx), pending.keys...); err != nil {
+			logrus.WithContext(ctx).Error("Failed to invalidate account cache: ", err)
+		}
+	}
+	return err
+}
@@ -2,19 +2,53 @@ package main
 
 import (
 	"context"
-	"finance_app/src/repositories"
+	"errors"
+	"finance_app/src/auth"
+	"finance_app/src/cache"
+	"finance_app/src/config"
+	"finance_app/src/metrics"
+	"finance_app/src/ratelimit"
+	"finance_app/src/services"
+	"finance_app/src/tracing"
+	"flag"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"strconv"
+	"syscall"
 	"time"
 
 	"finance_app/src/handlers"
 	"finance_app/src/routes"
-	"finance_app/src/utils"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 )
 
+// version is set at build time with -ldflags "-X main.version=v1.2.3"
+var version string
+
+// buildVersion returns version, falling back to the VCS revision Go stamps
+// into the binary
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+	return "dev"
+}
+
 func main() {
 	logrus.SetFormatter(&logrus.JSONFormatter{})
 	logrus.Info("Starting server")
@@ -24,40 +58,304 @@ func main() {
 		logrus.Warn("Warning: .env file not found, using system environment variables")
 	}
 
-	// Connect to MongoDB
-	logrus.Info("Connecting to MongoDB...")
-	client, err := utils.MongoConnect()
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		logrus.Fatal("Invalid configuration: ", err)
+	}
+	logrus.WithFields(cfg.LogFields()).Info("Configuration loaded")
+
+	// Logs written with a request context carry its trace and request IDs
+	logrus.AddHook(tracing.LogHook{})
+	shutdownTracing, err := tracing.Setup(tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		ServiceName: cfg.Tracing.ServiceName,
+	})
+	if err != nil {
+		logrus.Fatal("Failed to set up tracing: ", err)
+	}
+
+	// Flush spans last, after storage is closed
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logrus.Error("Error flushing traces: ", err)
+		}
+	}()
+
+	verifier, err := newVerifier(cfg.Auth)
+	if err != nil {
+		logrus.Fatal("Failed to set up authentication: ", err)
+	}
+	if verifier == nil {
+		logrus.Warn("Authentication is disabled, every request can act on every account")
+	}
+
+	// Schema migrations run at boot unless disabled, in which case they are
+	// applied with the migrate command before a deploy
+	migrate := cfg.Storage.MigrateOnBoot || (len(args) > 0 && args[0] == "migrate")
+
+	store, err := openStorage(cfg, migrate)
 	if err != nil {
-		logrus.Fatal("Failed to connect to MongoDB: ", err)
+		logrus.Fatal("Failed to open storage: ", err)
 		// logrus.Fatal already calls os.Exit(1), so this return is unreachable but makes intent clear
 		return
 	}
-	logrus.Info("Successfully connected to MongoDB")
 
-	// Ensure MongoDB disconnection on exit
+	// Ensure the storage connection is closed on exit
 	defer func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		if err := client.Disconnect(ctx); err != nil {
-			logrus.Error("Error disconnecting from MongoDB: ", err)
+		if err := store.close(ctx); err != nil {
+			logrus.Error("Error closing storage: ", err)
 		}
 	}()
 
-	// Initialize database and repository
-	db := client.Database("finance_db")
-	transactionRepo := repositories.NewTransactionMongoRepository(db)
-	accountsRepo := repositories.NewAccountsMongoRepository(db)
+	// Maintenance subcommands share the connection and exit when done
+	if len(args) > 0 {
+		runCommand(args[0], store)
+		return
+	}
+
+	// Redis is optional; features using it fall back to in-process state
+	redisClient, err := newRedisClient(cfg.Redis)
+	if err != nil {
+		logrus.Fatal("Invalid Redis configuration: ", err)
+	}
+	if redisClient != nil {
+		defer redisClient.Close()
+	}
+
+	cacheAccounts(cfg.AccountCache, redisClient, store)
 
 	// Create handler with dependencies
-	h := handlers.NewAppHandler(client, *transactionRepo, *accountsRepo)
+	h := handlers.NewAppHandler(store.client, store.transactor, store.transactionRepo, store.accountsRepo, store.idempotencyRepo, store.ledgerRepo, store.apiKeyRepo, store.auditRepo)
+	h.HealthService.Checks = append(h.HealthService.Checks, store.checks...)
+	if redisClient != nil {
+		h.HealthService.Checks = append(h.HealthService.Checks, services.HealthCheck{
+			Name: "redis",
+			Check: func(ctx context.Context) error {
+				return redisClient.Ping(ctx).Err()
+			},
+		})
+	}
+	h.Auth = verifier
+	h.RateLimiter, h.RateLimits = newRateLimiter(cfg.RateLimit, redisClient)
+	h.HealthService.Timeout = cfg.Server.ReadinessTimeout
+	h.HealthService.Version = buildVersion()
 
 	// Setup router
 	router := chi.NewRouter()
-	routes.Routes(router, h)
+	routes.Routes(router, h, cfg.Server)
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler: router,
+	}
 
 	// Start server
-	logrus.Info("Server starting on port 1234")
-	if err := http.ListenAndServe(":1234", router); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Summing balances scans every account, so it runs on a timer, not per scrape
+	go metrics.Poll(ctx, metrics.BalanceUnderManagement, time.Minute, func(ctx context.Context) (float64, error) {
+		total, err := h.AccountService.TotalBalance(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return strconv.ParseFloat(total.String(), 64)
+	})
+
+	// Events recorded inside transactions wait in an outbox until chained
+	go services.ChainAuditEvents(ctx, store.auditRepo, time.Second)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logrus.Infof("Server starting on port %d", cfg.Server.Port)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	// Scrapes on the admin port skip the API middleware and are not drained.
+	// Losing them is no reason to stop serving the API.
+	var adminServer *http.Server
+	if cfg.Server.MetricsPort != 0 {
+		admin := http.NewServeMux()
+		admin.Handle("/metrics", metrics.Handler())
+		adminServer = &http.Server{
+			Addr:    net.JoinHostPort(cfg.Server.MetricsHost, strconv.Itoa(cfg.Server.MetricsPort)),
+			Handler: admin,
+		}
+		go func() {
+			logrus.Infof("Metrics server starting on %s", adminServer.Addr)
+			if err := adminServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				logrus.Error("Metrics server stopped: ", err)
+			}
+		}()
+	}
+
+	select {
+	case err := <-serveErr:
 		logrus.Fatal("Failed to start server: ", err)
+	case <-ctx.Done():
+		// A second signal kills the process without waiting
+		stop()
+	}
+
+	shutdown(server, h.Lifecycle, cfg.Server)
+	if adminServer != nil {
+		adminServer.Close()
+	}
+}
+
+// newVerifier builds the bearer token verifier, or returns nil when
+// authentication is disabled
+func newVerifier(cfg config.AuthConfig) (*auth.Verifier, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	opts := auth.Options{
+		HMACSecret: []byte(cfg.HMACSecret),
+		Issuer:     cfg.Issuer,
+		Audience:   cfg.Audience,
+	}
+	if cfg.RSAPublicKeyFile != "" {
+		key, err := auth.LoadRSAPublicKey(cfg.RSAPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts.RSAPublicKey = key
+	}
+
+	return auth.NewVerifier(opts)
+}
+
+// newRedisClient returns a client for cfg, or nil when Redis is not
+// configured. It does not connect until first used. Commands honour the
+// deadline of their context, so the short timeouts of the rate limiter and
+// cache apply to the connection too.
+func newRedisClient(cfg config.RedisConfig) (*redis.Client, error) {
+	if cfg.URL == "" {
+		return nil, nil
+	}
+	opts, err := redis.ParseURL(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	opts.ContextTimeoutEnabled = true
+	return redis.NewClient(opts), nil
+}
+
+// cacheAccounts puts the configured cache in front of the account
+// repository, and has the transactor drop the entries of accounts a
+// transaction changed once it ends. Maintenance commands run before this and
+// always read storage.
+func cacheAccounts(cfg config.AccountCacheConfig, client *redis.Client, store *storage) {
+	var s cache.Store
+	switch cfg.Backend {
+	case config.CacheMemory:
+		s = cache.NewLRU(cfg.Size)
+	case config.CacheRedis:
+		s = cache.NewRedis(client)
+	default:
+		return
+	}
+
+	cached := cache.CacheAccounts(store.accountsRepo, s, cfg.TTL)
+	store.accountsRepo = cached
+	store.transactor = cached.Transactor(store.transactor)
+}
+
+// newRateLimiter builds the rate limiter and its policy, or returns a nil
+// limiter when rate limiting is disabled. Counts are kept in Redis when
+// there is a client, and in process while it is unavailable.
+func newRateLimiter(cfg config.RateLimitConfig, client *redis.Client) (ratelimit.Limiter, ratelimit.Policy) {
+	policy := ratelimit.Policy{
+		Default:    ratelimit.Rule{Requests: cfg.Default.Requests, Window: cfg.Default.Window},
+		Routes:     map[string]ratelimit.Rule{},
+		PerIP:      ratelimit.Rule{Requests: cfg.PerIP.Requests, Window: cfg.PerIP.Window},
+		PerAccount: ratelimit.Rule{Requests: cfg.PerAccount.Requests, Window: cfg.PerAccount.Window},
+	}
+	for route, rule := range cfg.Routes {
+		policy.Routes[route] = ratelimit.Rule{Requests: rule.Requests, Window: rule.Window}
+	}
+
+	if !cfg.Enabled {
+		return nil, policy
+	}
+	if client == nil {
+		logrus.Warn("Redis is not configured, rate limits are counted per instance")
+		return ratelimit.NewMemory(), policy
+	}
+	return &ratelimit.Fallback{
+		Primary:   ratelimit.NewRedis(client),
+		Secondary: ratelimit.NewMemory(),
+	}, policy
+}
+
+// shutdown stops the server gracefully. Health checks fail first so load
+// balancers stop routing here, then in-flight requests have up to the drain
+// timeout to finish. It returns once every handler has returned, so storage
+// can be closed safely afterwards.
+func shutdown(server *http.Server, lifecycle *handlers.Lifecycle, cfg config.ServerConfig) {
+	logrus.Info("Shutting down, draining in-flight requests")
+	lifecycle.StartDraining()
+	time.Sleep(cfg.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.DrainTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logrus.Warn("Drain timeout reached, closing remaining connections: ", err)
+		server.Close()
+	}
+
+	// Closing a connection does not stop its handler, so wait for those too
+	lifecycle.Wait()
+	logrus.Info("Server stopped")
+}
+
+// runCommand runs a one-off maintenance command instead of the HTTP server
+func runCommand(name string, store *storage) {
+	ctx := context.Background()
+
+	switch name {
+	case "migrate":
+		// openStorage has already applied the migrations
+		logrus.Info("Schema migrations applied")
+	case "repair-balances":
+		repairer := &services.BalanceRepairer{
+			TransactionsRepo: store.transactionRepo,
+			AccountsRepo:     store.accountsRepo,
+			Transactor:       store.transactor,
+		}
+		report, err := repairer.RepairRunningBalances(ctx)
+		if err != nil {
+			logrus.Fatal("Failed to repair running balances: ", err)
+		}
+		logrus.WithFields(logrus.Fields{
+			"accounts":     report.Accounts,
+			"transactions": report.Transactions,
+			"updated":      report.Updated,
+		}).Info("Running balance repair finished")
+	case "backfill-ledger":
+		ledger := &services.LedgerHandler{
+			LedgerRepo:   store.ledgerRepo,
+			AccountsRepo: store.accountsRepo,
+			Transactor:   store.transactor,
+		}
+		report, err := ledger.BackfillOpeningBalances(ctx)
+		if err != nil {
+			logrus.Fatal("Failed to backfill ledger: ", err)
+		}
+		logrus.WithFields(logrus.Fields{
+			"accounts": report.Accounts,
+			"posted":   report.Posted,
+		}).Info("Ledger backfill finished")
+	default:
+		logrus.Fatalf("Unknown command %q, available commands: migrate, repair-balances, backfill-ledger", name)
 	}
 }
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"finance_app/src/config"
+	"finance_app/src/metrics"
+	"finance_app/src/repositories"
+	"finance_app/src/services"
+	"finance_app/src/tracing"
+	"finance_app/src/utils"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// storage holds the repositories of the configured backend
+type storage struct {
+	// client is nil on the PostgreSQL backend
+	client          *mongo.Client
+	transactor      repositories.Transactor
+	transactionRepo repositories.TransactionRepository
+	accountsRepo    repositories.AccountRepository
+	idempotencyRepo repositories.IdempotencyRepository
+	ledgerRepo      repositories.LedgerRepository
+	apiKeyRepo      repositories.APIKeyRepository
+	auditRepo       repositories.AuditRepository
+	// checks are the readiness checks of the backend beyond the Mongo client
+	// ping every AppHandler has
+	checks []services.HealthCheck
+	close  func(ctx context.Context) error
+}
+
+// openStorage connects to the configured backend, applying its schema
+// migrations first when migrate is set
+func openStorage(cfg *config.Config, migrate bool) (*storage, error) {
+	var store *storage
+	var err error
+	switch cfg.Storage.Backend {
+	case config.BackendMongo:
+		store, err = openMongoStorage(cfg, migrate)
+	case config.BackendPostgres:
+		store, err = openPostgresStorage(cfg, migrate)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q, expected %s or %s", cfg.Storage.Backend, config.BackendMongo, config.BackendPostgres)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Trace account and transaction calls, and time every repository call
+	backend := cfg.Storage.Backend
+	dbSystem := map[string]string{config.BackendMongo: "mongodb", config.BackendPostgres: "postgresql"}[backend]
+	store.transactionRepo = metrics.InstrumentTransactions(tracing.TraceTransactions(store.transactionRepo, dbSystem), backend)
+	store.accountsRepo = metrics.InstrumentAccounts(tracing.TraceAccounts(store.accountsRepo, dbSystem), backend)
+	store.idempotencyRepo = metrics.InstrumentIdempotency(store.idempotencyRepo, backend)
+	store.ledgerRepo = metrics.InstrumentLedger(store.ledgerRepo, backend)
+	store.apiKeyRepo = metrics.InstrumentAPIKeys(store.apiKeyRepo, backend)
+	store.auditRepo = metrics.InstrumentAudit(store.auditRepo, backend)
+
+	return store, nil
+}
+
+func openMongoStorage(cfg *config.Config, migrate bool) (*storage, error) {
+	logrus.Info("Connecting to MongoDB...")
+	client, err := utils.MongoConnect(cfg.Mongo.URI, cfg.Mongo.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	logrus.Info("Successfully connected to MongoDB")
+
+	db := client.Database(cfg.Mongo.Database)
+	idempotencyRepo := repositories.NewIdempotencyMongoRepository(db, cfg.Idempotency.TTL)
+
+	migrateCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if migrate {
+		if err := repositories.MigrateMongo(migrateCtx, db); err != nil {
+			client.Disconnect(migrateCtx)
+			return nil, fmt.Errorf("failed to migrate MongoDB: %w", err)
+		}
+	}
+
+	// The TTL index follows the configured TTL rather than a schema version
+	if err := idempotencyRepo.EnsureIndexes(migrateCtx); err != nil {
+		client.Disconnect(migrateCtx)
+		return nil, fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	return &storage{
+		client:          client,
+		transactor:      repositories.NewMongoTransactor(client),
+		transactionRepo: repositories.NewTransactionMongoRepository(db),
+		accountsRepo:    repositories.NewAccountsMongoRepository(db),
+		idempotencyRepo: idempotencyRepo,
+		ledgerRepo:      repositories.NewLedgerMongoRepository(db),
+		apiKeyRepo:      repositories.NewAPIKeyMongoRepository(db),
+		auditRepo:       repositories.NewAuditMongoRepository(db),
+		checks: []services.HealthCheck{
+			migrationsCheck(func(ctx context.Context) (int, error) {
+				return repositories.PendingMongoMigrations(ctx, db)
+			}),
+		},
+		close: client.Disconnect,
+	}, nil
+}
+
+func openPostgresStorage(cfg *config.Config, migrate bool) (*storage, error) {
+	logrus.Info("Connecting to PostgreSQL...")
+	db, err := utils.PostgresConnect(cfg.Postgres.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	logrus.Info("Successfully connected to PostgreSQL")
+
+	migrateCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if migrate {
+		if err := repositories.MigratePostgres(migrateCtx, db); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to migrate PostgreSQL: %w", err)
+		}
+	}
+
+	idempotencyRepo := repositories.NewIdempotencyPostgresRepository(db, cfg.Idempotency.TTL)
+
+	// PostgreSQL has no TTL index, so expired keys are cleared at boot
+	if purged, err := idempotencyRepo.PurgeExpired(migrateCtx); err != nil {
+		logrus.Warn("Failed to purge expired idempotency keys: ", err)
+	} else if purged > 0 {
+		logrus.WithField("purged", purged).Info("Purged expired idempotency keys")
+	}
+
+	return &storage{
+		transactor:      repositories.NewPostgresTransactor(db),
+		transactionRepo: repositories.NewTransactionPostgresRepository(db),
+		accountsRepo:    repositories.NewAccountsPostgresRepository(db),
+		idempotencyRepo: idempotencyRepo,
+		ledgerRepo:      repositories.NewLedgerPostgresRepository(db),
+		apiKeyRepo:      repositories.NewAPIKeyPostgresRepository(db),
+		auditRepo:       repositories.NewAuditPostgresRepository(db),
+		checks: []services.HealthCheck{
+			{Name: "postgres", Required: true, Check: db.PingContext},
+			migrationsCheck(func(ctx context.Context) (int, error) {
+				return repositories.PendingPostgresMigrations(ctx, db)
+			}),
+		},
+		close: func(context.Context) error { return db.Close() },
+	}, nil
+}
+
+// migrationsCheck fails readiness while schema migrations are pending, as they
+// are when migrating on boot is off and the migrate command has not run yet
+func migrationsCheck(pending func(ctx context.Context) (int, error)) services.HealthCheck {
+	return services.HealthCheck{
+		Name:     "migrations",
+		Required: true,
+		Check: func(ctx context.Context) error {
+			count, err := pending(ctx)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return fmt.Errorf("%d schema migrations pending", count)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,713 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Storage backends selectable with storage.backend
+const (
+	BackendMongo    = "mongo"
+	BackendPostgres = "postgres"
+)
+
+// Trace exporters selectable with tracing.exporter
+const (
+	TraceExporterNone   = "none"
+	TraceExporterStdout = "stdout"
+	TraceExporterOTLP   = "otlp"
+)
+
+// Account cache backends selectable with accountCache.backend
+const (
+	CacheNone   = "none"
+	CacheMemory = "memory"
+	CacheRedis  = "redis"
+)
+
+// Config is the application configuration. Load fills it from, in increasing
+// precedence, the defaults, an optional YAML file, environment variables and
+// command-line flags.
+type Config struct {
+	Server       ServerConfig       `yaml:"server"`
+	Storage      StorageConfig      `yaml:"storage"`
+	Mongo        MongoConfig        `yaml:"mongo"`
+	Postgres     PostgresConfig     `yaml:"postgres"`
+	Idempotency  IdempotencyConfig  `yaml:"idempotency"`
+	Tracing      TracingConfig      `yaml:"tracing"`
+	Auth         AuthConfig         `yaml:"auth"`
+	Redis        RedisConfig        `yaml:"redis"`
+	RateLimit    RateLimitConfig    `yaml:"rateLimit"`
+	AccountCache AccountCacheConfig `yaml:"accountCache"`
+}
+
+type ServerConfig struct {
+	Port int `yaml:"port"`
+	// MetricsPort serves /metrics on a separate admin listener without
+	// authentication; 0, the default, serves it on Port to admins instead
+	MetricsPort int `yaml:"metricsPort"`
+	// MetricsHost is the address the admin listener binds to, loopback
+	// unless scrapers reach it from elsewhere
+	MetricsHost string `yaml:"metricsHost"`
+	// RequestTimeout bounds how long a handler may run
+	RequestTimeout time.Duration `yaml:"requestTimeout"`
+	// CORSOrigins are the origins browsers may call the API from; "*"
+	// allows any
+	CORSOrigins []string `yaml:"corsOrigins"`
+	// TrustedProxies are the addresses or CIDR ranges of reverse proxies
+	// whose X-Forwarded-For and X-Real-IP headers name the client. Requests
+	// from anywhere else are known by their own address.
+	TrustedProxies []string `yaml:"trustedProxies"`
+	// ShutdownDelay is how long health checks fail before the server stops
+	// accepting connections, giving load balancers time to notice
+	ShutdownDelay time.Duration `yaml:"shutdownDelay"`
+	// DrainTimeout bounds how long shutdown waits for in-flight requests
+	// before closing their connections
+	DrainTimeout time.Duration `yaml:"drainTimeout"`
+	// ReadinessTimeout bounds each dependency check of the readiness probe
+	ReadinessTimeout time.Duration `yaml:"readinessTimeout"`
+}
+
+type StorageConfig struct {
+	Backend string `yaml:"backend"`
+	// MigrateOnBoot applies pending schema migrations at startup; without it
+	// they run only with the migrate command
+	MigrateOnBoot bool `yaml:"migrateOnBoot"`
+}
+
+type MongoConfig struct {
+	URI      string `yaml:"uri"`
+	Database string `yaml:"database"`
+	// Timeout bounds connecting to MongoDB and the first ping
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type PostgresConfig struct {
+	DSN string `yaml:"dsn"`
+}
+
+type IdempotencyConfig struct {
+	// TTL is how long an Idempotency-Key is remembered
+	TTL time.Duration `yaml:"ttl"`
+}
+
+type TracingConfig struct {
+	// Exporter is none, stdout or otlp
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the base URL of an OTLP/HTTP collector
+	Endpoint    string `yaml:"endpoint"`
+	ServiceName string `yaml:"serviceName"`
+}
+
+type AuthConfig struct {
+	// Enabled requires a JWT bearer token on the API; only turn it off for
+	// local development
+	Enabled bool `yaml:"enabled"`
+	// HMACSecret verifies HS256 tokens
+	HMACSecret string `yaml:"hmacSecret"`
+	// RSAPublicKeyFile is a PEM file whose key verifies RS256 tokens
+	RSAPublicKeyFile string `yaml:"rsaPublicKeyFile"`
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+}
+
+type RedisConfig struct {
+	// URL is redis://[user:password@]host[:port][/db]; empty runs without
+	// Redis
+	URL string `yaml:"url"`
+}
+
+type RateLimitConfig struct {
+	// Enabled limits requests per API key, user or, without either, client
+	// IP. Counts are kept in Redis when it is configured, otherwise per
+	// instance.
+	Enabled bool `yaml:"enabled"`
+	// Default applies to routes without a limit of their own, which share it
+	Default RateLimitRule `yaml:"default"`
+	// Routes limits "METHOD /pattern" chi routes, such as
+	// "POST /api/v1/transactions", on their own
+	Routes map[string]RateLimitRule `yaml:"routes"`
+	// PerIP limits each client IP before authentication, so requests with
+	// bad credentials are limited as well
+	PerIP RateLimitRule `yaml:"perIP"`
+	// PerAccount limits the transactions on each account the caller may
+	// use, whichever clients send them
+	PerAccount RateLimitRule `yaml:"perAccount"`
+}
+
+type AccountCacheConfig struct {
+	// Backend is none, memory or redis
+	Backend string `yaml:"backend"`
+	// TTL bounds how stale an entry gets when another instance changes the
+	// account; changes made through this one drop its entries at once
+	TTL time.Duration `yaml:"ttl"`
+	// Size is how many accounts the memory backend holds
+	Size int `yaml:"size"`
+}
+
+// RateLimitRule allows Requests per sliding Window
+type RateLimitRule struct {
+	Requests int           `yaml:"requests"`
+	Window   time.Duration `yaml:"window"`
+}
+
+// minHMACSecretLength is the shortest HS256 secret accepted, the size of its
+// SHA-256 output
+const minHMACSecretLength = 32
+
+// Default returns the configuration used for anything left unset
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:             1234,
+			MetricsHost:      "127.0.0.1",
+			RequestTimeout:   60 * time.Second,
+			CORSOrigins:      []string{"*"},
+			ShutdownDelay:    5 * time.Second,
+			DrainTimeout:     30 * time.Second,
+			ReadinessTimeout: 2 * time.Second,
+		},
+		Storage: StorageConfig{
+			Backend:       BackendMongo,
+			MigrateOnBoot: true,
+		},
+		Mongo: MongoConfig{
+			Database: "finance_db",
+			Timeout:  10 * time.Second,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: 24 * time.Hour,
+		},
+		Tracing: TracingConfig{
+			Exporter:    TraceExporterNone,
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "finance_app",
+		},
+		Auth: AuthConfig{
+			Enabled: true,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Default: RateLimitRule{Requests: 300, Window: time.Minute},
+			Routes: map[string]RateLimitRule{
+				"POST /api/v1/transactions": {Requests: 30, Window: time.Minute},
+			},
+			PerIP:      RateLimitRule{Requests: 1000, Window: time.Minute},
+			PerAccount: RateLimitRule{Requests: 20, Window: time.Minute},
+		},
+		AccountCache: AccountCacheConfig{
+			Backend: CacheNone,
+			TTL:     30 * time.Second,
+			Size:    10000,
+		},
+	}
+}
+
+// setting is one configuration value settable by environment variable and
+// flag
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+var settings = []setting{
+	{"PORT", "port", "HTTP port to listen on", func(c *Config, v string) (err error) {
+		c.Server.Port, err = strconv.Atoi(v)
+		return
+	}},
+	{"METRICS_PORT", "metrics-port", "admin port for /metrics, or 0 to serve it on the API port to admins", func(c *Config, v string) (err error) {
+		c.Server.MetricsPort, err = strconv.Atoi(v)
+		return
+	}},
+	{"METRICS_HOST", "metrics-host", "address the admin port for /metrics binds to", func(c *Config, v string) error {
+		c.Server.MetricsHost = v
+		return nil
+	}},
+	{"REQUEST_TIMEOUT", "request-timeout", "longest a request may take, such as 60s", func(c *Config, v string) (err error) {
+		c.Server.RequestTimeout, err = time.ParseDuration(v)
+		return
+	}},
+	{"CORS_ORIGINS", "cors-origins", "comma-separated origins allowed by CORS, or *", func(c *Config, v string) error {
+		c.Server.CORSOrigins = splitList(v)
+		return nil
+	}},
+	{"TRUSTED_PROXIES", "trusted-proxies", "comma-separated addresses or CIDR ranges of proxies trusted to name the client", func(c *Config, v string) error {
+		c.Server.TrustedProxies = splitList(v)
+		return nil
+	}},
+	{"SHUTDOWN_DELAY", "shutdown-delay", "how long health checks fail before shutdown starts, such as 5s", func(c *Config, v string) (err error) {
+		c.Server.ShutdownDelay, err = time.ParseDuration(v)
+		return
+	}},
+	{"DRAIN_TIMEOUT", "drain-timeout", "longest shutdown waits for in-flight requests, such as 30s", func(c *Config, v string) (err error) {
+		c.Server.DrainTimeout, err = time.ParseDuration(v)
+		return
+	}},
+	{"READINESS_TIMEOUT", "readiness-timeout", "longest a readiness dependency check may take, such as 2s", func(c *Config, v string) (err error) {
+		c.Server.ReadinessTimeout, err = time.ParseDuration(v)
+		return
+	}},
+	{"STORAGE_BACKEND", "storage-backend", "storage backend: mongo or postgres", func(c *Config, v string) error {
+		c.Storage.Backend = v
+		return nil
+	}},
+	{"MIGRATE_ON_BOOT", "migrate-on-boot", "apply schema migrations at startup", func(c *Config, v string) (err error) {
+		c.Storage.MigrateOnBoot, err = strconv.ParseBool(v)
+		return
+	}},
+	{"MONGO_URI", "mongo-uri", "MongoDB connection string", func(c *Config, v string) error {
+		c.Mongo.URI = v
+		return nil
+	}},
+	{"MONGO_DATABASE", "mongo-database", "MongoDB database name", func(c *Config, v string) error {
+		c.Mongo.Database = v
+		return nil
+	}},
+	{"MONGO_TIMEOUT", "mongo-timeout", "MongoDB connect timeout, such as 10s", func(c *Config, v string) (err error) {
+		c.Mongo.Timeout, err = time.ParseDuration(v)
+		return
+	}},
+	{"POSTGRES_DSN", "postgres-dsn", "PostgreSQL connection string", func(c *Config, v string) error {
+		c.Postgres.DSN = v
+		return nil
+	}},
+	{"IDEMPOTENCY_TTL", "idempotency-ttl", "how long Idempotency-Keys are remembered, such as 24h", func(c *Config, v string) (err error) {
+		c.Idempotency.TTL, err = time.ParseDuration(v)
+		return
+	}},
+	{"TRACING_EXPORTER", "tracing-exporter", "trace exporter: none, stdout or otlp", func(c *Config, v string) error {
+		c.Tracing.Exporter = v
+		return nil
+	}},
+	{"OTEL_EXPORTER_OTLP_ENDPOINT", "tracing-endpoint", "OTLP/HTTP collector base URL", func(c *Config, v string) error {
+		c.Tracing.Endpoint = v
+		return nil
+	}},
+	{"OTEL_SERVICE_NAME", "tracing-service-name", "service name reported with traces", func(c *Config, v string) error {
+		c.Tracing.ServiceName = v
+		return nil
+	}},
+	{"AUTH_ENABLED", "auth-enabled", "require JWT bearer tokens on the API", func(c *Config, v string) (err error) {
+		c.Auth.Enabled, err = strconv.ParseBool(v)
+		return
+	}},
+	{"AUTH_HMAC_SECRET", "auth-hmac-secret", "secret verifying HS256 tokens", func(c *Config, v string) error {
+		c.Auth.HMACSecret = v
+		return nil
+	}},
+	{"AUTH_RSA_PUBLIC_KEY_FILE", "auth-rsa-public-key-file", "PEM public key file verifying RS256 tokens", func(c *Config, v string) error {
+		c.Auth.RSAPublicKeyFile = v
+		return nil
+	}},
+	{"AUTH_ISSUER", "auth-issuer", "required iss claim of tokens", func(c *Config, v string) error {
+		c.Auth.Issuer = v
+		return nil
+	}},
+	{"AUTH_AUDIENCE", "auth-audience", "required aud claim of tokens", func(c *Config, v string) error {
+		c.Auth.Audience = v
+		return nil
+	}},
+	{"REDIS_URL", "redis-url", "Redis URL, such as redis://localhost:6379/0", func(c *Config, v string) error {
+		c.Redis.URL = v
+		return nil
+	}},
+	{"RATE_LIMIT_ENABLED", "rate-limit-enabled", "limit requests per client", func(c *Config, v string) (err error) {
+		c.RateLimit.Enabled, err = strconv.ParseBool(v)
+		return
+	}},
+	{"RATE_LIMIT_REQUESTS", "rate-limit-requests", "requests allowed per window on routes without their own limit", func(c *Config, v string) (err error) {
+		c.RateLimit.Default.Requests, err = strconv.Atoi(v)
+		return
+	}},
+	{"RATE_LIMIT_WINDOW", "rate-limit-window", "rate limit window, such as 1m", func(c *Config, v string) (err error) {
+		c.RateLimit.Default.Window, err = time.ParseDuration(v)
+		return
+	}},
+	{"RATE_LIMIT_ROUTES", "rate-limit-routes", "comma-separated route limits, such as POST /api/v1/transactions=30/1m", func(c *Config, v string) (err error) {
+		c.RateLimit.Routes, err = parseRouteLimits(v)
+		return
+	}},
+	{"RATE_LIMIT_PER_IP", "rate-limit-per-ip", "requests allowed per client IP before authentication, such as 1000/1m", func(c *Config, v string) (err error) {
+		c.RateLimit.PerIP, err = parseRouteLimit(v)
+		return
+	}},
+	{"RATE_LIMIT_PER_ACCOUNT", "rate-limit-per-account", "transactions allowed per account, such as 20/1m", func(c *Config, v string) (err error) {
+		c.RateLimit.PerAccount, err = parseRouteLimit(v)
+		return
+	}},
+	{"ACCOUNT_CACHE", "account-cache", "account cache: none, memory or redis", func(c *Config, v string) error {
+		c.AccountCache.Backend = v
+		return nil
+	}},
+	{"ACCOUNT_CACHE_TTL", "account-cache-ttl", "how long accounts stay cached, such as 30s", func(c *Config, v string) (err error) {
+		c.AccountCache.TTL, err = time.ParseDuration(v)
+		return
+	}},
+	{"ACCOUNT_CACHE_SIZE", "account-cache-size", "how many accounts the memory cache holds", func(c *Config, v string) (err error) {
+		c.AccountCache.Size, err = strconv.Atoi(v)
+		return
+	}},
+}
+
+// Load builds the configuration from args, which are the command-line
+// arguments without the program name, and the environment. The YAML file is
+// named by -config or CONFIG_FILE. It returns the arguments left after the
+// flags, such as a subcommand.
+func Load(args []string) (*Config, []string, error) {
+	fs := flag.NewFlagSet("finance_app", flag.ContinueOnError)
+
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML configuration file")
+
+	// Flags are applied last, in the order given
+	type flagValue struct {
+		setting setting
+		value   string
+	}
+	var flagValues []flagValue
+	for _, s := range settings {
+		fs.Func(s.flag, s.usage, func(v string) error {
+			flagValues = append(flagValues, flagValue{s, v})
+			return nil
+		})
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	cfg := Default()
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env); ok && v != "" {
+			if err := s.set(cfg, v); err != nil {
+				return nil, nil, fmt.Errorf("invalid %s: %w", s.env, err)
+			}
+		}
+	}
+
+	for _, f := range flagValues {
+		if err := f.setting.set(cfg, f.value); err != nil {
+			return nil, nil, fmt.Errorf("invalid -%s: %w", f.setting.flag, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+// loadFile overlays the YAML file at path; keys it leaves out keep their
+// current values
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Validate reports every invalid value at once
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server port must be between 1 and 65535, got %d", c.Server.Port))
+	}
+	if c.Server.MetricsPort < 0 || c.Server.MetricsPort > 65535 {
+		errs = append(errs, fmt.Errorf("metrics port must be between 0 and 65535, got %d", c.Server.MetricsPort))
+	} else if c.Server.MetricsPort == c.Server.Port {
+		errs = append(errs, errors.New("metrics port must differ from the server port"))
+	}
+	if c.Server.RequestTimeout <= 0 {
+		errs = append(errs, errors.New("server request timeout must be positive"))
+	}
+	if c.Server.ShutdownDelay < 0 {
+		errs = append(errs, errors.New("server shutdown delay cannot be negative"))
+	}
+	if c.Server.DrainTimeout <= 0 {
+		errs = append(errs, errors.New("server drain timeout must be positive"))
+	}
+	if c.Server.ReadinessTimeout <= 0 {
+		errs = append(errs, errors.New("server readiness timeout must be positive"))
+	}
+	if len(c.Server.CORSOrigins) == 0 {
+		errs = append(errs, errors.New("at least one CORS origin is required, use * to allow any"))
+	}
+	for _, origin := range c.Server.CORSOrigins {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			errs = append(errs, fmt.Errorf("CORS origin %q must start with http:// or https://", origin))
+		}
+	}
+	if _, err := ParseTrustedProxies(c.Server.TrustedProxies); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch c.Storage.Backend {
+	case BackendMongo:
+		if c.Mongo.URI == "" {
+			errs = append(errs, errors.New("mongo URI is required for the mongo backend"))
+		}
+		if c.Mongo.Database == "" {
+			errs = append(errs, errors.New("mongo database is required for the mongo backend"))
+		}
+		if c.Mongo.Timeout <= 0 {
+			errs = append(errs, errors.New("mongo timeout must be positive"))
+		}
+	case BackendPostgres:
+		if c.Postgres.DSN == "" {
+			errs = append(errs, errors.New("postgres DSN is required for the postgres backend"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown storage backend %q, expected %s or %s", c.Storage.Backend, BackendMongo, BackendPostgres))
+	}
+
+	if c.Idempotency.TTL <= 0 {
+		errs = append(errs, errors.New("idempotency TTL must be positive"))
+	}
+
+	switch c.Tracing.Exporter {
+	case TraceExporterNone, TraceExporterStdout:
+	case TraceExporterOTLP:
+		if !strings.HasPrefix(c.Tracing.Endpoint, "http://") && !strings.HasPrefix(c.Tracing.Endpoint, "https://") {
+			errs = append(errs, fmt.Errorf("tracing endpoint %q must start with http:// or https://", c.Tracing.Endpoint))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown trace exporter %q, expected %s, %s or %s", c.Tracing.Exporter, TraceExporterNone, TraceExporterStdout, TraceExporterOTLP))
+	}
+	if c.Tracing.ServiceName == "" {
+		errs = append(errs, errors.New("tracing service name is required"))
+	}
+
+	if c.Auth.Enabled {
+		if c.Auth.HMACSecret == "" && c.Auth.RSAPublicKeyFile == "" {
+			errs = append(errs, errors.New("auth needs an HMAC secret or RSA public key file, or must be disabled"))
+		}
+		if c.Auth.HMACSecret != "" && len(c.Auth.HMACSecret) < minHMACSecretLength {
+			errs = append(errs, fmt.Errorf("auth HMAC secret must be at least %d bytes", minHMACSecretLength))
+		}
+	}
+
+	if c.Redis.URL != "" && !strings.HasPrefix(c.Redis.URL, "redis://") {
+		errs = append(errs, fmt.Errorf("redis URL %q must start with redis://", redact(c.Redis.URL)))
+	}
+
+	if c.RateLimit.Enabled {
+		if c.RateLimit.Default.Requests <= 0 || c.RateLimit.Default.Window <= 0 {
+			errs = append(errs, errors.New("default rate limit needs positive requests and window"))
+		}
+		if c.RateLimit.PerIP.Requests <= 0 || c.RateLimit.PerIP.Window <= 0 {
+			errs = append(errs, errors.New("per-IP rate limit needs positive requests and window"))
+		}
+		if c.RateLimit.PerAccount.Requests <= 0 || c.RateLimit.PerAccount.Window <= 0 {
+			errs = append(errs, errors.New("per-account rate limit needs positive requests and window"))
+		}
+		for route, rule := range c.RateLimit.Routes {
+			if method, path, ok := strings.Cut(route, " "); !ok || method == "" || !strings.HasPrefix(path, "/") {
+				errs = append(errs, fmt.Errorf("rate limit route %q must be a method and a path, such as POST /api/v1/transactions", route))
+			}
+			if rule.Requests <= 0 || rule.Window <= 0 {
+				errs = append(errs, fmt.Errorf("rate limit of %s needs positive requests and window", route))
+			}
+		}
+	}
+
+	switch c.AccountCache.Backend {
+	case CacheNone:
+	case CacheMemory, CacheRedis:
+		if c.AccountCache.TTL <= 0 {
+			errs = append(errs, errors.New("account cache TTL must be positive"))
+		}
+		if c.AccountCache.Backend == CacheMemory && c.AccountCache.Size <= 0 {
+			errs = append(errs, errors.New("account cache size must be positive"))
+		}
+		if c.AccountCache.Backend == CacheRedis && c.Redis.URL == "" {
+			errs = append(errs, errors.New("the redis account cache needs a redis URL"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown account cache %q, expected %s, %s or %s", c.AccountCache.Backend, CacheNone, CacheMemory, CacheRedis))
+	}
+
+	return errors.Join(errs...)
+}
+
+// LogFields returns the effective configuration for logging, with passwords
+// in connection strings and secrets redacted
+func (c *Config) LogFields() map[string]interface{} {
+	return map[string]interface{}{
+		"server.port":             c.Server.Port,
+		"server.metricsPort":      c.Server.MetricsPort,
+		"server.metricsHost":      c.Server.MetricsHost,
+		"server.requestTimeout":   c.Server.RequestTimeout.String(),
+		"server.corsOrigins":      strings.Join(c.Server.CORSOrigins, ","),
+		"server.trustedProxies":   strings.Join(c.Server.TrustedProxies, ","),
+		"server.shutdownDelay":    c.Server.ShutdownDelay.String(),
+		"server.drainTimeout":     c.Server.DrainTimeout.String(),
+		"server.readinessTimeout": c.Server.ReadinessTimeout.String(),
+		"storage.backend":         c.Storage.Backend,
+		"storage.migrateOnBoot":   c.Storage.MigrateOnBoot,
+		"mongo.uri":               redact(c.Mongo.URI),
+		"mongo.database":          c.Mongo.Database,
+		"mongo.timeout":           c.Mongo.Timeout.String(),
+		"postgres.dsn":            redact(c.Postgres.DSN),
+		"tracing.exporter":        c.Tracing.Exporter,
+		"tracing.endpoint":        redact(c.Tracing.Endpoint),
+		"tracing.serviceName":     c.Tracing.ServiceName,
+		"idempotency.ttl":         c.Idempotency.TTL.String(),
+		"auth.enabled":            c.Auth.Enabled,
+		"auth.hmacSecret":         secret(c.Auth.HMACSecret),
+		"auth.rsaPublicKeyFile":   c.Auth.RSAPublicKeyFile,
+		"auth.issuer":             c.Auth.Issuer,
+		"auth.audience":           c.Auth.Audience,
+		"redis.url":               redact(c.Redis.URL),
+		"rateLimit.enabled":       c.RateLimit.Enabled,
+		"rateLimit.default":       formatRouteLimit(c.RateLimit.Default),
+		"rateLimit.routes":        formatRouteLimits(c.RateLimit.Routes),
+		"rateLimit.perIP":         formatRouteLimit(c.RateLimit.PerIP),
+		"rateLimit.perAccount":    formatRouteLimit(c.RateLimit.PerAccount),
+		"accountCache.backend":    c.AccountCache.Backend,
+		"accountCache.ttl":        c.AccountCache.TTL.String(),
+		"accountCache.size":       c.AccountCache.Size,
+	}
+}
+
+// keywordPassword matches the password of a key=value connection string
+var keywordPassword = regexp.MustCompile(`(?i)(password\s*=\s*)('[^']*'|\S+)`)
+
+// redact masks the password of a URL or key=value connection string
+func redact(connection string) string {
+	u, err := url.Parse(connection)
+	if err != nil || u.Scheme == "" {
+		return keywordPassword.ReplaceAllString(connection, "${1}xxxxx")
+	}
+
+	// Drivers also accept the password as a query parameter
+	if query := u.Query(); query.Has("password") {
+		query.Set("password", "xxxxx")
+		u.RawQuery = query.Encode()
+	}
+	return u.Redacted()
+}
+
+// secret hides a secret value, showing only whether it is set
+func secret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "xxxxx"
+}
+
+// parseRouteLimits reads "METHOD /path=requests/window" pairs separated by
+// commas
+func parseRouteLimits(value string) (map[string]RateLimitRule, error) {
+	routes := map[string]RateLimitRule{}
+	for _, item := range splitList(value) {
+		route, limit, ok := strings.Cut(item, "=")
+		if !ok || !strings.Contains(limit, "/") {
+			return nil, fmt.Errorf("route limit %q must look like POST /api/v1/transactions=30/1m", item)
+		}
+		rule, err := parseRouteLimit(limit)
+		if err != nil {
+			return nil, fmt.Errorf("route limit %q: %w", item, err)
+		}
+		routes[strings.TrimSpace(route)] = rule
+	}
+	return routes, nil
+}
+
+// parseRouteLimit reads a "requests/window" limit, such as 30/1m
+func parseRouteLimit(value string) (RateLimitRule, error) {
+	requests, window, ok := strings.Cut(value, "/")
+	if !ok {
+		return RateLimitRule{}, fmt.Errorf("limit %q must look like 30/1m", value)
+	}
+	var rule RateLimitRule
+	var err error
+	if rule.Requests, err = strconv.Atoi(strings.TrimSpace(requests)); err != nil {
+		return RateLimitRule{}, err
+	}
+	if rule.Window, err = time.ParseDuration(strings.TrimSpace(window)); err != nil {
+		return RateLimitRule{}, err
+	}
+	return rule, nil
+}
+
+func formatRouteLimit(rule RateLimitRule) string {
+	return fmt.Sprintf("%d/%s", rule.Requests, rule.Window)
+}
+
+// formatRouteLimits is the inverse of parseRouteLimits, sorted by route
+func formatRouteLimits(routes map[string]RateLimitRule) string {
+	items := make([]string, 0, len(routes))
+	for route, rule := range routes {
+		items = append(items, route+"="+formatRouteLimit(rule))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+// ParseTrustedProxies parses proxy addresses and CIDR ranges; a single
+// address is a range of one
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("trusted proxy %q must be an IP address or CIDR range", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q must be an IP address or CIDR range", proxy)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -1,38 +1,106 @@
 package handlers
 
 import (
+	"context"
+	"finance_app/src/auth"
+	"finance_app/src/ratelimit"
 	"finance_app/src/repositories"
 	"finance_app/src/services"
+	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 // AppHandler holds dependencies like the DB client and services
 type AppHandler struct {
-	TransactionRepository repositories.TransactionMongoRepository
-	AccountsRepository    repositories.AccountsMongoRepository
+	TransactionRepository repositories.TransactionRepository
+	AccountsRepository    repositories.AccountRepository
+	IdempotencyRepository repositories.IdempotencyRepository
+	LedgerRepository      repositories.LedgerRepository
+	APIKeyRepository      repositories.APIKeyRepository
+	AuditRepository       repositories.AuditRepository
 	TransactionService    *services.TransactionHandler
 	AccountService        *services.AccountHandler
-	Client                *mongo.Client
+	LedgerService         *services.LedgerHandler
+	HealthService         *services.HealthHandler
+	APIKeyService         *services.APIKeyHandler
+	AuditService          *services.AuditHandler
+	// Client is nil when the app runs on a non-Mongo storage backend
+	Client    *mongo.Client
+	Lifecycle *Lifecycle
+	// Auth verifies bearer tokens; nil disables authentication
+	Auth *auth.Verifier
+	// RateLimiter counts requests against RateLimits; nil disables rate
+	// limiting
+	RateLimiter ratelimit.Limiter
+	RateLimits  ratelimit.Policy
 }
 
 // NewAppHandler creates a new AppHandler with initialized services
-func NewAppHandler(client *mongo.Client, transactionRepo repositories.TransactionMongoRepository, accountsRepo repositories.AccountsMongoRepository) *AppHandler {
+func NewAppHandler(client *mongo.Client, transactor repositories.Transactor, transactionRepo repositories.TransactionRepository, accountsRepo repositories.AccountRepository, idempotencyRepo repositories.IdempotencyRepository, ledgerRepo repositories.LedgerRepository, apiKeyRepo repositories.APIKeyRepository, auditRepo repositories.AuditRepository) *AppHandler {
 	transactionService := &services.TransactionHandler{
 		TransactionsRepo: transactionRepo,
 		AccountsRepo:     accountsRepo,
+		LedgerRepo:       ledgerRepo,
+		AuditRepo:        auditRepo,
+		Transactor:       transactor,
 	}
 
 	accountService := &services.AccountHandler{
 		AccountsRepo:     accountsRepo,
 		TransactionsRepo: transactionRepo,
+		LedgerRepo:       ledgerRepo,
+		AuditRepo:        auditRepo,
+		Transactor:       transactor,
+	}
+
+	ledgerService := &services.LedgerHandler{
+		LedgerRepo:   ledgerRepo,
+		AccountsRepo: accountsRepo,
+		Transactor:   transactor,
+	}
+
+	apiKeyService := &services.APIKeyHandler{
+		APIKeysRepo: apiKeyRepo,
+	}
+
+	auditService := &services.AuditHandler{
+		AuditRepo: auditRepo,
+	}
+
+	lifecycle := &Lifecycle{}
+
+	// Callers add the checks of their storage backend
+	healthService := &services.HealthHandler{
+		Timeout:  2 * time.Second,
+		Version:  "dev",
+		Draining: lifecycle.Draining,
+	}
+	if client != nil {
+		healthService.Checks = append(healthService.Checks, services.HealthCheck{
+			Name:     "mongo",
+			Required: true,
+			Check: func(ctx context.Context) error {
+				return client.Ping(ctx, readpref.Primary())
+			},
+		})
 	}
 
 	return &AppHandler{
 		TransactionRepository: transactionRepo,
 		AccountsRepository:    accountsRepo,
+		IdempotencyRepository: idempotencyRepo,
+		LedgerRepository:      ledgerRepo,
+		APIKeyRepository:      apiKeyRepo,
+		AuditRepository:       auditRepo,
 		TransactionService:    transactionService,
 		AccountService:        accountService,
+		LedgerService:         ledgerService,
+		HealthService:         healthService,
+		APIKeyService:         apiKeyService,
+		AuditService:          auditService,
 		Client:                client,
+		Lifecycle:             lifecycle,
 	}
 }
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Lifecycle tracks whether the app is shutting down and which requests are
+// still running, so storage is only closed once every handler has returned
+type Lifecycle struct {
+	draining atomic.Bool
+	inFlight sync.WaitGroup
+}
+
+// StartDraining marks the app as shutting down, which fails readiness checks
+func (l *Lifecycle) StartDraining() {
+	l.draining.Store(true)
+}
+
+// Draining reports whether StartDraining has been called
+func (l *Lifecycle) Draining() bool {
+	return l.draining.Load()
+}
+
+// Track is middleware that counts the requests Wait waits for
+func (l *Lifecycle) Track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.inFlight.Add(1)
+		defer l.inFlight.Done()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Wait blocks until every tracked request has returned. Call it only once the
+// server has stopped accepting requests.
+func (l *Lifecycle) Wait() {
+	l.inFlight.Wait()
+}
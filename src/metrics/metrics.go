@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "finance_http_requests_total",
+		Help: "HTTP requests handled, by method, chi route pattern and status code.",
+	}, []string{"method", "route", "status"})
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "finance_http_request_duration_seconds",
+		Help:    "HTTP request latency, by method and chi route pattern.",
+		Buckets: DefaultBuckets,
+	}, []string{"method", "route"})
+
+	RateLimited = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "finance_rate_limited_requests_total",
+		Help: "Requests rejected with 429, by method and chi route pattern.",
+	}, []string{"method", "route"})
+
+	RepositoryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "finance_repository_operation_duration_seconds",
+		Help:    "Storage latency per repository method, by backend. Streaming methods include the time spent in their callback.",
+		Buckets: DefaultBuckets,
+	}, []string{"backend", "repository", "method"})
+
+	CacheRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "finance_cache_requests_total",
+		Help: "Cache lookups, by cache and result: hit, miss or error. Reads that bypass the cache are not counted.",
+	}, []string{"cache", "result"})
+
+	Transactions = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "finance_transactions_total",
+		Help: "Transaction requests, by type and outcome: created, rejected (client error) or failed (server error).",
+	}, []string{"type", "outcome"})
+	RejectedWithdrawals = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "finance_withdrawals_rejected_total",
+		Help: "Debits refused, by transaction type and reason: insufficient_funds or account_status.",
+	}, []string{"type", "reason"})
+
+	BalanceUnderManagement = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "finance_balance_under_management",
+		Help: "Sum of all account balances in major currency units, recomputed every minute.",
+	})
+)
+
+// Transaction outcomes
+const (
+	OutcomeCreated  = "created"
+	OutcomeRejected = "rejected"
+	OutcomeFailed   = "failed"
+)
+
+// Cache lookup results
+const (
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+)
+
+// Reasons a debit is rejected
+const (
+	ReasonInsufficientFunds = "insufficient_funds"
+	ReasonAccountStatus     = "account_status"
+)
+
+// Outcome classifies the HTTP status a transaction request was answered with
+func Outcome(status int) string {
+	switch {
+	case status < 400:
+		return OutcomeCreated
+	case status < 500:
+		return OutcomeRejected
+	default:
+		return OutcomeFailed
+	}
+}
+
+// Middleware records HTTP request counts and latency. Requests are labeled
+// with their chi route pattern rather than their path so IDs do not create a
+// series each.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		defer func() {
+			route := "unmatched"
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			HTTPRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+			HTTPRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
@@ -0,0 +1,53 @@
+// Package metrics exposes application metrics for Prometheus to scrape
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+// Default is the registry served on /metrics. It holds the application's
+// metrics only, not the Go runtime ones of the global registry.
+var Default = prometheus.NewRegistry()
+
+// factory registers the metrics it creates in Default
+var factory = promauto.With(Default)
+
+// DefaultBuckets suit latencies in seconds, from 5ms to 10s
+var DefaultBuckets = prometheus.DefBuckets
+
+// Handler serves every metric in Default in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Default, promhttp.HandlerOpts{})
+}
+
+// Poll sets gauge to the result of fn now and every interval after that
+// until ctx is done, so scrapes never wait on fn. A failed poll is logged and
+// keeps the last value.
+func Poll(ctx context.Context, gauge prometheus.Gauge, interval time.Duration, fn func(ctx context.Context) (float64, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		pollCtx, cancel := context.WithTimeout(ctx, interval)
+		value, err := fn(pollCtx)
+		cancel()
+		switch {
+		case err == nil:
+			gauge.Set(value)
+		case ctx.Err() == nil:
+			logrus.Error("Failed to update gauge: ", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package metrics
+
+import (
+	"context"
+	"finance_app/src/models"
+	"finance_app/src/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// timer starts timing one repository call; the returned func records it
+func timer(backend, repository, method string) func() {
+	start := time.Now()
+	return func() {
+		RepositoryDuration.WithLabelValues(backend, repository, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// InstrumentAccounts records the latency of every call to next
+func InstrumentAccounts(next repositories.AccountRepository, backend string) repositories.AccountRepository {
+	return &accountsTimer{next: next, backend: backend}
+}
+
+type accountsTimer struct {
+	next    repositories.AccountRepository
+	backend string
+}
+
+func (a *accountsTimer) FindOne(ctx context.Context, id string) (*models.Accounts, error) {
+	defer timer(a.backend, "accounts", "FindOne")()
+	return a.next.FindOne(ctx, id)
+}
+
+func (a *accountsTimer) Credit(ctx context.Context, id string, amount models.Money) (*models.Accounts, error) {
+	defer timer(a.backend, "accounts", "Credit")()
+	return a.next.Credit(ctx, id, amount)
+}
+
+func (a *accountsTimer) Debit(ctx context.Context, id string, amount models.Money) (*models.Accounts, error) {
+	defer timer(a.backend, "accounts", "Debit")()
+	return a.next.Debit(ctx, id, amount)
+}
+
+func (a *accountsTimer) GetAllAccounts(ctx context.Context, page repositories.PageRequest) ([]models.Accounts, *repositories.PageCursor, error) {
+	defer timer(a.backend, "accounts", "GetAllAccounts")()
+	return a.next.GetAllAccounts(ctx, page)
+}
+
+func (a *accountsTimer) GetByOwner(ctx context.Context, owner string, page repositories.PageRequest) ([]models.Accounts, *repositories.PageCursor, error) {
+	defer timer(a.backend, "accounts", "GetByOwner")()
+	return a.next.GetByOwner(ctx, owner, page)
+}
+
+func (a *accountsTimer) ForEachAccount(ctx context.Context, fn func(*models.Accounts) error) error {
+	defer timer(a.backend, "accounts", "ForEachAccount")()
+	return a.next.ForEachAccount(ctx, fn)
+}
+
+func (a *accountsTimer) CreateAccount(ctx context.Context, account *models.Accounts) error {
+	defer timer(a.backend, "accounts", "CreateAccount")()
+	return a.next.CreateAccount(ctx, account)
+}
+
+func (a *accountsTimer) UpdateDetails(ctx context.Context, id string, update models.AccountUpdate) (*models.Accounts, error) {
+	defer timer(a.backend, "accounts", "UpdateDetails")()
+	return a.next.UpdateDetails(ctx, id, update)
+}
+
+func (a *accountsTimer) UpdateStatus(ctx context.Context, id string, status models.AccountStatus) (*models.Accounts, error) {
+	defer timer(a.backend, "accounts", "UpdateStatus")()
+	return a.next.UpdateStatus(ctx, id, status)
+}
+
+// InstrumentTransactions records the latency of every call to next
+func InstrumentTransactions(next repositories.TransactionRepository, backend string) repositories.TransactionRepository {
+	return &transactionsTimer{next: next, backend: backend}
+}
+
+type transactionsTimer struct {
+	next    repositories.TransactionRepository
+	backend string
+}
+
+func (t *transactionsTimer) Create(ctx context.Context, transaction *models.Transaction) error {
+	defer timer(t.backend, "transactions", "Create")()
+	return t.next.Create(ctx, transaction)
+}
+
+func (t *transactionsTimer) GetAllTransactions(ctx context.Context, query repositories.TransactionQuery) ([]models.Transaction, *repositories.PageCursor, error) {
+	defer timer(t.backend, "transactions", "GetAllTransactions")()
+	return t.next.GetAllTransactions(ctx, query)
+}
+
+func (t *transactionsTimer) GetByID(ctx context.Context, id string) (*models.Transaction, error) {
+	defer timer(t.backend, "transactions", "GetByID")()
+	return t.next.GetByID(ctx, id)
+}
+
+func (t *transactionsTimer) GetByAccountID(ctx context.Context, accountID string, query repositories.TransactionQuery) ([]*models.Transaction, *repositories.PageCursor, error) {
+	defer timer(t.backend, "transactions", "GetByAccountID")()
+	return t.next.GetByAccountID(ctx, accountID, query)
+}
+
+func (t *transactionsTimer) ForEachByAccountID(ctx context.Context, accountID string, fn func(*models.Transaction) error) error {
+	defer timer(t.backend, "transactions", "ForEachByAccountID")()
+	return t.next.ForEachByAccountID(ctx, accountID, fn)
+}
+
+func (t *transactionsTimer) SetBalance(ctx context.Context, id primitive.ObjectID, balance models.Money) error {
+	defer timer(t.backend, "transactions", "SetBalance")()
+	return t.next.SetBalance(ctx, id, balance)
+}
+
+func (t *transactionsTimer) AddReversal(ctx context.Context, id primitive.ObjectID, amount models.Money) (*models.Transaction, error) {
+	defer timer(t.backend, "transactions", "AddReversal")()
+	return t.next.AddReversal(ctx, id, amount)
+}
+
+func (t *transactionsTimer) GetReversals(ctx context.Context, id primitive.ObjectID) ([]models.Transaction, error) {
+	defer timer(t.backend, "transactions", "GetReversals")()
+	return t.next.GetReversals(ctx, id)
+}
+
+// InstrumentIdempotency records the latency of every call to next
+func InstrumentIdempotency(next repositories.IdempotencyRepository, backend string) repositories.IdempotencyRepository {
+	return &idempotencyTimer{next: next, backend: backend}
+}
+
+type idempotencyTimer struct {
+	next    repositories.IdempotencyRepository
+	backend string
+}
+
+func (i *idempotencyTimer) Reserve(ctx context.Context, key, fingerprint string) (*models.IdempotencyRecord, error) {
+	defer timer(i.backend, "idempotency", "Reserve")()
+	return i.next.Reserve(ctx, key, fingerprint)
+}
+
+func (i *idempotencyTimer) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error {
+	defer timer(i.backend, "idempotency", "Complete")()
+	return i.next.Complete(ctx, key, statusCode, contentType, body)
+}
+
+func (i *idempotencyTimer) Release(ctx context.Context, key string) error {
+	defer timer(i.backend, "idempotency", "Release")()
+	return i.next.Release(ctx, key)
+}
+
+// InstrumentLedger records the latency of every call to next
+func InstrumentLedger(next repositories.LedgerRepository, backend string) repositories.LedgerRepository {
+	return &ledgerTimer{next: next, backend: backend}
+}
+
+type ledgerTimer struct {
+	next    repositories.LedgerRepository
+	backend string
+}
+
+func (l *ledgerTimer) Post(ctx context.Context, entry *models.JournalEntry) error {
+	defer timer(l.backend, "ledger", "Post")()
+	return l.next.Post(ctx, entry)
+}
+
+func (l *ledgerTimer) Balance(ctx context.Context, account string) (models.Money, error) {
+	defer timer(l.backend, "ledger", "Balance")()
+	return l.next.Balance(ctx, account)
+}
+
+func (l *ledgerTimer) ForEachEntry(ctx context.Context, fn func(*models.JournalEntry) error) error {
+	defer timer(l.backend, "ledger", "ForEachEntry")()
+	return l.next.ForEachEntry(ctx, fn)
+}
+
+// InstrumentAPIKeys records the latency of every call to next
+func InstrumentAPIKeys(next repositories.APIKeyRepository, backend string) repositories.APIKeyRepository {
+	return &apiKeysTimer{next: next, backend: backend}
+}
+
+type apiKeysTimer struct {
+	next    repositories.APIKeyRepository
+	backend string
+}
+
+func (a *apiKeysTimer) Create(ctx context.Context, key *models.APIKey) error {
+	defer timer(a.backend, "api_keys", "Create")()
+	return a.next.Create(ctx, key)
+}
+
+func (a *apiKeysTimer) FindByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	defer timer(a.backend, "api_keys", "FindByHash")()
+	return a.next.FindByHash(ctx, hash)
+}
+
+func (a *apiKeysTimer) List(ctx context.Context) ([]models.APIKey, error) {
+	defer timer(a.backend, "api_keys", "List")()
+	return a.next.List(ctx)
+}
+
+func (a *apiKeysTimer) Revoke(ctx context.Context, id string) (*models.APIKey, error) {
+	defer timer(a.backend, "api_keys", "Revoke")()
+	return a.next.Revoke(ctx, id)
+}
+
+func (a *apiKeysTimer) MarkUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	defer timer(a.backend, "api_keys", "MarkUsed")()
+	return a.next.MarkUsed(ctx, id, at)
+}
+
+// InstrumentAudit records the latency of every call to next
+func InstrumentAudit(next repositories.AuditRepository, backend string) repositories.AuditRepository {
+	return &auditTimer{next: next, backend: backend}
+}
+
+type auditTimer struct {
+	next    repositories.AuditRepository
+	backend string
+}
+
+func (a *auditTimer) Append(ctx context.Context, event *models.AuditEvent) error {
+	defer timer(a.backend, "audit", "Append")()
+	return a.next.Append(ctx, event)
+}
+
+func (a *auditTimer) ChainPending(ctx context.Context) (int, error) {
+	defer timer(a.backend, "audit", "ChainPending")()
+	return a.next.ChainPending(ctx)
+}
+
+func (a *auditTimer) Find(ctx context.Context, query repositories.AuditQuery) ([]models.AuditEvent, *repositories.PageCursor, error) {
+	defer timer(a.backend, "audit", "Find")()
+	return a.next.Find(ctx, query)
+}
+
+func (a *auditTimer) ForEachEvent(ctx context.Context, fn func(*models.AuditEvent) error) error {
+	defer timer(a.backend, "audit", "ForEachEvent")()
+	return a.next.ForEachEvent(ctx, fn)
+}
@@ -2,11 +2,43 @@ package models
 
 import "go.mongodb.org/mongo-driver/bson/primitive"
 
+// AccountStatus is where an account is in its lifecycle
+type AccountStatus string
+
+const (
+	// AccountActive accounts accept deposits, withdrawals and transfers
+	AccountActive AccountStatus = "ACTIVE"
+	// AccountFrozen accounts keep their balance but no money can move
+	AccountFrozen AccountStatus = "FROZEN"
+	// AccountClosed accounts are kept for their history and never reopen
+	AccountClosed AccountStatus = "CLOSED"
+)
+
 type Accounts struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Balance   float64            `bson:"balance" json:"balance"`
-	Name      string             `bson:"name" json:"name"`
-	Email     string             `bson:"email" json:"email"`
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Balance Money              `bson:"balance" json:"balance"`
+	Name    string             `bson:"name" json:"name"`
+	Email   string             `bson:"email" json:"email"`
+	// Owner is the subject of the token that created the account; it is
+	// empty on accounts created without authentication
+	Owner string `bson:"owner,omitempty" json:"owner,omitempty"`
+	// Status is empty on accounts created before lifecycle states existed,
+	// which counts as active
+	Status    AccountStatus      `bson:"status,omitempty" json:"status,omitempty"`
 	CreatedAt primitive.DateTime `bson:"created_at,omitempty" json:"created_at,omitempty"`
 	UpdatedAt primitive.DateTime `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
 }
+
+// CurrentStatus returns the account status, treating an unset one as active
+func (a *Accounts) CurrentStatus() AccountStatus {
+	if a.Status == "" {
+		return AccountActive
+	}
+	return a.Status
+}
+
+// AccountUpdate lists the account details to change; nil fields are kept
+type AccountUpdate struct {
+	Name  *string `json:"name,omitempty"`
+	Email *string `json:"email,omitempty"`
+}
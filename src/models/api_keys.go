@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKey is a credential for a machine client. Only a hash of the key is
+// stored; the key itself is shown once, when it is created.
+type APIKey struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name string             `bson:"name" json:"name"`
+	// Prefix is the start of the key, enough to tell keys apart in a list
+	Prefix string   `bson:"prefix" json:"prefix"`
+	Hash   string   `bson:"hash" json:"-"`
+	Scopes []string `bson:"scopes" json:"scopes"`
+	// CreatedBy is the subject of the admin that created the key
+	CreatedBy  string              `bson:"createdBy,omitempty" json:"createdBy,omitempty"`
+	CreatedAt  primitive.DateTime  `bson:"created_at" json:"created_at"`
+	ExpiresAt  primitive.DateTime  `bson:"expires_at" json:"expires_at"`
+	LastUsedAt *primitive.DateTime `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	RevokedAt  *primitive.DateTime `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the key can still authenticate at now
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && now.Before(k.ExpiresAt.Time())
+}
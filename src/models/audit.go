@@ -0,0 +1,86 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audit actions, one per kind of change made through the API
+const (
+	AuditAccountCreated      = "account.created"
+	AuditAccountUpdated      = "account.updated"
+	AuditAccountFrozen       = "account.frozen"
+	AuditAccountUnfrozen     = "account.unfrozen"
+	AuditAccountClosed       = "account.closed"
+	AuditTransactionCreated  = "transaction.created"
+	AuditTransactionReversed = "transaction.reversed"
+	// AuditAccessDenied records a request refused for lack of a permission
+	AuditAccessDenied = "access.denied"
+)
+
+// BalanceSnapshot is the balance of an account a change touched, before and
+// after it
+type BalanceSnapshot struct {
+	AccountId string `json:"accountId" bson:"accountId"`
+	// Before is nil for an account the change created
+	Before *Money `json:"before" bson:"before"`
+	After  Money  `json:"after" bson:"after"`
+}
+
+// AuditEvent records one change made through the API, or one request refused
+// for lack of a permission: who made it, from where, through which endpoint,
+// and what it did to balances. Events are
+// chained: each hash covers the event and the hash of the one before it, so
+// editing, removing or reordering a stored event breaks the chain from there
+// on.
+type AuditEvent struct {
+	ID primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	// Sequence numbers the events from 1 in the order they were chained
+	Sequence int64 `json:"sequence" bson:"sequence"`
+	// Actor is the subject of the principal that made the change
+	Actor     string `json:"actor" bson:"actor"`
+	RequestID string `json:"requestId,omitempty" bson:"requestId,omitempty"`
+	ClientIP  string `json:"clientIp" bson:"clientIp"`
+	// Endpoint is the method and route, such as PATCH /api/v1/accounts/{id}
+	Endpoint string `json:"endpoint" bson:"endpoint"`
+	Action   string `json:"action" bson:"action"`
+	// Permission is the one a refused request lacked
+	Permission string `json:"permission,omitempty" bson:"permission,omitempty"`
+	// TransactionId is set for changes that created a transaction
+	TransactionId string             `json:"transactionId,omitempty" bson:"transactionId,omitempty"`
+	Balances      []BalanceSnapshot  `json:"balances" bson:"balances"`
+	CreatedAt     primitive.DateTime `json:"createdAt" bson:"created_at"`
+	// PrevHash is the hash of the previous event, empty for the first
+	PrevHash string `json:"prevHash" bson:"prevHash"`
+	Hash     string `json:"hash" bson:"hash"`
+}
+
+// ComputeHash returns the hex SHA-256 of PrevHash and the event's contents.
+// The ID and the stored hash are not covered.
+func (e *AuditEvent) ComputeHash() string {
+	balances := e.Balances
+	if balances == nil {
+		balances = []BalanceSnapshot{}
+	}
+
+	// Field order is fixed by the struct, and Money always marshals with two
+	// decimals, so the same event always encodes to the same bytes
+	content, _ := json.Marshal(struct {
+		Sequence      int64             `json:"sequence"`
+		Actor         string            `json:"actor"`
+		RequestID     string            `json:"requestId"`
+		ClientIP      string            `json:"clientIp"`
+		Endpoint      string            `json:"endpoint"`
+		Action        string            `json:"action"`
+		Permission    string            `json:"permission"`
+		TransactionId string            `json:"transactionId"`
+		Balances      []BalanceSnapshot `json:"balances"`
+		CreatedAt     int64             `json:"createdAt"`
+	}{e.Sequence, e.Actor, e.RequestID, e.ClientIP, e.Endpoint, e.Action, e.Permission, e.TransactionId, balances, int64(e.CreatedAt)})
+
+	sum := sha256.Sum256(append([]byte(e.PrevHash), content...))
+	return hex.EncodeToString(sum[:])
+}
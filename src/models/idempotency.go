@@ -0,0 +1,17 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// IdempotencyRecord remembers a request made with an Idempotency-Key and,
+// once it has finished, the response that was sent for it
+type IdempotencyRecord struct {
+	// ID is the client key scoped to the method and path it was used on
+	ID          string             `bson:"_id" json:"id"`
+	Fingerprint string             `bson:"fingerprint" json:"fingerprint"`
+	Completed   bool               `bson:"completed" json:"completed"`
+	StatusCode  int                `bson:"statusCode,omitempty" json:"statusCode,omitempty"`
+	ContentType string             `bson:"contentType,omitempty" json:"contentType,omitempty"`
+	Body        []byte             `bson:"body,omitempty" json:"-"`
+	CreatedAt   primitive.DateTime `bson:"created_at" json:"created_at"`
+	ExpiresAt   primitive.DateTime `bson:"expires_at" json:"expires_at"`
+}
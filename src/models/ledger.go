@@ -0,0 +1,83 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// System ledger accounts stand in for the world outside the app: deposits are
+// posted against cash in and withdrawals against cash out
+const (
+	LedgerCashIn  = "system:cash_in"
+	LedgerCashOut = "system:cash_out"
+)
+
+// IsSystemLedgerAccount reports whether account is one of the system accounts
+// rather than a customer account ID
+func IsSystemLedgerAccount(account string) bool {
+	return account == LedgerCashIn || account == LedgerCashOut
+}
+
+// LedgerLeg is one side of a journal entry. A positive amount credits the
+// ledger account (its balance goes up) and a negative amount debits it.
+type LedgerLeg struct {
+	// Account is a customer account ID in hex or a system ledger account
+	Account string `json:"account" bson:"account"`
+	Amount  Money  `json:"amount" bson:"amount"`
+}
+
+// JournalEntry is one balanced posting: its legs always sum to zero, so money
+// only ever moves between ledger accounts
+type JournalEntry struct {
+	ID primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	// TransactionId is the transaction that caused the entry; it is empty for
+	// opening balances
+	TransactionId *primitive.ObjectID `json:"transactionId,omitempty" bson:"transactionId,omitempty"`
+	Description   string              `json:"description" bson:"description"`
+	Legs          []LedgerLeg         `json:"legs" bson:"legs"`
+	CreatedAt     primitive.DateTime  `json:"createdAt" bson:"created_at"`
+}
+
+// Sum adds up the legs; it is zero for a balanced entry
+func (e *JournalEntry) Sum() Money {
+	var sum Money
+	for _, leg := range e.Legs {
+		sum = sum.Add(leg.Amount)
+	}
+	return sum
+}
+
+// Validate checks that the entry has at least two non-zero legs and balances
+func (e *JournalEntry) Validate() error {
+	if len(e.Legs) < 2 {
+		return errors.New("journal entry needs at least two legs")
+	}
+
+	for _, leg := range e.Legs {
+		if leg.Account == "" {
+			return errors.New("journal leg account is required")
+		}
+		if leg.Amount.IsZero() {
+			return fmt.Errorf("journal leg for %s has a zero amount", leg.Account)
+		}
+	}
+
+	if sum := e.Sum(); !sum.IsZero() {
+		return fmt.Errorf("journal entry is unbalanced by %s", sum)
+	}
+
+	return nil
+}
+
+// NewTransferEntry moves amount from one ledger account to another
+func NewTransferEntry(description, from, to string, amount Money) *JournalEntry {
+	return &JournalEntry{
+		Description: description,
+		Legs: []LedgerLeg{
+			{Account: from, Amount: amount.Neg()},
+			{Account: to, Amount: amount},
+		},
+	}
+}
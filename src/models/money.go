@@ -0,0 +1,383 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// MoneyScale is the number of decimal places a Money value carries
+const MoneyScale = 2
+
+const minorPerUnit = 100
+
+// MaxAmount is the largest amount, positive or negative, a request may carry.
+// It keeps sums of amounts and balances far from the int64 limit.
+var MaxAmount = Money{minor: 1_000_000_000_000 * minorPerUnit}
+
+// maxExponent bounds the exponent of amounts written like 1.5e3. No amount
+// that fits in int64 minor units needs more.
+const maxExponent = 20
+
+// ErrAmountOutOfRange is returned for amounts too large to hold, and by
+// CheckedAdd and CheckedSub when a result would be
+var ErrAmountOutOfRange = errors.New("amount is out of range")
+
+// Money is an exact monetary amount held as integer minor units (cents).
+// It is stored in MongoDB as Decimal128 and written to JSON as a plain number
+// with exactly two decimal places, so values round-trip without drift.
+type Money struct {
+	minor int64
+}
+
+// NewMoneyFromMinor builds a Money value from minor units, e.g. 1050 is 10.50
+func NewMoneyFromMinor(minor int64) Money {
+	return Money{minor: minor}
+}
+
+// ParseMoney parses a decimal string such as "10", "-3.5" or "0.25" exactly.
+// More than two decimal places is an error rather than being rounded.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Money{}, errors.New("amount cannot be empty")
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, frac, hasDot := strings.Cut(s, ".")
+	if whole == "" && (!hasDot || frac == "") {
+		return Money{}, fmt.Errorf("invalid amount: %q", s)
+	}
+	if len(frac) > MoneyScale {
+		return Money{}, fmt.Errorf("amount %q has more than %d decimal places", s, MoneyScale)
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("invalid amount: %q", s)
+	}
+
+	frac += strings.Repeat("0", MoneyScale-len(frac))
+	if whole == "" {
+		whole = "0"
+	}
+
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrAmountOutOfRange, s)
+	}
+
+	if negative {
+		minor = -minor
+	}
+
+	return Money{minor: minor}, nil
+}
+
+// MustParseMoney is ParseMoney for constants; it panics on invalid input
+func MustParseMoney(s string) Money {
+	m, err := ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// moneyFromFloat rounds a legacy float64 amount to the nearest minor unit
+func moneyFromFloat(f float64) Money {
+	return Money{minor: int64(math.Round(f * minorPerUnit))}
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Minor returns the amount in minor units
+func (m Money) Minor() int64 {
+	return m.minor
+}
+
+// Add and Sub are for amounts bounded by MaxAmount; sums of balances go
+// through CheckedAdd and CheckedSub
+func (m Money) Add(other Money) Money {
+	return Money{minor: m.minor + other.minor}
+}
+
+func (m Money) Sub(other Money) Money {
+	return Money{minor: m.minor - other.minor}
+}
+
+// CheckedAdd is Add that returns ErrAmountOutOfRange instead of overflowing
+func (m Money) CheckedAdd(other Money) (Money, error) {
+	sum := m.minor + other.minor
+	if (other.minor > 0 && sum < m.minor) || (other.minor < 0 && sum > m.minor) {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrAmountOutOfRange, m, other)
+	}
+	return Money{minor: sum}, nil
+}
+
+// CheckedSub is Sub that returns ErrAmountOutOfRange instead of overflowing
+func (m Money) CheckedSub(other Money) (Money, error) {
+	difference := m.minor - other.minor
+	if (other.minor > 0 && difference > m.minor) || (other.minor < 0 && difference < m.minor) {
+		return Money{}, fmt.Errorf("%w: %s - %s", ErrAmountOutOfRange, m, other)
+	}
+	return Money{minor: difference}, nil
+}
+
+func (m Money) Neg() Money {
+	return Money{minor: -m.minor}
+}
+
+// Cmp returns -1, 0 or 1 when m is less than, equal to or greater than other
+func (m Money) Cmp(other Money) int {
+	switch {
+	case m.minor < other.minor:
+		return -1
+	case m.minor > other.minor:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (m Money) LessThan(other Money) bool {
+	return m.minor < other.minor
+}
+
+func (m Money) IsZero() bool {
+	return m.minor == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.minor > 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.minor < 0
+}
+
+// String formats the amount with exactly two decimal places, e.g. "-3.50"
+func (m Money) String() string {
+	minor := m.minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, minor/minorPerUnit, minor%minorPerUnit)
+}
+
+// MarshalJSON writes the amount as a JSON number, e.g. 1500.00
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a quoted decimal string and parses
+// the literal text, never going through float64. Amounts beyond MaxAmount are
+// rejected with ErrAmountOutOfRange.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	text := string(data)
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	} else if strings.ContainsAny(text, "eE") {
+		expanded, err := expandExponent(text)
+		if err != nil {
+			return err
+		}
+		text = expanded
+	}
+
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+
+	if MaxAmount.LessThan(parsed) || parsed.LessThan(MaxAmount.Neg()) {
+		return fmt.Errorf("%w: %s is beyond %s", ErrAmountOutOfRange, parsed, MaxAmount)
+	}
+
+	*m = parsed
+	return nil
+}
+
+// expandExponent rewrites a number in exponent notation, such as 1.5e2, as
+// plain decimal text by moving the decimal point, refusing exponents beyond
+// maxExponent before writing out a single digit
+func expandExponent(text string) (string, error) {
+	mantissa, exponent, _ := strings.Cut(strings.ToLower(text), "e")
+	exp, err := strconv.Atoi(strings.TrimPrefix(exponent, "+"))
+	if err != nil {
+		return "", fmt.Errorf("invalid amount: %s", text)
+	}
+	if exp > maxExponent || exp < -maxExponent {
+		return "", fmt.Errorf("%w: %s", ErrAmountOutOfRange, text)
+	}
+
+	sign := ""
+	if strings.HasPrefix(mantissa, "-") {
+		sign, mantissa = "-", mantissa[1:]
+	}
+	whole, frac, _ := strings.Cut(mantissa, ".")
+	if whole+frac == "" || !isDigits(whole) || !isDigits(frac) {
+		return "", fmt.Errorf("invalid amount: %s", text)
+	}
+
+	digits := whole + frac
+	point := len(whole) + exp
+	if point < 0 {
+		digits = strings.Repeat("0", -point) + digits
+		point = 0
+	}
+	if point > len(digits) {
+		digits += strings.Repeat("0", point-len(digits))
+	}
+
+	// Trailing zeros after the point are not extra precision
+	return sign + digits[:point] + "." + strings.TrimRight(digits[point:], "0"), nil
+}
+
+// MarshalBSONValue stores the amount as Decimal128
+func (m Money) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	dec, err := primitive.ParseDecimal128(m.String())
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to encode amount: %w", err)
+	}
+	return bsontype.Decimal128, bsoncore.AppendDecimal128(nil, dec), nil
+}
+
+// UnmarshalBSONValue reads Decimal128 as well as the double and integer
+// values written before amounts were stored exactly
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bsontype.Decimal128:
+		dec, _, ok := bsoncore.ReadDecimal128(data)
+		if !ok {
+			return errors.New("failed to decode Decimal128 amount")
+		}
+		parsed, err := moneyFromDecimal128(dec)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case bsontype.Double:
+		f, _, ok := bsoncore.ReadDouble(data)
+		if !ok {
+			return errors.New("failed to decode double amount")
+		}
+		*m = moneyFromFloat(f)
+	case bsontype.Int32:
+		i, _, ok := bsoncore.ReadInt32(data)
+		if !ok {
+			return errors.New("failed to decode int32 amount")
+		}
+		*m = Money{minor: int64(i) * minorPerUnit}
+	case bsontype.Int64:
+		i, _, ok := bsoncore.ReadInt64(data)
+		if !ok {
+			return errors.New("failed to decode int64 amount")
+		}
+		*m = Money{minor: i * minorPerUnit}
+	case bsontype.Null, bsontype.Undefined:
+		*m = Money{}
+	default:
+		return fmt.Errorf("cannot decode %s into an amount", t)
+	}
+	return nil
+}
+
+// moneyFromDecimal128 converts a Decimal128 to minor units, rounding half away
+// from zero if it carries more than two decimal places
+func moneyFromDecimal128(dec primitive.Decimal128) (Money, error) {
+	coefficient, exp, err := dec.BigInt()
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid Decimal128 amount: %w", err)
+	}
+
+	shift := exp + MoneyScale
+	ten := big.NewInt(10)
+	if shift >= 0 {
+		coefficient.Mul(coefficient, new(big.Int).Exp(ten, big.NewInt(int64(shift)), nil))
+	} else {
+		divisor := new(big.Int).Exp(ten, big.NewInt(int64(-shift)), nil)
+		quotient, remainder := new(big.Int).QuoRem(coefficient, divisor, new(big.Int))
+		if new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(divisor) >= 0 {
+			quotient.Add(quotient, big.NewInt(int64(coefficient.Sign())))
+		}
+		coefficient = quotient
+	}
+
+	if !coefficient.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s", ErrAmountOutOfRange, dec.String())
+	}
+
+	return Money{minor: coefficient.Int64()}, nil
+}
+
+// Value stores the amount in a SQL NUMERIC column as its exact decimal text
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads a SQL NUMERIC column, which drivers hand over as text
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		return m.scanText(v)
+	case []byte:
+		return m.scanText(string(v))
+	case int64:
+		*m = Money{minor: v * minorPerUnit}
+	case float64:
+		*m = moneyFromFloat(v)
+	case nil:
+		*m = Money{}
+	default:
+		return fmt.Errorf("cannot scan %T into an amount", src)
+	}
+	return nil
+}
+
+// scanText parses NUMERIC text, which may carry trailing zeros beyond two
+// decimal places depending on the column's scale
+func (m *Money) scanText(text string) error {
+	if whole, frac, ok := strings.Cut(text, "."); ok && len(frac) > MoneyScale {
+		trimmed := strings.TrimRight(frac[MoneyScale:], "0")
+		if trimmed != "" {
+			return fmt.Errorf("amount %q has more than %d decimal places", text, MoneyScale)
+		}
+		text = whole + "." + frac[:MoneyScale]
+	}
+
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
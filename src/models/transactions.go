@@ -9,15 +9,71 @@ const (
 	Deposit  TransactionType = "DEPOSIT"
 	Withdraw TransactionType = "WITHDRAW"
 	Transfer TransactionType = "TRANSFER"
+	// Reversal gives back all or part of an earlier transaction
+	Reversal TransactionType = "REVERSAL"
+)
+
+// TransferDirection tells which side of a transfer a transaction record
+// belongs to, and for a reversal whether money left or entered the account
+type TransferDirection string
+
+const (
+	TransferOut TransferDirection = "OUT"
+	TransferIn  TransferDirection = "IN"
+)
+
+// ReversalStatus tells how much of a transaction has been reversed
+type ReversalStatus string
+
+const (
+	PartiallyReversed ReversalStatus = "PARTIALLY_REVERSED"
+	FullyReversed     ReversalStatus = "REVERSED"
 )
 
 // Transaction model based on schema
 type Transaction struct {
 	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	TransactionType TransactionType    `bson:"transactionType" json:"transactionType"`
-	Amount          float64            `bson:"amount" json:"amount"`
-	Balance         float64            `bson:"balance" json:"balance"`
+	Amount          Money              `bson:"amount" json:"amount"`
+	Balance         Money              `bson:"balance" json:"balance"`
 	AccountId       primitive.ObjectID `bson:"accountId" json:"accountId"`
-	CreatedAt       primitive.DateTime `bson:"created_at,omitempty" json:"created_at,omitempty"`
-	UpdatedAt       primitive.DateTime `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	// Transfer fields: the other account and the matching record on its side.
+	// Reversals set them the same way, and the direction on their own.
+	Direction             TransferDirection   `bson:"direction,omitempty" json:"direction,omitempty"`
+	CounterpartyAccountId *primitive.ObjectID `bson:"counterpartyAccountId,omitempty" json:"counterpartyAccountId,omitempty"`
+	LinkedTransactionId   *primitive.ObjectID `bson:"linkedTransactionId,omitempty" json:"linkedTransactionId,omitempty"`
+	// ReversalOf is the transaction a reversal gives back
+	ReversalOf *primitive.ObjectID `bson:"reversalOf,omitempty" json:"reversalOf,omitempty"`
+	// ReversedAmount is how much of this transaction its reversals gave back
+	// so far, never more than Amount
+	ReversedAmount Money          `bson:"reversedAmount,omitempty" json:"reversedAmount,omitzero"`
+	ReversalStatus ReversalStatus `bson:"reversalStatus,omitempty" json:"reversalStatus,omitempty"`
+	// Reversals lists the reversals of this transaction, oldest first. It is
+	// not stored; GetTransactionByID fills it in.
+	Reversals []Transaction `bson:"-" json:"reversals,omitempty"`
+
+	CreatedAt primitive.DateTime `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt primitive.DateTime `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// SignedAmount is the change this transaction made to its account's balance
+func (t *Transaction) SignedAmount() Money {
+	switch {
+	case t.TransactionType == Withdraw:
+		return t.Amount.Neg()
+	case t.TransactionType == Transfer && t.Direction == TransferOut:
+		return t.Amount.Neg()
+	case t.TransactionType == Reversal && t.Direction == TransferOut:
+		return t.Amount.Neg()
+	default:
+		return t.Amount
+	}
+}
+
+// ReversibleAmount is how much of the transaction can still be reversed
+func (t *Transaction) ReversibleAmount() Money {
+	if t.TransactionType == Reversal {
+		return Money{}
+	}
+	return t.Amount.Sub(t.ReversedAmount)
 }
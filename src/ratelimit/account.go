@@ -0,0 +1,28 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+)
+
+// AccountLimit counts a request against the window of an account. Once the
+// window is full it answers the request and returns false.
+type AccountLimit func(w http.ResponseWriter, r *http.Request, accountID string) bool
+
+type accountLimitKey struct{}
+
+// WithAccountLimit returns ctx carrying limit, for the handler to charge
+// once it knows the request acts on an account the caller may use
+func WithAccountLimit(ctx context.Context, limit AccountLimit) context.Context {
+	return context.WithValue(ctx, accountLimitKey{}, limit)
+}
+
+// ChargeAccount counts r against the window of accountID with the limit of
+// its context. Requests without one are allowed.
+func ChargeAccount(w http.ResponseWriter, r *http.Request, accountID string) bool {
+	limit, ok := r.Context().Value(accountLimitKey{}).(AccountLimit)
+	if !ok {
+		return true
+	}
+	return limit(w, r, accountID)
+}
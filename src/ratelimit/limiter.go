@@ -0,0 +1,97 @@
+// Package ratelimit counts requests in sliding windows, in Redis so every
+// instance shares the count, or in process
+package ratelimit
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Rule allows Requests per Window
+type Rule struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Policy picks the rule of each route
+type Policy struct {
+	// Default applies to every route without a rule of its own, counting
+	// their requests together
+	Default Rule
+	// Routes maps "METHOD /pattern" chi routes to their own rules
+	Routes map[string]Rule
+	// PerIP limits each client IP across every route before it is
+	// authenticated. A zero rule does not limit.
+	PerIP Rule
+	// PerAccount limits the transactions on each account the caller may
+	// use, whichever clients send them. A zero rule does not limit.
+	PerAccount Rule
+}
+
+// Rule returns the rule of route and the bucket its requests are counted in
+func (p Policy) Rule(route string) (string, Rule) {
+	if rule, ok := p.Routes[route]; ok {
+		return route, rule
+	}
+	return "default", p.Default
+}
+
+// Result is the outcome of counting one request
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining is how many more requests the window allows
+	Remaining int
+	// Reset is how long until the oldest request leaves the window, freeing
+	// a slot
+	Reset time.Duration
+}
+
+// Limiter counts requests per key in a sliding window
+type Limiter interface {
+	// Allow counts a request for key under rule, unless the window is full
+	Allow(ctx context.Context, key string, rule Rule) (Result, error)
+}
+
+// Fallback uses Primary and, while it fails, Secondary. It lets the API keep
+// limiting per instance when Redis is unavailable.
+type Fallback struct {
+	Primary   Limiter
+	Secondary Limiter
+
+	degraded atomic.Bool
+}
+
+func (f *Fallback) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	result, err := f.Primary.Allow(ctx, key, rule)
+	if err == nil {
+		if f.degraded.CompareAndSwap(true, false) {
+			logrus.WithContext(ctx).Info("Rate limiter recovered, counting in Redis again")
+		}
+		return result, nil
+	}
+
+	// Log the switch only, not every request while it lasts
+	if f.degraded.CompareAndSwap(false, true) {
+		logrus.WithContext(ctx).Warn("Rate limiter failed, counting in process until it recovers: ", err)
+	}
+	return f.Secondary.Allow(ctx, key, rule)
+}
+
+// result builds the Result of a window holding count requests, whose oldest
+// leaves it after untilReset
+func result(allowed bool, rule Rule, count int, untilReset time.Duration) Result {
+	remaining := rule.Requests - count
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Result{
+		Allowed:   allowed,
+		Limit:     rule.Requests,
+		Remaining: remaining,
+		Reset:     untilReset,
+	}
+}
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle keys are dropped
+const sweepInterval = time.Minute
+
+// Memory counts requests in process. Each instance counts on its own, so
+// with several instances a client gets the limit from each.
+type Memory struct {
+	mu        sync.Mutex
+	requests  map[string][]time.Time
+	lastSweep time.Time
+	// longest is the longest window counted so far; keys idle for longer
+	// can be dropped
+	longest time.Duration
+	now     func() time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		requests: make(map[string][]time.Time),
+		now:      time.Now,
+	}
+}
+
+func (m *Memory) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if rule.Window > m.longest {
+		m.longest = rule.Window
+	}
+	if now.Sub(m.lastSweep) > sweepInterval {
+		m.sweep(now)
+		m.lastSweep = now
+	}
+
+	window := prune(m.requests[key], now.Add(-rule.Window))
+	allowed := len(window) < rule.Requests
+	if allowed {
+		window = append(window, now)
+	}
+	m.requests[key] = window
+
+	reset := rule.Window
+	if len(window) > 0 {
+		reset = window[0].Add(rule.Window).Sub(now)
+	}
+	return result(allowed, rule, len(window), reset), nil
+}
+
+// sweep drops keys without requests in the longest window, so clients that
+// went away do not hold memory
+func (m *Memory) sweep(now time.Time) {
+	for key, requests := range m.requests {
+		if len(requests) == 0 || now.Sub(requests[len(requests)-1]) > m.longest {
+			delete(m.requests, key)
+		}
+	}
+}
+
+// prune drops the requests at or before since, keeping the rest in order
+func prune(requests []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(requests) && !requests[i].After(since) {
+		i++
+	}
+	return requests[i:]
+}
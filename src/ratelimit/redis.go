@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindow keeps the requests of KEYS[1] in a sorted set scored by
+// arrival in microseconds, using the server's clock so every instance
+// agrees. ARGV is the window in microseconds, the limit and a member unique
+// to this request. It returns whether the request was allowed, the count in
+// the window and the microseconds until the oldest leaves it. It runs with
+// EVALSHA, falling back to EVAL when the server does not have it cached.
+var slidingWindow = redis.NewScript(`
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[3])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], math.ceil(window / 1000))
+
+local reset = window
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, count, reset}
+`)
+
+// Redis counts requests in Redis, shared by every instance
+type Redis struct {
+	Client *redis.Client
+	// Prefix namespaces the keys
+	Prefix string
+	// Timeout bounds each check, so a slow Redis fails over quickly instead
+	// of holding up requests
+	Timeout time.Duration
+}
+
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{Client: client, Prefix: "ratelimit:", Timeout: 250 * time.Millisecond}
+}
+
+func (l *Redis) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, l.Timeout)
+	defer cancel()
+
+	member := make([]byte, 8)
+	if _, err := rand.Read(member); err != nil {
+		return Result{}, err
+	}
+
+	counts, err := slidingWindow.Run(ctx, l.Client, []string{l.Prefix + key},
+		rule.Window.Microseconds(),
+		rule.Requests,
+		hex.EncodeToString(member)).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(counts) != 3 {
+		return Result{}, fmt.Errorf("unexpected rate limit reply %v", counts)
+	}
+
+	return result(counts[0] == 1, rule, int(counts[1]), time.Duration(counts[2])*time.Microsecond), nil
+}
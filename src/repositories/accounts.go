@@ -5,6 +5,7 @@ import (
 	"errors"
 	"finance_app/src/models"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -13,6 +14,54 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrAccountNotFound is returned when no account matches the given ID
+var ErrAccountNotFound = errors.New("account not found")
+
+// ErrDuplicateEmail is returned when another account already uses the email
+var ErrDuplicateEmail = errors.New("account with this email already exists")
+
+// ErrBalanceNotZero is returned when closing an account that still holds money
+var ErrBalanceNotZero = errors.New("account balance must be zero to close it")
+
+// AccountStatusError is returned when an account's lifecycle state does not
+// allow the requested change, such as moving money on a frozen account
+type AccountStatusError struct {
+	AccountID string
+	Status    models.AccountStatus
+}
+
+func (e *AccountStatusError) Error() string {
+	return fmt.Sprintf("account %s is %s", e.AccountID, strings.ToLower(string(e.Status)))
+}
+
+// blockedStatuses are the states in which no money can move
+var blockedStatuses = []models.AccountStatus{models.AccountFrozen, models.AccountClosed}
+
+// validateAccountUpdate checks the fields a PATCH may change
+func validateAccountUpdate(update models.AccountUpdate) error {
+	if update.Name == nil && update.Email == nil {
+		return errors.New("nothing to update")
+	}
+	if update.Name != nil && *update.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if update.Email != nil && *update.Email == "" {
+		return errors.New("email cannot be empty")
+	}
+	return nil
+}
+
+// InsufficientFundsError is returned when a debit is larger than the balance
+type InsufficientFundsError struct {
+	AccountID string
+	Requested models.Money
+	Available models.Money
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("insufficient funds: account %s has %s, requested %s", e.AccountID, e.Available, e.Requested)
+}
+
 type AccountsMongoRepository struct {
 	collection *mongo.Collection
 }
@@ -39,52 +88,132 @@ func (r *AccountsMongoRepository) FindOne(ctx context.Context, id string) (*mode
 	err = r.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&account)
 
 	if err != nil {
-		return nil, errors.New("account not found")
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch account: %w", err)
 	}
 
 	return &account, nil
 }
 
-func (r *AccountsMongoRepository) UpdateBalance(ctx context.Context, id string, newBalance float64) error {
-	account, err := r.FindOne(ctx, id)
+// Credit atomically adds amount to the account balance and returns the
+// updated account
+func (r *AccountsMongoRepository) Credit(ctx context.Context, id string, amount models.Money) (*models.Accounts, error) {
+	return r.applyBalanceChange(ctx, id, amount, false)
+}
+
+// Debit atomically subtracts amount from the account balance, but only if the
+// balance covers it. Otherwise it returns an *InsufficientFundsError and leaves
+// the balance untouched. Both Credit and Debit return an *AccountStatusError
+// for frozen and closed accounts.
+func (r *AccountsMongoRepository) Debit(ctx context.Context, id string, amount models.Money) (*models.Accounts, error) {
+	return r.applyBalanceChange(ctx, id, amount, true)
+}
+
+// applyBalanceChange runs a single guarded $inc so concurrent changes to the
+// same account can neither overwrite each other nor overdraw it
+func (r *AccountsMongoRepository) applyBalanceChange(ctx context.Context, id string, amount models.Money, debit bool) (*models.Accounts, error) {
+	if !amount.IsPositive() {
+		return nil, errors.New("amount must be greater than 0")
+	}
+
+	if id == "" {
+		return nil, errors.New("account ID cannot be empty")
+	}
 
+	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return err
+		return nil, errors.New("invalid account ID format")
 	}
 
-	if newBalance < 0 {
-		return errors.New("balance cannot be negative")
+	filter := bson.M{"_id": objID, "status": bson.M{"$nin": blockedStatuses}}
+	delta := amount
+	if debit {
+		filter["balance"] = bson.M{"$gte": amount}
+		delta = amount.Neg()
 	}
 
-	account.Balance = newBalance
+	update := bson.M{
+		"$inc": bson.M{"balance": delta},
+		"$set": bson.M{"updated_at": primitive.NewDateTimeFromTime(time.Now())},
+	}
 
-	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": account.ID}, bson.M{"$set": bson.M{"balance": newBalance}})
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var account models.Accounts
+	err = r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&account)
+	if err == nil {
+		return &account, nil
+	}
+
+	if err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to update balance: %w", err)
+	}
+
+	// Nothing matched: the account is missing, blocked or the guard failed
+	current, findErr := r.FindOne(ctx, id)
+	if findErr != nil {
+		return nil, findErr
+	}
+
+	if status := current.CurrentStatus(); status != models.AccountActive {
+		return nil, &AccountStatusError{AccountID: id, Status: status}
+	}
+
+	return nil, &InsufficientFundsError{
+		AccountID: id,
+		Requested: amount,
+		Available: current.Balance,
+	}
+}
 
+// GetAllAccounts returns one page of accounts, oldest first, and the cursor
+// of the next page if there is one
+func (r *AccountsMongoRepository) GetAllAccounts(ctx context.Context, page PageRequest) ([]models.Accounts, *PageCursor, error) {
+	accounts, next, err := findMongoPage(ctx, r.collection, bson.M{}, page, accountOrder, accountPageKey)
 	if err != nil {
-		return fmt.Errorf("failed to update balance: %w", err)
+		return nil, nil, fmt.Errorf("failed to fetch accounts: %w", err)
 	}
 
-	return nil
+	return accounts, next, nil
 }
 
-func (r *AccountsMongoRepository) GetAllAccounts(ctx context.Context) ([]models.Accounts, error) {
-	var accounts []models.Accounts
+// GetByOwner returns one page of the accounts owned by owner, oldest first,
+// and the cursor of the next page if there is one
+func (r *AccountsMongoRepository) GetByOwner(ctx context.Context, owner string, page PageRequest) ([]models.Accounts, *PageCursor, error) {
+	accounts, next, err := findMongoPage(ctx, r.collection, bson.M{"owner": owner}, page, accountOrder, accountPageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch accounts: %w", err)
+	}
 
-	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	return accounts, next, nil
+}
 
+// ForEachAccount streams every account oldest first without loading them all
+func (r *AccountsMongoRepository) ForEachAccount(ctx context.Context, fn func(*models.Accounts) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
 	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
-
 	if err != nil {
-		return nil, errors.New("failed to fetch accounts")
+		return fmt.Errorf("failed to fetch accounts: %w", err)
 	}
-
 	defer cursor.Close(ctx)
 
-	if err = cursor.All(ctx, &accounts); err != nil {
-		return nil, errors.New("failed to decode accounts")
+	for cursor.Next(ctx) {
+		var account models.Accounts
+		if err := cursor.Decode(&account); err != nil {
+			return fmt.Errorf("failed to decode account: %w", err)
+		}
+		if err := fn(&account); err != nil {
+			return err
+		}
 	}
 
-	return accounts, nil
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor error: %w", err)
+	}
+
+	return nil
 }
 
 func (r *AccountsMongoRepository) CreateAccount(ctx context.Context, account *models.Accounts) error {
@@ -95,14 +224,22 @@ func (r *AccountsMongoRepository) CreateAccount(ctx context.Context, account *mo
 
 	if err == nil && oldAccount != nil {
 		account.ID = oldAccount.ID
-		return errors.New("account with this email already exists")
+		return ErrDuplicateEmail
 	}
 
+	account.Status = models.AccountActive
 	account.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
 	account.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
 
-	// Insert into database
+	// Insert into database. The unique email index catches a concurrent
+	// insert for the same email that the lookup above missed.
 	result, err := r.collection.InsertOne(ctx, account)
+	if mongo.IsDuplicateKeyError(err) {
+		if r.collection.FindOne(ctx, bson.M{"email": account.Email}).Decode(oldAccount) == nil {
+			account.ID = oldAccount.ID
+		}
+		return ErrDuplicateEmail
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create account: %w", err)
 	}
@@ -114,3 +251,93 @@ func (r *AccountsMongoRepository) CreateAccount(ctx context.Context, account *mo
 
 	return nil
 }
+
+// UpdateDetails changes the name and email of an account that is not closed.
+// The email must not belong to any other account.
+func (r *AccountsMongoRepository) UpdateDetails(ctx context.Context, id string, update models.AccountUpdate) (*models.Accounts, error) {
+	objID, err := parseAccountID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateAccountUpdate(update); err != nil {
+		return nil, err
+	}
+
+	set := bson.M{"updated_at": primitive.NewDateTimeFromTime(time.Now())}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.Email != nil {
+		err := r.collection.FindOne(ctx, bson.M{"email": *update.Email, "_id": bson.M{"$ne": objID}}).Err()
+		if err == nil {
+			return nil, ErrDuplicateEmail
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("failed to check email: %w", err)
+		}
+		set["email"] = *update.Email
+	}
+
+	filter := bson.M{"_id": objID, "status": bson.M{"$ne": models.AccountClosed}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var account models.Accounts
+	err = r.collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": set}, opts).Decode(&account)
+	if err == nil {
+		return &account, nil
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrDuplicateEmail
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to update account: %w", err)
+	}
+
+	// Nothing matched: the account is missing or closed
+	current, findErr := r.FindOne(ctx, id)
+	if findErr != nil {
+		return nil, findErr
+	}
+	return nil, &AccountStatusError{AccountID: id, Status: current.CurrentStatus()}
+}
+
+// UpdateStatus moves an account to status. Closed accounts never change again,
+// and an account can only be closed once its balance is zero; both rules are
+// part of the update filter so they hold against concurrent balance changes.
+func (r *AccountsMongoRepository) UpdateStatus(ctx context.Context, id string, status models.AccountStatus) (*models.Accounts, error) {
+	objID, err := parseAccountID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"_id": objID, "status": bson.M{"$ne": models.AccountClosed}}
+	if status == models.AccountClosed {
+		filter["balance"] = models.Money{}
+	}
+
+	update := bson.M{"$set": bson.M{
+		"status":     status,
+		"updated_at": primitive.NewDateTimeFromTime(time.Now()),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var account models.Accounts
+	err = r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&account)
+	if err == nil {
+		return &account, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to update account status: %w", err)
+	}
+
+	// Nothing matched: the account is missing, already closed or not empty
+	current, findErr := r.FindOne(ctx, id)
+	if findErr != nil {
+		return nil, findErr
+	}
+	if current.CurrentStatus() == models.AccountClosed {
+		return nil, &AccountStatusError{AccountID: id, Status: models.AccountClosed}
+	}
+	return nil, ErrBalanceNotZero
+}
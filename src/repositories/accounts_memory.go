@@ -0,0 +1,285 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"finance_app/src/models"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AccountsMemoryRepository is a thread-safe in-memory AccountRepository with
+// the same validation and errors as AccountsMongoRepository
+type AccountsMemoryRepository struct {
+	mu       sync.RWMutex
+	accounts map[primitive.ObjectID]*models.Accounts
+}
+
+func NewAccountsMemoryRepository() *AccountsMemoryRepository {
+	return &AccountsMemoryRepository{
+		accounts: make(map[primitive.ObjectID]*models.Accounts),
+	}
+}
+
+func (r *AccountsMemoryRepository) FindOne(ctx context.Context, id string) (*models.Accounts, error) {
+	objID, err := parseAccountID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	account, ok := r.accounts[objID]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	copied := *account
+	return &copied, nil
+}
+
+func (r *AccountsMemoryRepository) Credit(ctx context.Context, id string, amount models.Money) (*models.Accounts, error) {
+	return r.applyBalanceChange(ctx, id, amount, false)
+}
+
+func (r *AccountsMemoryRepository) Debit(ctx context.Context, id string, amount models.Money) (*models.Accounts, error) {
+	return r.applyBalanceChange(ctx, id, amount, true)
+}
+
+func (r *AccountsMemoryRepository) applyBalanceChange(ctx context.Context, id string, amount models.Money, debit bool) (*models.Accounts, error) {
+	if !amount.IsPositive() {
+		return nil, errors.New("amount must be greater than 0")
+	}
+
+	objID, err := parseAccountID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[objID]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	if status := account.CurrentStatus(); status != models.AccountActive {
+		return nil, &AccountStatusError{AccountID: id, Status: status}
+	}
+
+	delta := amount
+	if debit {
+		if account.Balance.LessThan(amount) {
+			return nil, &InsufficientFundsError{
+				AccountID: id,
+				Requested: amount,
+				Available: account.Balance,
+			}
+		}
+		delta = amount.Neg()
+	}
+
+	balance, err := account.Balance.CheckedAdd(delta)
+	if err != nil {
+		return nil, err
+	}
+	account.Balance = balance
+	account.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if account, ok := r.accounts[objID]; ok {
+			account.Balance = account.Balance.Sub(delta)
+		}
+	})
+
+	copied := *account
+	return &copied, nil
+}
+
+func (r *AccountsMemoryRepository) GetAllAccounts(ctx context.Context, page PageRequest) ([]models.Accounts, *PageCursor, error) {
+	return memoryPage(r.sorted(), page, accountOrder, accountPageKey)
+}
+
+func (r *AccountsMemoryRepository) GetByOwner(ctx context.Context, owner string, page PageRequest) ([]models.Accounts, *PageCursor, error) {
+	var owned []models.Accounts
+	for _, account := range r.sorted() {
+		if account.Owner == owner {
+			owned = append(owned, account)
+		}
+	}
+	return memoryPage(owned, page, accountOrder, accountPageKey)
+}
+
+// ForEachAccount calls fn for a snapshot of the accounts, oldest first
+func (r *AccountsMemoryRepository) ForEachAccount(ctx context.Context, fn func(*models.Accounts) error) error {
+	accounts := r.sorted()
+	for i := range accounts {
+		if err := fn(&accounts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sorted returns copies of all accounts in (created_at, _id) order
+func (r *AccountsMemoryRepository) sorted() []models.Accounts {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	accounts := make([]models.Accounts, 0, len(r.accounts))
+	for _, account := range r.accounts {
+		accounts = append(accounts, *account)
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		a, b := accounts[i], accounts[j]
+		if a.CreatedAt != b.CreatedAt {
+			return a.CreatedAt < b.CreatedAt
+		}
+		return a.ID.Hex() < b.ID.Hex()
+	})
+
+	return accounts
+}
+
+func (r *AccountsMemoryRepository) CreateAccount(ctx context.Context, account *models.Accounts) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.accounts {
+		if existing.Email == account.Email {
+			account.ID = existing.ID
+			return ErrDuplicateEmail
+		}
+	}
+
+	account.Status = models.AccountActive
+	account.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	account.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+	if account.ID.IsZero() {
+		account.ID = primitive.NewObjectID()
+	}
+
+	stored := *account
+	r.accounts[stored.ID] = &stored
+
+	id := stored.ID
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.accounts, id)
+	})
+
+	return nil
+}
+
+func (r *AccountsMemoryRepository) UpdateDetails(ctx context.Context, id string, update models.AccountUpdate) (*models.Accounts, error) {
+	objID, err := parseAccountID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateAccountUpdate(update); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[objID]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	if update.Email != nil {
+		for _, existing := range r.accounts {
+			if existing.ID != objID && existing.Email == *update.Email {
+				return nil, ErrDuplicateEmail
+			}
+		}
+	}
+
+	if account.CurrentStatus() == models.AccountClosed {
+		return nil, &AccountStatusError{AccountID: id, Status: models.AccountClosed}
+	}
+
+	previous := *account
+	if update.Name != nil {
+		account.Name = *update.Name
+	}
+	if update.Email != nil {
+		account.Email = *update.Email
+	}
+	account.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if account, ok := r.accounts[objID]; ok {
+			account.Name = previous.Name
+			account.Email = previous.Email
+		}
+	})
+
+	copied := *account
+	return &copied, nil
+}
+
+func (r *AccountsMemoryRepository) UpdateStatus(ctx context.Context, id string, status models.AccountStatus) (*models.Accounts, error) {
+	objID, err := parseAccountID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[objID]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	if account.CurrentStatus() == models.AccountClosed {
+		return nil, &AccountStatusError{AccountID: id, Status: models.AccountClosed}
+	}
+
+	if status == models.AccountClosed && !account.Balance.IsZero() {
+		return nil, ErrBalanceNotZero
+	}
+
+	previous := account.Status
+	account.Status = status
+	account.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if account, ok := r.accounts[objID]; ok {
+			account.Status = previous
+		}
+	})
+
+	copied := *account
+	return &copied, nil
+}
+
+// parseAccountID validates an account ID the same way the Mongo repository does
+func parseAccountID(id string) (primitive.ObjectID, error) {
+	if id == "" {
+		return primitive.NilObjectID, errors.New("account ID cannot be empty")
+	}
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, errors.New("invalid account ID format")
+	}
+
+	return objID, nil
+}
@@ -0,0 +1,374 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"finance_app/src/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const accountColumns = "id, balance, name, email, owner, status, created_at, updated_at"
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+type AccountsPostgresRepository struct {
+	db *sql.DB
+}
+
+func NewAccountsPostgresRepository(db *sql.DB) *AccountsPostgresRepository {
+	return &AccountsPostgresRepository{db: db}
+}
+
+func scanAccount(row rowScanner) (*models.Accounts, error) {
+	var (
+		account   models.Accounts
+		id        string
+		owner     sql.NullString
+		createdAt time.Time
+		updatedAt sql.NullTime
+	)
+
+	if err := row.Scan(&id, &account.Balance, &account.Name, &account.Email, &owner, &account.Status, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	objID, err := parseHexID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	account.ID = objID
+	account.Owner = owner.String
+	account.CreatedAt = primitive.NewDateTimeFromTime(createdAt)
+	account.UpdatedAt = fromNullTime(updatedAt)
+
+	return &account, nil
+}
+
+func (r *AccountsPostgresRepository) FindOne(ctx context.Context, id string) (*models.Accounts, error) {
+	objID, err := parseAccountID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	row := querier(ctx, r.db).QueryRowContext(ctx, `SELECT `+accountColumns+` FROM accounts WHERE id = $1`, objID.Hex())
+
+	account, err := scanAccount(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch account: %w", err)
+	}
+
+	return account, nil
+}
+
+// Credit adds amount to the account balance and returns the updated account
+func (r *AccountsPostgresRepository) Credit(ctx context.Context, id string, amount models.Money) (*models.Accounts, error) {
+	return r.applyBalanceChange(ctx, id, amount, false)
+}
+
+// Debit subtracts amount from the account balance, but only if the balance
+// covers it. Otherwise it returns an *InsufficientFundsError and leaves the
+// balance untouched. Both Credit and Debit return an *AccountStatusError for
+// frozen and closed accounts.
+func (r *AccountsPostgresRepository) Debit(ctx context.Context, id string, amount models.Money) (*models.Accounts, error) {
+	return r.applyBalanceChange(ctx, id, amount, true)
+}
+
+// applyBalanceChange locks the account row with SELECT ... FOR UPDATE before
+// changing it, so concurrent changes to the same account queue up behind each
+// other instead of overwriting or overdrawing it
+func (r *AccountsPostgresRepository) applyBalanceChange(ctx context.Context, id string, amount models.Money, debit bool) (*models.Accounts, error) {
+	if !amount.IsPositive() {
+		return nil, errors.New("amount must be greater than 0")
+	}
+
+	objID, err := parseAccountID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	delta := amount
+	if debit {
+		delta = amount.Neg()
+	}
+
+	var account *models.Accounts
+	err = NewPostgresTransactor(r.db).WithTransaction(ctx, func(ctx context.Context) error {
+		q := querier(ctx, r.db)
+
+		current, err := r.lock(ctx, q, objID)
+		if err != nil {
+			return err
+		}
+
+		if status := current.CurrentStatus(); status != models.AccountActive {
+			return &AccountStatusError{AccountID: id, Status: status}
+		}
+
+		if debit && current.Balance.LessThan(amount) {
+			return &InsufficientFundsError{
+				AccountID: id,
+				Requested: amount,
+				Available: current.Balance,
+			}
+		}
+
+		row := q.QueryRowContext(ctx,
+			`UPDATE accounts SET balance = balance + $2, updated_at = $3 WHERE id = $1 RETURNING `+accountColumns,
+			objID.Hex(), delta, time.Now())
+		account, err = scanAccount(row)
+		if err != nil {
+			return fmt.Errorf("failed to update balance: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// GetAllAccounts returns one page of accounts, oldest first, and the cursor
+// of the next page if there is one
+func (r *AccountsPostgresRepository) GetAllAccounts(ctx context.Context, page PageRequest) ([]models.Accounts, *PageCursor, error) {
+	return r.findPage(ctx, nil, nil, page)
+}
+
+// GetByOwner returns one page of the accounts owned by owner, oldest first,
+// and the cursor of the next page if there is one
+func (r *AccountsPostgresRepository) GetByOwner(ctx context.Context, owner string, page PageRequest) ([]models.Accounts, *PageCursor, error) {
+	return r.findPage(ctx, []string{`owner = $1`}, []interface{}{owner}, page)
+}
+
+// findPage reads one page of the accounts matching conditions, whose
+// placeholders are numbered from $1
+func (r *AccountsPostgresRepository) findPage(ctx context.Context, conditions []string, args []interface{}, page PageRequest) ([]models.Accounts, *PageCursor, error) {
+	if err := accountOrder.check(page); err != nil {
+		return nil, nil, err
+	}
+
+	query := `SELECT ` + accountColumns + ` FROM accounts`
+	condition, tail, pageArgs := postgresPageClause(page, accountOrder, len(args))
+	if condition != "" {
+		conditions = append(conditions, condition)
+		args = append(args, pageArgs...)
+	}
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+
+	rows, err := querier(ctx, r.db).QueryContext(ctx, query+tail, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := make([]models.Accounts, 0, page.Size()+1)
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode accounts: %w", err)
+		}
+		accounts = append(accounts, *account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	accounts, next := trimPostgresPage(accounts, page, accountPageKey)
+	return accounts, next, nil
+}
+
+// ForEachAccount streams every account oldest first, a page at a time so fn
+// can run its own queries in the same SQL transaction
+func (r *AccountsPostgresRepository) ForEachAccount(ctx context.Context, fn func(*models.Accounts) error) error {
+	page := PageRequest{Limit: postgresStreamBatch}
+	for {
+		accounts, next, err := r.GetAllAccounts(ctx, page)
+		if err != nil {
+			return err
+		}
+
+		for i := range accounts {
+			if err := fn(&accounts[i]); err != nil {
+				return err
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+		page.After = next
+	}
+}
+
+func (r *AccountsPostgresRepository) CreateAccount(ctx context.Context, account *models.Accounts) error {
+	q := querier(ctx, r.db)
+
+	if existing, err := r.findIDByEmail(ctx, q, account.Email); err == nil {
+		account.ID = existing
+		return ErrDuplicateEmail
+	}
+
+	if account.ID.IsZero() {
+		account.ID = primitive.NewObjectID()
+	}
+	now := time.Now()
+	account.Status = models.AccountActive
+	account.CreatedAt = primitive.NewDateTimeFromTime(now)
+	account.UpdatedAt = primitive.NewDateTimeFromTime(now)
+
+	_, err := q.ExecContext(ctx,
+		`INSERT INTO accounts (`+accountColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		account.ID.Hex(), account.Balance, account.Name, account.Email, sql.NullString{String: account.Owner, Valid: account.Owner != ""}, account.Status, account.CreatedAt.Time(), account.UpdatedAt.Time())
+	if err != nil {
+		// Lost a race with another insert for the same email
+		if postgresErrorCode(err) == pgUniqueViolation {
+			if existing, findErr := r.findIDByEmail(ctx, q, account.Email); findErr == nil {
+				account.ID = existing
+			}
+			return ErrDuplicateEmail
+		}
+		return fmt.Errorf("failed to create account: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AccountsPostgresRepository) findIDByEmail(ctx context.Context, q sqlQuerier, email string) (primitive.ObjectID, error) {
+	var id string
+	if err := q.QueryRowContext(ctx, `SELECT id FROM accounts WHERE email = $1`, email).Scan(&id); err != nil {
+		return primitive.NilObjectID, err
+	}
+	return parseHexID(id)
+}
+
+// lock reads an account and holds its row lock until the transaction ends
+func (r *AccountsPostgresRepository) lock(ctx context.Context, q sqlQuerier, id primitive.ObjectID) (*models.Accounts, error) {
+	row := q.QueryRowContext(ctx, `SELECT `+accountColumns+` FROM accounts WHERE id = $1 FOR UPDATE`, id.Hex())
+
+	account, err := scanAccount(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock account: %w", err)
+	}
+
+	return account, nil
+}
+
+// UpdateDetails changes the name and email of an account that is not closed.
+// The email must not belong to any other account.
+func (r *AccountsPostgresRepository) UpdateDetails(ctx context.Context, id string, update models.AccountUpdate) (*models.Accounts, error) {
+	objID, err := parseAccountID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateAccountUpdate(update); err != nil {
+		return nil, err
+	}
+
+	var account *models.Accounts
+	err = NewPostgresTransactor(r.db).WithTransaction(ctx, func(ctx context.Context) error {
+		q := querier(ctx, r.db)
+
+		current, err := r.lock(ctx, q, objID)
+		if err != nil {
+			return err
+		}
+
+		if update.Email != nil {
+			if existing, err := r.findIDByEmail(ctx, q, *update.Email); err == nil && existing != objID {
+				return ErrDuplicateEmail
+			}
+		}
+
+		if current.CurrentStatus() == models.AccountClosed {
+			return &AccountStatusError{AccountID: id, Status: models.AccountClosed}
+		}
+
+		name, email := current.Name, current.Email
+		if update.Name != nil {
+			name = *update.Name
+		}
+		if update.Email != nil {
+			email = *update.Email
+		}
+
+		row := q.QueryRowContext(ctx,
+			`UPDATE accounts SET name = $2, email = $3, updated_at = $4 WHERE id = $1 RETURNING `+accountColumns,
+			objID.Hex(), name, email, time.Now())
+		account, err = scanAccount(row)
+		if postgresErrorCode(err) == pgUniqueViolation {
+			return ErrDuplicateEmail
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update account: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// UpdateStatus moves an account to status under its row lock. Closed accounts
+// never change again, and an account can only be closed at a zero balance.
+func (r *AccountsPostgresRepository) UpdateStatus(ctx context.Context, id string, status models.AccountStatus) (*models.Accounts, error) {
+	objID, err := parseAccountID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var account *models.Accounts
+	err = NewPostgresTransactor(r.db).WithTransaction(ctx, func(ctx context.Context) error {
+		q := querier(ctx, r.db)
+
+		current, err := r.lock(ctx, q, objID)
+		if err != nil {
+			return err
+		}
+
+		if current.CurrentStatus() == models.AccountClosed {
+			return &AccountStatusError{AccountID: id, Status: models.AccountClosed}
+		}
+
+		if status == models.AccountClosed && !current.Balance.IsZero() {
+			return ErrBalanceNotZero
+		}
+
+		row := q.QueryRowContext(ctx,
+			`UPDATE accounts SET status = $2, updated_at = $3 WHERE id = $1 RETURNING `+accountColumns,
+			objID.Hex(), status, time.Now())
+		account, err = scanAccount(row)
+		if err != nil {
+			return fmt.Errorf("failed to update account status: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"finance_app/src/models"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrAPIKeyNotFound is returned when no API key matches the given ID or hash
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+type APIKeyMongoRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAPIKeyMongoRepository(db *mongo.Database) *APIKeyMongoRepository {
+	return &APIKeyMongoRepository{
+		collection: db.Collection("api_keys"),
+	}
+}
+
+func (r *APIKeyMongoRepository) Create(ctx context.Context, key *models.APIKey) error {
+	key.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	result, err := r.collection.InsertOne(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		key.ID = oid
+	}
+	return nil
+}
+
+// FindByHash returns the key stored with hash, whether or not it is active
+func (r *APIKeyMongoRepository) FindByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.collection.FindOne(ctx, bson.M{"hash": hash}).Decode(&key)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch API key: %w", err)
+	}
+	return &key, nil
+}
+
+// List returns every key, including revoked and expired ones, newest first
+func (r *APIKeyMongoRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch API keys: %w", err)
+	}
+
+	keys := []models.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Revoke stops a key from authenticating. Revoking it again keeps the first
+// revocation time.
+func (r *APIKeyMongoRepository) Revoke(ctx context.Context, id string) (*models.APIKey, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid API key ID format")
+	}
+
+	_, err = r.collection.UpdateOne(ctx,
+		bson.M{"_id": objID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": primitive.NewDateTimeFromTime(time.Now())}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	var key models.APIKey
+	err = r.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&key)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch API key: %w", err)
+	}
+	return &key, nil
+}
+
+// MarkUsed records that the key authenticated a request at
+func (r *APIKeyMongoRepository) MarkUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": primitive.NewDateTimeFromTime(at)}})
+	if err != nil {
+		return fmt.Errorf("failed to record API key use: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"finance_app/src/models"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKeyMemoryRepository is a thread-safe in-memory APIKeyRepository
+type APIKeyMemoryRepository struct {
+	mu   sync.RWMutex
+	keys map[primitive.ObjectID]*models.APIKey
+}
+
+func NewAPIKeyMemoryRepository() *APIKeyMemoryRepository {
+	return &APIKeyMemoryRepository{
+		keys: make(map[primitive.ObjectID]*models.APIKey),
+	}
+}
+
+func (r *APIKeyMemoryRepository) Create(ctx context.Context, key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	if key.ID.IsZero() {
+		key.ID = primitive.NewObjectID()
+	}
+
+	stored := *key
+	r.keys[stored.ID] = &stored
+	return nil
+}
+
+func (r *APIKeyMemoryRepository) FindByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.Hash == hash {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+func (r *APIKeyMemoryRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]models.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, *key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt != keys[j].CreatedAt {
+			return keys[i].CreatedAt > keys[j].CreatedAt
+		}
+		return keys[i].ID.Hex() > keys[j].ID.Hex()
+	})
+	return keys, nil
+}
+
+func (r *APIKeyMemoryRepository) Revoke(ctx context.Context, id string) (*models.APIKey, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid API key ID format")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[objID]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		now := primitive.NewDateTimeFromTime(time.Now())
+		key.RevokedAt = &now
+	}
+
+	copied := *key
+	return &copied, nil
+}
+
+func (r *APIKeyMemoryRepository) MarkUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key, ok := r.keys[id]; ok {
+		used := primitive.NewDateTimeFromTime(at)
+		key.LastUsedAt = &used
+	}
+	return nil
+}
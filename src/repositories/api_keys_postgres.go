@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"finance_app/src/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const apiKeyColumns = "id, name, prefix, hash, scopes, created_by, created_at, expires_at, last_used_at, revoked_at"
+
+type APIKeyPostgresRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyPostgresRepository(db *sql.DB) *APIKeyPostgresRepository {
+	return &APIKeyPostgresRepository{db: db}
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var (
+		key       models.APIKey
+		id        string
+		scopes    string
+		createdBy sql.NullString
+		createdAt time.Time
+		expiresAt time.Time
+		lastUsed  sql.NullTime
+		revoked   sql.NullTime
+	)
+
+	if err := row.Scan(&id, &key.Name, &key.Prefix, &key.Hash, &scopes, &createdBy, &createdAt, &expiresAt, &lastUsed, &revoked); err != nil {
+		return nil, err
+	}
+
+	objID, err := parseHexID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	key.ID = objID
+	key.Scopes = strings.Fields(scopes)
+	key.CreatedBy = createdBy.String
+	key.CreatedAt = primitive.NewDateTimeFromTime(createdAt)
+	key.ExpiresAt = primitive.NewDateTimeFromTime(expiresAt)
+	if lastUsed.Valid {
+		at := primitive.NewDateTimeFromTime(lastUsed.Time)
+		key.LastUsedAt = &at
+	}
+	if revoked.Valid {
+		at := primitive.NewDateTimeFromTime(revoked.Time)
+		key.RevokedAt = &at
+	}
+
+	return &key, nil
+}
+
+func (r *APIKeyPostgresRepository) Create(ctx context.Context, key *models.APIKey) error {
+	if key.ID.IsZero() {
+		key.ID = primitive.NewObjectID()
+	}
+	key.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	_, err := querier(ctx, r.db).ExecContext(ctx,
+		`INSERT INTO api_keys (id, name, prefix, hash, scopes, created_by, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		key.ID.Hex(), key.Name, key.Prefix, key.Hash, strings.Join(key.Scopes, " "),
+		sql.NullString{String: key.CreatedBy, Valid: key.CreatedBy != ""}, key.CreatedAt.Time(), key.ExpiresAt.Time())
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+	return nil
+}
+
+// FindByHash returns the key stored with hash, whether or not it is active
+func (r *APIKeyPostgresRepository) FindByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	row := querier(ctx, r.db).QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE hash = $1`, hash)
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch API key: %w", err)
+	}
+	return key, nil
+}
+
+// List returns every key, including revoked and expired ones, newest first
+func (r *APIKeyPostgresRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := querier(ctx, r.db).QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode API keys: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+	return keys, nil
+}
+
+// Revoke stops a key from authenticating. Revoking it again keeps the first
+// revocation time.
+func (r *APIKeyPostgresRepository) Revoke(ctx context.Context, id string) (*models.APIKey, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid API key ID format")
+	}
+
+	row := querier(ctx, r.db).QueryRowContext(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $2) WHERE id = $1 RETURNING `+apiKeyColumns,
+		objID.Hex(), time.Now())
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return key, nil
+}
+
+// MarkUsed records that the key authenticated a request at
+func (r *APIKeyPostgresRepository) MarkUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := querier(ctx, r.db).ExecContext(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id.Hex(), at)
+	if err != nil {
+		return fmt.Errorf("failed to record API key use: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"finance_app/src/models"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrAuditChainBusy is returned when an event could not be chained because
+// other events kept being appended first
+var ErrAuditChainBusy = errors.New("audit trail is busy, event not recorded")
+
+// maxAuditAppendAttempts bounds how often MongoDB retries chaining an event
+// after another instance took its sequence number
+const maxAuditAppendAttempts = 5
+
+// auditOrder lists audit events newest first
+var auditOrder = pageOrder{field: SortByCreatedAt, descending: true}
+
+func auditPageKey(event *models.AuditEvent) PageCursor {
+	return auditOrder.cursor(int64(event.CreatedAt), event.ID)
+}
+
+// AuditQuery filters and pages the audit trail. The zero value matches every
+// event, newest first.
+type AuditQuery struct {
+	// Actor keeps only events made by this subject when set
+	Actor string
+	// AccountID keeps only events that touched this account when set
+	AccountID string
+	// From and To bound created_at, both inclusive
+	From *time.Time
+	To   *time.Time
+	Page PageRequest
+}
+
+// Validate checks the date range and that the page cursor was issued for
+// the audit trail's order
+func (q AuditQuery) Validate() error {
+	if q.From != nil && q.To != nil && q.To.Before(*q.From) {
+		return errors.New("date range ends before it starts")
+	}
+	return auditOrder.check(q.Page)
+}
+
+// matches applies the filters to one event
+func (q AuditQuery) matches(event *models.AuditEvent) bool {
+	if q.Actor != "" && event.Actor != q.Actor {
+		return false
+	}
+	if q.AccountID != "" {
+		touched := false
+		for _, balance := range event.Balances {
+			touched = touched || balance.AccountId == q.AccountID
+		}
+		if !touched {
+			return false
+		}
+	}
+	if q.From != nil && event.CreatedAt < primitive.NewDateTimeFromTime(*q.From) {
+		return false
+	}
+	if q.To != nil && event.CreatedAt > primitive.NewDateTimeFromTime(*q.To) {
+		return false
+	}
+	return true
+}
+
+// chainAuditEvent fills in the event's ID and timestamp, unless it already
+// has them from waiting in the outbox, and links it to last, the newest event
+// so far or nil for the first one
+func chainAuditEvent(event *models.AuditEvent, last *models.AuditEvent) {
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+	if event.CreatedAt == 0 {
+		event.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	}
+
+	event.Sequence = 1
+	event.PrevHash = ""
+	if last != nil {
+		event.Sequence = last.Sequence + 1
+		event.PrevHash = last.Hash
+	}
+	event.Hash = event.ComputeHash()
+}
+
+// queueAuditEvent readies event for the outbox, where it waits unchained
+func queueAuditEvent(event *models.AuditEvent) {
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+	event.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+	event.Sequence, event.PrevHash, event.Hash = 0, "", ""
+}
+
+type AuditMongoRepository struct {
+	collection *mongo.Collection
+	// outbox holds events appended inside transactions until ChainPending
+	// chains them
+	outbox *mongo.Collection
+}
+
+func NewAuditMongoRepository(db *mongo.Database) *AuditMongoRepository {
+	return &AuditMongoRepository{
+		collection: db.Collection("audit_events"),
+		outbox:     db.Collection("audit_outbox"),
+	}
+}
+
+// Append chains event after the newest one and stores it. Inside a
+// transaction it goes to the outbox instead, kept or dropped with the rest of
+// the transaction: chaining there would make every transaction write the
+// same place and conflict with all the others.
+func (r *AuditMongoRepository) Append(ctx context.Context, event *models.AuditEvent) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		queueAuditEvent(event)
+		if _, err := r.outbox.InsertOne(ctx, event); err != nil {
+			return fmt.Errorf("failed to queue audit event: %w", err)
+		}
+		return nil
+	}
+	return r.chain(ctx, event)
+}
+
+// chain stores event after the newest one. The unique index on sequence
+// refuses a second event claiming the same place in the chain, in which case
+// the event is chained again after the new newest one.
+func (r *AuditMongoRepository) chain(ctx context.Context, event *models.AuditEvent) error {
+	for attempt := 0; attempt < maxAuditAppendAttempts; attempt++ {
+		var last *models.AuditEvent
+		var newest models.AuditEvent
+		opts := options.FindOne().SetSort(bson.D{{Key: "sequence", Value: -1}})
+		err := r.collection.FindOne(ctx, bson.M{}, opts).Decode(&newest)
+		switch {
+		case err == nil:
+			last = &newest
+		case err != mongo.ErrNoDocuments:
+			return fmt.Errorf("failed to fetch the newest audit event: %w", err)
+		}
+
+		chainAuditEvent(event, last)
+		_, err = r.collection.InsertOne(ctx, event)
+		if mongo.IsDuplicateKeyError(err) {
+			// Another instance may have chained this very event from the
+			// outbox already
+			if n, err := r.collection.CountDocuments(ctx, bson.M{"_id": event.ID}); err == nil && n > 0 {
+				return nil
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to append audit event: %w", err)
+		}
+		return nil
+	}
+
+	return ErrAuditChainBusy
+}
+
+// ChainPending chains the events waiting in the outbox, oldest first, and
+// removes each once it is in the trail. An event chained by an instance that
+// stopped before removing it is recognized by its ID and not chained twice.
+func (r *AuditMongoRepository) ChainPending(ctx context.Context) (int, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.outbox.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch queued audit events: %w", err)
+	}
+	var pending []models.AuditEvent
+	if err := cursor.All(ctx, &pending); err != nil {
+		return 0, fmt.Errorf("failed to decode queued audit events: %w", err)
+	}
+
+	for i := range pending {
+		if err := r.chain(ctx, &pending[i]); err != nil {
+			return i, err
+		}
+		if _, err := r.outbox.DeleteOne(ctx, bson.M{"_id": pending[i].ID}); err != nil {
+			return i, fmt.Errorf("failed to remove queued audit event: %w", err)
+		}
+	}
+	return len(pending), nil
+}
+
+// Find returns one page of the events matching query, newest first
+func (r *AuditMongoRepository) Find(ctx context.Context, query AuditQuery) ([]models.AuditEvent, *PageCursor, error) {
+	if err := query.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	filter := bson.M{}
+	if query.Actor != "" {
+		filter["actor"] = query.Actor
+	}
+	if query.AccountID != "" {
+		filter["balances.accountId"] = query.AccountID
+	}
+
+	createdAt := bson.M{}
+	if query.From != nil {
+		createdAt["$gte"] = primitive.NewDateTimeFromTime(*query.From)
+	}
+	if query.To != nil {
+		createdAt["$lte"] = primitive.NewDateTimeFromTime(*query.To)
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+
+	return findMongoPage(ctx, r.collection, filter, query.Page, auditOrder, auditPageKey)
+}
+
+// ForEachEvent streams the whole trail in chain order
+func (r *AuditMongoRepository) ForEachEvent(ctx context.Context, fn func(*models.AuditEvent) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "sequence", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to fetch audit events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var event models.AuditEvent
+		if err := cursor.Decode(&event); err != nil {
+			return fmt.Errorf("failed to decode audit event: %w", err)
+		}
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor error: %w", err)
+	}
+
+	return nil
+}
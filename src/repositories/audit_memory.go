@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"context"
+	"finance_app/src/models"
+	"sort"
+	"sync"
+)
+
+// AuditMemoryRepository is a thread-safe in-memory AuditRepository
+type AuditMemoryRepository struct {
+	mu sync.RWMutex
+	// events are in chain order
+	events []models.AuditEvent
+}
+
+func NewAuditMemoryRepository() *AuditMemoryRepository {
+	return &AuditMemoryRepository{}
+}
+
+func (r *AuditMemoryRepository) Append(ctx context.Context, event *models.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var last *models.AuditEvent
+	if len(r.events) > 0 {
+		last = &r.events[len(r.events)-1]
+	}
+	chainAuditEvent(event, last)
+	r.events = append(r.events, copyAuditEvent(event))
+
+	// Transactions run one at a time, so nothing was chained after an event
+	// that is rolled back
+	id := event.ID
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i := range r.events {
+			if r.events[i].ID == id {
+				r.events = append(r.events[:i], r.events[i+1:]...)
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// ChainPending has nothing to do: transactions run one at a time, so events
+// are chained as they are appended
+func (r *AuditMemoryRepository) ChainPending(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+// copyAuditEvent copies event down to its balances, so stored events cannot
+// be changed through the ones handed out
+func copyAuditEvent(event *models.AuditEvent) models.AuditEvent {
+	copied := *event
+	copied.Balances = append([]models.BalanceSnapshot(nil), event.Balances...)
+	return copied
+}
+
+func (r *AuditMemoryRepository) Find(ctx context.Context, query AuditQuery) ([]models.AuditEvent, *PageCursor, error) {
+	if err := query.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	r.mu.RLock()
+	var matched []models.AuditEvent
+	for i := range r.events {
+		if query.matches(&r.events[i]) {
+			matched = append(matched, copyAuditEvent(&r.events[i]))
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return auditOrder.less(auditPageKey(&matched[i]), auditPageKey(&matched[j]))
+	})
+	return memoryPage(matched, query.Page, auditOrder, auditPageKey)
+}
+
+func (r *AuditMemoryRepository) ForEachEvent(ctx context.Context, fn func(*models.AuditEvent) error) error {
+	r.mu.RLock()
+	events := make([]models.AuditEvent, len(r.events))
+	for i := range r.events {
+		events[i] = copyAuditEvent(&r.events[i])
+	}
+	r.mu.RUnlock()
+
+	for i := range events {
+		if err := fn(&events[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"finance_app/src/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const auditEventColumns = "id, sequence, actor, request_id, client_ip, endpoint, action, permission, transaction_id, balances, created_at, prev_hash, hash"
+
+// auditChainLockKey is the advisory lock that serializes appends, so each
+// event is chained after the one committed before it
+const auditChainLockKey = 727319
+
+type AuditPostgresRepository struct {
+	db *sql.DB
+}
+
+func NewAuditPostgresRepository(db *sql.DB) *AuditPostgresRepository {
+	return &AuditPostgresRepository{db: db}
+}
+
+func scanAuditEvent(row rowScanner) (*models.AuditEvent, error) {
+	var (
+		event         models.AuditEvent
+		id            string
+		requestID     sql.NullString
+		permission    sql.NullString
+		transactionID sql.NullString
+		balances      []byte
+		createdAt     time.Time
+	)
+
+	err := row.Scan(&id, &event.Sequence, &event.Actor, &requestID, &event.ClientIP, &event.Endpoint, &event.Action,
+		&permission, &transactionID, &balances, &createdAt, &event.PrevHash, &event.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	objID, err := parseHexID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	event.ID = objID
+	event.RequestID = requestID.String
+	event.Permission = permission.String
+	event.TransactionId = transactionID.String
+	event.CreatedAt = primitive.NewDateTimeFromTime(createdAt)
+	if err := json.Unmarshal(balances, &event.Balances); err != nil {
+		return nil, fmt.Errorf("invalid stored balances: %w", err)
+	}
+
+	return &event, nil
+}
+
+// Append chains event after the newest one and stores it, holding the chain
+// lock until the insert commits. Inside a transaction it goes to the outbox
+// instead, kept or dropped with the change it records: chaining there would
+// hold the lock until the whole transaction commits, and make every
+// transaction wait for the one before it.
+func (r *AuditPostgresRepository) Append(ctx context.Context, event *models.AuditEvent) error {
+	if _, ok := ctx.Value(pgTxKey{}).(*sql.Tx); ok {
+		queueAuditEvent(event)
+		if err := insertAuditEvent(ctx, querier(ctx, r.db), "audit_outbox", event); err != nil {
+			return fmt.Errorf("failed to queue audit event: %w", err)
+		}
+		return nil
+	}
+
+	return NewPostgresTransactor(r.db).WithTransaction(ctx, func(ctx context.Context) error {
+		q := querier(ctx, r.db)
+		if _, err := q.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLockKey); err != nil {
+			return fmt.Errorf("failed to lock the audit trail: %w", err)
+		}
+		return chainAuditEventAfterNewest(ctx, q, event)
+	})
+}
+
+// ChainPending chains the events waiting in the outbox, oldest first, in
+// batches that each commit with their removal from the outbox
+func (r *AuditPostgresRepository) ChainPending(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		chained := 0
+		err := NewPostgresTransactor(r.db).WithTransaction(ctx, func(ctx context.Context) error {
+			chained = 0
+			q := querier(ctx, r.db)
+			if _, err := q.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLockKey); err != nil {
+				return fmt.Errorf("failed to lock the audit trail: %w", err)
+			}
+
+			rows, err := q.QueryContext(ctx,
+				fmt.Sprintf(`SELECT `+auditEventColumns+` FROM audit_outbox ORDER BY created_at, id LIMIT %d`, postgresStreamBatch))
+			if err != nil {
+				return fmt.Errorf("failed to fetch queued audit events: %w", err)
+			}
+			var pending []*models.AuditEvent
+			for rows.Next() {
+				event, err := scanAuditEvent(rows)
+				if err != nil {
+					rows.Close()
+					return fmt.Errorf("failed to decode queued audit event: %w", err)
+				}
+				pending = append(pending, event)
+			}
+			err = rows.Err()
+			rows.Close()
+			if err != nil {
+				return fmt.Errorf("cursor error: %w", err)
+			}
+
+			for _, event := range pending {
+				if err := chainAuditEventAfterNewest(ctx, q, event); err != nil {
+					return err
+				}
+				if _, err := q.ExecContext(ctx, `DELETE FROM audit_outbox WHERE id = $1`, event.ID.Hex()); err != nil {
+					return fmt.Errorf("failed to remove queued audit event: %w", err)
+				}
+			}
+			chained = len(pending)
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+
+		total += chained
+		if chained < postgresStreamBatch {
+			return total, nil
+		}
+	}
+}
+
+// chainAuditEventAfterNewest chains event after the newest one in the trail
+// and inserts it. The caller holds the chain lock.
+func chainAuditEventAfterNewest(ctx context.Context, q sqlQuerier, event *models.AuditEvent) error {
+	last, err := scanAuditEvent(q.QueryRowContext(ctx, `SELECT `+auditEventColumns+` FROM audit_events ORDER BY sequence DESC LIMIT 1`))
+	if errors.Is(err, sql.ErrNoRows) {
+		last = nil
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch the newest audit event: %w", err)
+	}
+
+	chainAuditEvent(event, last)
+	if err := insertAuditEvent(ctx, q, "audit_events", event); err != nil {
+		return fmt.Errorf("failed to append audit event: %w", err)
+	}
+	return nil
+}
+
+// insertAuditEvent writes event to table, audit_events or audit_outbox
+func insertAuditEvent(ctx context.Context, q sqlQuerier, table string, event *models.AuditEvent) error {
+	balances, err := json.Marshal(event.Balances)
+	if err != nil {
+		return fmt.Errorf("failed to encode balances: %w", err)
+	}
+
+	_, err = q.ExecContext(ctx,
+		`INSERT INTO `+table+` (`+auditEventColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		event.ID.Hex(), event.Sequence, event.Actor,
+		sql.NullString{String: event.RequestID, Valid: event.RequestID != ""},
+		event.ClientIP, event.Endpoint, event.Action,
+		sql.NullString{String: event.Permission, Valid: event.Permission != ""},
+		sql.NullString{String: event.TransactionId, Valid: event.TransactionId != ""},
+		string(balances), event.CreatedAt.Time(), event.PrevHash, event.Hash)
+	return err
+}
+
+// Find returns one page of the events matching query, newest first
+func (r *AuditPostgresRepository) Find(ctx context.Context, query AuditQuery) ([]models.AuditEvent, *PageCursor, error) {
+	if err := query.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if query.Actor != "" {
+		add(`actor = $%d`, query.Actor)
+	}
+	if query.AccountID != "" {
+		touched, _ := json.Marshal([]map[string]string{{"accountId": query.AccountID}})
+		add(`balances @> $%d::jsonb`, string(touched))
+	}
+	if query.From != nil {
+		add(`created_at >= $%d`, primitive.NewDateTimeFromTime(*query.From).Time())
+	}
+	if query.To != nil {
+		add(`created_at <= $%d`, primitive.NewDateTimeFromTime(*query.To).Time())
+	}
+
+	condition, tail, pageArgs := postgresPageClause(query.Page, auditOrder, len(args))
+	if condition != "" {
+		conditions = append(conditions, condition)
+	}
+
+	statement := `SELECT ` + auditEventColumns + ` FROM audit_events`
+	if len(conditions) > 0 {
+		statement += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+
+	rows, err := querier(ctx, r.db).QueryContext(ctx, statement+tail, append(args, pageArgs...)...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]models.AuditEvent, 0, query.Page.Size()+1)
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode audit events: %w", err)
+		}
+		events = append(events, *event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	events, next := trimPostgresPage(events, query.Page, auditPageKey)
+	return events, next, nil
+}
+
+// ForEachEvent streams the whole trail in chain order, in batches for the
+// same reason as TransactionPostgresRepository.ForEachByAccountID
+func (r *AuditPostgresRepository) ForEachEvent(ctx context.Context, fn func(*models.AuditEvent) error) error {
+	var after int64
+	for {
+		rows, err := querier(ctx, r.db).QueryContext(ctx,
+			fmt.Sprintf(`SELECT `+auditEventColumns+` FROM audit_events WHERE sequence > $1 ORDER BY sequence LIMIT %d`, postgresStreamBatch),
+			after)
+		if err != nil {
+			return fmt.Errorf("failed to fetch audit events: %w", err)
+		}
+
+		var batch []*models.AuditEvent
+		for rows.Next() {
+			event, err := scanAuditEvent(rows)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to decode audit event: %w", err)
+			}
+			batch = append(batch, event)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("cursor error: %w", err)
+		}
+
+		for _, event := range batch {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+
+		if len(batch) < postgresStreamBatch {
+			return nil
+		}
+		after = batch[len(batch)-1].Sequence
+	}
+}
@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"finance_app/src/models"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type IdempotencyMongoRepository struct {
+	collection *mongo.Collection
+	ttl        time.Duration
+}
+
+// NewIdempotencyMongoRepository stores keys in idempotency_keys; a key can be
+// reused for a new request once ttl has passed
+func NewIdempotencyMongoRepository(db *mongo.Database, ttl time.Duration) *IdempotencyMongoRepository {
+	return &IdempotencyMongoRepository{
+		collection: db.Collection("idempotency_keys"),
+		ttl:        ttl,
+	}
+}
+
+// EnsureIndexes creates the TTL index that lets MongoDB purge expired keys
+func (r *IdempotencyMongoRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0).SetName("expires_at_ttl"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create idempotency TTL index: %w", err)
+	}
+	return nil
+}
+
+// Reserve claims key for a request with the given fingerprint. It returns nil
+// when the caller now owns the key, or the live record of an earlier request.
+func (r *IdempotencyMongoRepository) Reserve(ctx context.Context, key, fingerprint string) (*models.IdempotencyRecord, error) {
+	if key == "" {
+		return nil, errors.New("idempotency key cannot be empty")
+	}
+
+	now := time.Now()
+	record := models.IdempotencyRecord{
+		ID:          key,
+		Fingerprint: fingerprint,
+		CreatedAt:   primitive.NewDateTimeFromTime(now),
+		ExpiresAt:   primitive.NewDateTimeFromTime(now.Add(r.ttl)),
+	}
+
+	// A second attempt covers an expired record the TTL monitor has not
+	// removed yet
+	for attempt := 0; attempt < 2; attempt++ {
+		_, err := r.collection.InsertOne(ctx, record)
+		if err == nil {
+			return nil, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+
+		var existing models.IdempotencyRecord
+		err = r.collection.FindOne(ctx, bson.M{"_id": key}).Decode(&existing)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch idempotency key: %w", err)
+		}
+
+		if existing.ExpiresAt.Time().After(now) {
+			return &existing, nil
+		}
+
+		_, err = r.collection.DeleteOne(ctx, bson.M{"_id": key, "expires_at": existing.ExpiresAt})
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove expired idempotency key: %w", err)
+		}
+	}
+
+	return nil, errors.New("failed to reserve idempotency key")
+}
+
+// Complete stores the response sent for a reserved key
+func (r *IdempotencyMongoRepository) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error {
+	update := bson.M{"$set": bson.M{
+		"completed":   true,
+		"statusCode":  statusCode,
+		"contentType": contentType,
+		"body":        body,
+	}}
+
+	if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": key}, update); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release drops a reservation so the same key can be retried
+func (r *IdempotencyMongoRepository) Release(ctx context.Context, key string) error {
+	if _, err := r.collection.DeleteOne(ctx, bson.M{"_id": key, "completed": false}); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"finance_app/src/models"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IdempotencyMemoryRepository is a thread-safe in-memory IdempotencyRepository
+type IdempotencyMemoryRepository struct {
+	mu      sync.Mutex
+	records map[string]*models.IdempotencyRecord
+	ttl     time.Duration
+}
+
+func NewIdempotencyMemoryRepository(ttl time.Duration) *IdempotencyMemoryRepository {
+	return &IdempotencyMemoryRepository{
+		records: make(map[string]*models.IdempotencyRecord),
+		ttl:     ttl,
+	}
+}
+
+func (r *IdempotencyMemoryRepository) Reserve(ctx context.Context, key, fingerprint string) (*models.IdempotencyRecord, error) {
+	if key == "" {
+		return nil, errors.New("idempotency key cannot be empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := r.records[key]; ok && existing.ExpiresAt.Time().After(now) {
+		copied := *existing
+		return &copied, nil
+	}
+
+	r.records[key] = &models.IdempotencyRecord{
+		ID:          key,
+		Fingerprint: fingerprint,
+		CreatedAt:   primitive.NewDateTimeFromTime(now),
+		ExpiresAt:   primitive.NewDateTimeFromTime(now.Add(r.ttl)),
+	}
+
+	return nil, nil
+}
+
+func (r *IdempotencyMemoryRepository) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if record, ok := r.records[key]; ok {
+		record.Completed = true
+		record.StatusCode = statusCode
+		record.ContentType = contentType
+		record.Body = append([]byte(nil), body...)
+	}
+
+	return nil
+}
+
+func (r *IdempotencyMemoryRepository) Release(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if record, ok := r.records[key]; ok && !record.Completed {
+		delete(r.records, key)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"finance_app/src/models"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type IdempotencyPostgresRepository struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+// NewIdempotencyPostgresRepository stores keys in idempotency_keys; a key can
+// be reused for a new request once ttl has passed
+func NewIdempotencyPostgresRepository(db *sql.DB, ttl time.Duration) *IdempotencyPostgresRepository {
+	return &IdempotencyPostgresRepository{db: db, ttl: ttl}
+}
+
+// Reserve claims key for a request with the given fingerprint. It returns nil
+// when the caller now owns the key, or the live record of an earlier request.
+// An expired record is taken over in the same statement that inserts.
+func (r *IdempotencyPostgresRepository) Reserve(ctx context.Context, key, fingerprint string) (*models.IdempotencyRecord, error) {
+	if key == "" {
+		return nil, errors.New("idempotency key cannot be empty")
+	}
+
+	q := querier(ctx, r.db)
+	now := time.Now()
+
+	// A second attempt covers a live record that expired between the two
+	// statements
+	for attempt := 0; attempt < 2; attempt++ {
+		var id string
+		err := q.QueryRowContext(ctx, `
+			INSERT INTO idempotency_keys (id, fingerprint, completed, created_at, expires_at)
+			VALUES ($1, $2, FALSE, $3, $4)
+			ON CONFLICT (id) DO UPDATE SET
+				fingerprint = EXCLUDED.fingerprint,
+				completed = FALSE,
+				status_code = NULL,
+				content_type = NULL,
+				body = NULL,
+				created_at = EXCLUDED.created_at,
+				expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+			RETURNING id`,
+			key, fingerprint, now, now.Add(r.ttl)).Scan(&id)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+
+		existing, err := r.find(ctx, q, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch idempotency key: %w", err)
+		}
+
+		if existing.ExpiresAt.Time().After(now) {
+			return existing, nil
+		}
+	}
+
+	return nil, errors.New("failed to reserve idempotency key")
+}
+
+func (r *IdempotencyPostgresRepository) find(ctx context.Context, q sqlQuerier, key string) (*models.IdempotencyRecord, error) {
+	var (
+		record      models.IdempotencyRecord
+		statusCode  sql.NullInt64
+		contentType sql.NullString
+		createdAt   time.Time
+		expiresAt   time.Time
+	)
+
+	err := q.QueryRowContext(ctx,
+		`SELECT id, fingerprint, completed, status_code, content_type, body, created_at, expires_at FROM idempotency_keys WHERE id = $1`,
+		key).Scan(&record.ID, &record.Fingerprint, &record.Completed, &statusCode, &contentType, &record.Body, &createdAt, &expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	record.StatusCode = int(statusCode.Int64)
+	record.ContentType = contentType.String
+	record.CreatedAt = primitive.NewDateTimeFromTime(createdAt)
+	record.ExpiresAt = primitive.NewDateTimeFromTime(expiresAt)
+
+	return &record, nil
+}
+
+// Complete stores the response sent for a reserved key
+func (r *IdempotencyPostgresRepository) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error {
+	_, err := querier(ctx, r.db).ExecContext(ctx,
+		`UPDATE idempotency_keys SET completed = TRUE, status_code = $2, content_type = $3, body = $4 WHERE id = $1`,
+		key, statusCode, contentType, body)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release drops a reservation so the same key can be retried
+func (r *IdempotencyPostgresRepository) Release(ctx context.Context, key string) error {
+	_, err := querier(ctx, r.db).ExecContext(ctx, `DELETE FROM idempotency_keys WHERE id = $1 AND NOT completed`, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpired deletes expired keys, which MongoDB's TTL index would
+// otherwise have removed
+func (r *IdempotencyPostgresRepository) PurgeExpired(ctx context.Context) (int64, error) {
+	result, err := querier(ctx, r.db).ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"finance_app/src/models"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// prepareJournalEntry validates an entry and fills in its ID and timestamp
+func prepareJournalEntry(entry *models.JournalEntry) error {
+	if entry == nil {
+		return errors.New("journal entry cannot be nil")
+	}
+
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	entry.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	return nil
+}
+
+type LedgerMongoRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLedgerMongoRepository(db *mongo.Database) *LedgerMongoRepository {
+	return &LedgerMongoRepository{
+		collection: db.Collection("journal_entries"),
+	}
+}
+
+// Post appends a balanced entry to the journal
+func (r *LedgerMongoRepository) Post(ctx context.Context, entry *models.JournalEntry) error {
+	if err := prepareJournalEntry(entry); err != nil {
+		return err
+	}
+
+	if _, err := r.collection.InsertOne(ctx, entry); err != nil {
+		return fmt.Errorf("failed to post journal entry: %w", err)
+	}
+
+	return nil
+}
+
+// Balance sums every leg posted to account
+func (r *LedgerMongoRepository) Balance(ctx context.Context, account string) (models.Money, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"legs.account": account}}},
+		{{Key: "$unwind", Value: "$legs"}},
+		{{Key: "$match", Value: bson.M{"legs.account": account}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "balance": bson.M{"$sum": "$legs.amount"}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return models.Money{}, fmt.Errorf("failed to sum journal legs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Balance models.Money `bson:"balance"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return models.Money{}, fmt.Errorf("failed to decode journal balance: %w", err)
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return models.Money{}, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return result.Balance, nil
+}
+
+// ForEachEntry streams the journal oldest first
+func (r *LedgerMongoRepository) ForEachEntry(ctx context.Context, fn func(*models.JournalEntry) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to fetch journal entries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry models.JournalEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return fmt.Errorf("failed to decode journal entry: %w", err)
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor error: %w", err)
+	}
+
+	return nil
+}
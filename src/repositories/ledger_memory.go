@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"context"
+	"finance_app/src/models"
+	"sync"
+)
+
+// LedgerMemoryRepository is a thread-safe in-memory LedgerRepository
+type LedgerMemoryRepository struct {
+	mu      sync.RWMutex
+	entries []models.JournalEntry
+}
+
+func NewLedgerMemoryRepository() *LedgerMemoryRepository {
+	return &LedgerMemoryRepository{}
+}
+
+func (r *LedgerMemoryRepository) Post(ctx context.Context, entry *models.JournalEntry) error {
+	if err := prepareJournalEntry(entry); err != nil {
+		return err
+	}
+
+	stored := *entry
+	stored.Legs = append([]models.LedgerLeg(nil), entry.Legs...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, stored)
+
+	id := stored.ID
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i := range r.entries {
+			if r.entries[i].ID == id {
+				r.entries = append(r.entries[:i], r.entries[i+1:]...)
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+func (r *LedgerMemoryRepository) Balance(ctx context.Context, account string) (models.Money, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var balance models.Money
+	for _, entry := range r.entries {
+		for _, leg := range entry.Legs {
+			if leg.Account != account {
+				continue
+			}
+			var err error
+			if balance, err = balance.CheckedAdd(leg.Amount); err != nil {
+				return models.Money{}, err
+			}
+		}
+	}
+
+	return balance, nil
+}
+
+// ForEachEntry calls fn for a snapshot of the journal, oldest first
+func (r *LedgerMemoryRepository) ForEachEntry(ctx context.Context, fn func(*models.JournalEntry) error) error {
+	r.mu.RLock()
+	snapshot := make([]models.JournalEntry, len(r.entries))
+	copy(snapshot, r.entries)
+	r.mu.RUnlock()
+
+	for i := range snapshot {
+		entry := snapshot[i]
+		entry.Legs = append([]models.LedgerLeg(nil), entry.Legs...)
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"finance_app/src/models"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LedgerPostgresRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerPostgresRepository(db *sql.DB) *LedgerPostgresRepository {
+	return &LedgerPostgresRepository{db: db}
+}
+
+// Post writes the entry and its legs in one transaction
+func (r *LedgerPostgresRepository) Post(ctx context.Context, entry *models.JournalEntry) error {
+	if err := prepareJournalEntry(entry); err != nil {
+		return err
+	}
+
+	return NewPostgresTransactor(r.db).WithTransaction(ctx, func(ctx context.Context) error {
+		q := querier(ctx, r.db)
+
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO journal_entries (id, transaction_id, description, created_at) VALUES ($1, $2, $3, $4)`,
+			entry.ID.Hex(), nullHexID(entry.TransactionId), entry.Description, entry.CreatedAt.Time())
+		if err != nil {
+			return fmt.Errorf("failed to post journal entry: %w", err)
+		}
+
+		for position, leg := range entry.Legs {
+			_, err := q.ExecContext(ctx,
+				`INSERT INTO journal_legs (entry_id, position, account, amount) VALUES ($1, $2, $3, $4)`,
+				entry.ID.Hex(), position, leg.Account, leg.Amount)
+			if err != nil {
+				return fmt.Errorf("failed to post journal leg: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Balance sums every leg posted to account
+func (r *LedgerPostgresRepository) Balance(ctx context.Context, account string) (models.Money, error) {
+	var balance models.Money
+	err := querier(ctx, r.db).QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(amount), 0) FROM journal_legs WHERE account = $1`, account).Scan(&balance)
+	if err != nil {
+		return models.Money{}, fmt.Errorf("failed to sum journal legs: %w", err)
+	}
+	return balance, nil
+}
+
+// ForEachEntry streams the journal oldest first, in batches for the same
+// reason as TransactionPostgresRepository.ForEachByAccountID
+func (r *LedgerPostgresRepository) ForEachEntry(ctx context.Context, fn func(*models.JournalEntry) error) error {
+	var last *models.JournalEntry
+	for {
+		batch, err := r.entryBatch(ctx, last)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range batch {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+
+		if len(batch) < postgresStreamBatch {
+			return nil
+		}
+		last = batch[len(batch)-1]
+	}
+}
+
+// entryBatch returns the next batch of entries after last, with their legs
+func (r *LedgerPostgresRepository) entryBatch(ctx context.Context, last *models.JournalEntry) ([]*models.JournalEntry, error) {
+	page := `SELECT id, transaction_id, description, created_at FROM journal_entries`
+	var args []interface{}
+	if last != nil {
+		page += ` WHERE (created_at, id) > ($1, $2)`
+		args = append(args, last.CreatedAt.Time(), last.ID.Hex())
+	}
+	page += fmt.Sprintf(` ORDER BY created_at, id LIMIT %d`, postgresStreamBatch)
+
+	rows, err := querier(ctx, r.db).QueryContext(ctx, `
+		SELECT e.id, e.transaction_id, e.description, e.created_at, l.account, l.amount
+		FROM (`+page+`) e
+		JOIN journal_legs l ON l.entry_id = e.id
+		ORDER BY e.created_at, e.id, l.position`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch journal entries: %w", err)
+	}
+	defer rows.Close()
+
+	var batch []*models.JournalEntry
+	for rows.Next() {
+		var (
+			id            string
+			transactionID sql.NullString
+			description   string
+			createdAt     time.Time
+			leg           models.LedgerLeg
+		)
+		if err := rows.Scan(&id, &transactionID, &description, &createdAt, &leg.Account, &leg.Amount); err != nil {
+			return nil, fmt.Errorf("failed to decode journal entry: %w", err)
+		}
+
+		objID, err := parseHexID(id)
+		if err != nil {
+			return nil, err
+		}
+
+		// Legs of the same entry arrive next to each other
+		if len(batch) == 0 || batch[len(batch)-1].ID != objID {
+			entry := &models.JournalEntry{
+				ID:          objID,
+				Description: description,
+				CreatedAt:   primitive.NewDateTimeFromTime(createdAt),
+			}
+			if transactionID.Valid {
+				txID, err := parseHexID(transactionID.String)
+				if err != nil {
+					return nil, err
+				}
+				entry.TransactionId = &txID
+			}
+			batch = append(batch, entry)
+		}
+
+		current := batch[len(batch)-1]
+		current.Legs = append(current.Legs, leg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return batch, nil
+}
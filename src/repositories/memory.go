@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+)
+
+type memoryTxKey struct{}
+
+// memoryTx collects the undo steps of the writes made inside a transaction
+type memoryTx struct {
+	undo []func()
+}
+
+// MemoryTransactor gives the in-memory repositories transactional behaviour.
+// Transactions run one at a time, and each write made inside one registers an
+// undo step that is replayed in reverse order if the transaction fails.
+type MemoryTransactor struct {
+	mu sync.Mutex
+}
+
+func NewMemoryTransactor() *MemoryTransactor {
+	return &MemoryTransactor{}
+}
+
+func (t *MemoryTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	// Nested calls join the transaction that is already running
+	if _, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok {
+		return fn(ctx)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tx := &memoryTx{}
+	if err := fn(context.WithValue(ctx, memoryTxKey{}, tx)); err != nil {
+		for i := len(tx.undo) - 1; i >= 0; i-- {
+			tx.undo[i]()
+		}
+		return err
+	}
+
+	return nil
+}
+
+// onRollback registers undo to run if the transaction in ctx fails. Outside a
+// transaction writes are final and undo is dropped.
+func onRollback(ctx context.Context, undo func()) {
+	if tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok {
+		tx.undo = append(tx.undo, undo)
+	}
+}
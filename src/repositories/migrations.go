@@ -0,0 +1,319 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoMigration is one versioned change to the MongoDB schema. Migrations
+// must be safe to run twice: two instances starting together may both apply
+// one before either records it.
+type mongoMigration struct {
+	version int
+	name    string
+	up      func(ctx context.Context, db *mongo.Database) error
+}
+
+// mongoMigrations are applied in order; append new ones, never edit or
+// renumber one that has shipped
+var mongoMigrations = []mongoMigration{
+	{1, "create_accounts_email_index", func(ctx context.Context, db *mongo.Database) error {
+		// Accounts created before the index could share an email, which
+		// would fail the build with nothing saying which ones
+		if err := checkDuplicateEmails(ctx, db.Collection("accounts")); err != nil {
+			return err
+		}
+
+		return createIndexes(ctx, db.Collection("accounts"), mongo.IndexModel{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetName("email_unique").SetUnique(true),
+		})
+	}},
+	{2, "create_transactions_account_created_at_index", func(ctx context.Context, db *mongo.Database) error {
+		return createIndexes(ctx, db.Collection("transactions"), mongo.IndexModel{
+			Keys:    bson.D{{Key: "accountId", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetName("account_created_at"),
+		})
+	}},
+	{3, "add_accounts_validator", func(ctx context.Context, db *mongo.Database) error {
+		return setValidator(ctx, db, "accounts", bson.M{
+			"bsonType": "object",
+			"required": bson.A{"name", "email", "balance", "created_at"},
+			"properties": bson.M{
+				"name":       bson.M{"bsonType": "string", "minLength": 1},
+				"email":      bson.M{"bsonType": "string", "minLength": 1},
+				"balance":    bson.M{"bsonType": "decimal"},
+				"status":     bson.M{"enum": bson.A{"ACTIVE", "FROZEN", "CLOSED"}},
+				"created_at": bson.M{"bsonType": "date"},
+				"updated_at": bson.M{"bsonType": "date"},
+			},
+		})
+	}},
+	{4, "add_transactions_validator", func(ctx context.Context, db *mongo.Database) error {
+		return setValidator(ctx, db, "transactions", bson.M{
+			"bsonType": "object",
+			"required": bson.A{"transactionType", "amount", "balance", "accountId", "created_at"},
+			"properties": bson.M{
+				"transactionType":       bson.M{"enum": bson.A{"DEPOSIT", "WITHDRAW", "TRANSFER"}},
+				"amount":                bson.M{"bsonType": "decimal"},
+				"balance":               bson.M{"bsonType": "decimal"},
+				"accountId":             bson.M{"bsonType": "objectId"},
+				"direction":             bson.M{"enum": bson.A{"OUT", "IN"}},
+				"counterpartyAccountId": bson.M{"bsonType": "objectId"},
+				"linkedTransactionId":   bson.M{"bsonType": "objectId"},
+				"created_at":            bson.M{"bsonType": "date"},
+				"updated_at":            bson.M{"bsonType": "date"},
+			},
+		})
+	}},
+	{5, "create_transactions_filter_indexes", func(ctx context.Context, db *mongo.Database) error {
+		index := func(name string, keys bson.D) mongo.IndexModel {
+			return mongo.IndexModel{Keys: keys, Options: options.Index().SetName(name)}
+		}
+
+		// Each ends in _id, the tie-breaker of every page order
+		return createIndexes(ctx, db.Collection("transactions"),
+			index("account_type_created_at", bson.D{{Key: "accountId", Value: 1}, {Key: "transactionType", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}),
+			index("account_amount", bson.D{{Key: "accountId", Value: 1}, {Key: "amount", Value: -1}, {Key: "_id", Value: -1}}),
+			index("created_at", bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}),
+			index("type_created_at", bson.D{{Key: "transactionType", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}),
+			index("amount", bson.D{{Key: "amount", Value: -1}, {Key: "_id", Value: -1}}),
+		)
+	}},
+	{6, "create_journal_legs_account_index", func(ctx context.Context, db *mongo.Database) error {
+		return createIndexes(ctx, db.Collection("journal_entries"), mongo.IndexModel{
+			Keys:    bson.D{{Key: "legs.account", Value: 1}},
+			Options: options.Index().SetName("legs_account"),
+		})
+	}},
+	{7, "create_accounts_owner_index", func(ctx context.Context, db *mongo.Database) error {
+		return createIndexes(ctx, db.Collection("accounts"), mongo.IndexModel{
+			Keys:    bson.D{{Key: "owner", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName("owner_created_at"),
+		})
+	}},
+	{8, "create_api_keys_hash_index", func(ctx context.Context, db *mongo.Database) error {
+		return createIndexes(ctx, db.Collection("api_keys"), mongo.IndexModel{
+			Keys:    bson.D{{Key: "hash", Value: 1}},
+			Options: options.Index().SetName("hash_unique").SetUnique(true),
+		})
+	}},
+	{9, "create_audit_events_indexes", func(ctx context.Context, db *mongo.Database) error {
+		index := func(name string, keys bson.D) mongo.IndexModel {
+			return mongo.IndexModel{Keys: keys, Options: options.Index().SetName(name)}
+		}
+
+		// The unique sequence keeps two events from taking the same place in
+		// the chain
+		err := createIndexes(ctx, db.Collection("audit_events"),
+			mongo.IndexModel{
+				Keys:    bson.D{{Key: "sequence", Value: 1}},
+				Options: options.Index().SetName("sequence_unique").SetUnique(true),
+			},
+			index("created_at", bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}),
+			index("actor_created_at", bson.D{{Key: "actor", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}),
+			index("account_created_at", bson.D{{Key: "balances.accountId", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}),
+		)
+		if err != nil {
+			return err
+		}
+
+		// Creates the outbox too, which transactions cannot always do
+		return createIndexes(ctx, db.Collection("audit_outbox"),
+			index("created_at", bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	}},
+	{10, "add_transaction_reversals", func(ctx context.Context, db *mongo.Database) error {
+		err := setValidator(ctx, db, "transactions", bson.M{
+			"bsonType": "object",
+			"required": bson.A{"transactionType", "amount", "balance", "accountId", "created_at"},
+			"properties": bson.M{
+				"transactionType":       bson.M{"enum": bson.A{"DEPOSIT", "WITHDRAW", "TRANSFER", "REVERSAL"}},
+				"amount":                bson.M{"bsonType": "decimal"},
+				"balance":               bson.M{"bsonType": "decimal"},
+				"accountId":             bson.M{"bsonType": "objectId"},
+				"direction":             bson.M{"enum": bson.A{"OUT", "IN"}},
+				"counterpartyAccountId": bson.M{"bsonType": "objectId"},
+				"linkedTransactionId":   bson.M{"bsonType": "objectId"},
+				"reversalOf":            bson.M{"bsonType": "objectId"},
+				"reversedAmount":        bson.M{"bsonType": "decimal"},
+				"reversalStatus":        bson.M{"enum": bson.A{"PARTIALLY_REVERSED", "REVERSED"}},
+				"created_at":            bson.M{"bsonType": "date"},
+				"updated_at":            bson.M{"bsonType": "date"},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		// Only reversals have reversalOf, so the index leaves the rest out
+		return createIndexes(ctx, db.Collection("transactions"), mongo.IndexModel{
+			Keys: bson.D{{Key: "reversalOf", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName("reversal_of").
+				SetPartialFilterExpression(bson.M{"reversalOf": bson.M{"$exists": true}}),
+		})
+	}},
+}
+
+// MigrateMongo applies the schema migrations that have not run yet, in
+// version order, and records each one in schema_migrations
+func MigrateMongo(ctx context.Context, db *mongo.Database) error {
+	applied, err := appliedMongoMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	records := db.Collection("schema_migrations")
+	for _, migration := range mongoMigrations {
+		if applied[migration.version] {
+			continue
+		}
+
+		if err := migration.up(ctx, db); err != nil {
+			return fmt.Errorf("failed to apply migration %d_%s: %w", migration.version, migration.name, err)
+		}
+
+		_, err := records.InsertOne(ctx, bson.M{
+			"_id":        migration.version,
+			"name":       migration.name,
+			"applied_at": time.Now(),
+		})
+		// Another instance recorded it first
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to record migration %d: %w", migration.version, err)
+		}
+	}
+
+	return nil
+}
+
+// PendingMongoMigrations returns how many schema migrations have not been
+// applied yet
+func PendingMongoMigrations(ctx context.Context, db *mongo.Database) (int, error) {
+	applied, err := appliedMongoMigrations(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, migration := range mongoMigrations {
+		if !applied[migration.version] {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// appliedMongoMigrations returns the versions recorded in schema_migrations
+func appliedMongoMigrations(ctx context.Context, db *mongo.Database) (map[int]bool, error) {
+	cursor, err := db.Collection("schema_migrations").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	applied := map[int]bool{}
+	for cursor.Next(ctx) {
+		var record struct {
+			Version int `bson:"_id"`
+		}
+		if err := cursor.Decode(&record); err != nil {
+			return nil, fmt.Errorf("failed to decode schema_migrations: %w", err)
+		}
+		applied[record.Version] = true
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return applied, nil
+}
+
+// checkDuplicateEmails fails with the IDs of the accounts sharing an email,
+// if any do
+func checkDuplicateEmails(ctx context.Context, accounts *mongo.Collection) error {
+	cursor, err := accounts.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$email",
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to look for duplicate emails: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var duplicates []struct {
+		Email string               `bson:"_id"`
+		IDs   []primitive.ObjectID `bson:"ids"`
+	}
+	if err := cursor.All(ctx, &duplicates); err != nil {
+		return fmt.Errorf("failed to decode duplicate emails: %w", err)
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	conflicts := make([]string, len(duplicates))
+	for i, duplicate := range duplicates {
+		ids := make([]string, len(duplicate.IDs))
+		for j, id := range duplicate.IDs {
+			ids[j] = id.Hex()
+		}
+		conflicts[i] = fmt.Sprintf("%s (accounts %s)", duplicate.Email, strings.Join(ids, ", "))
+	}
+	return fmt.Errorf("%d emails are used by more than one account, change or merge them so each is unique and migrate again: %s",
+		len(duplicates), strings.Join(conflicts, "; "))
+}
+
+// createIndexes builds indexes, which is a no-op for any that already exist
+// with the same keys and options
+func createIndexes(ctx context.Context, collection *mongo.Collection, indexes ...mongo.IndexModel) error {
+	_, err := collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// setValidator attaches a $jsonSchema validator to a collection, creating the
+// collection if it does not exist yet. Moderate validation checks inserts and
+// updates to documents that already pass, so documents written before the
+// validator can still be repaired.
+func setValidator(ctx context.Context, db *mongo.Database, collection string, schema bson.M) error {
+	validator := bson.M{"$jsonSchema": schema}
+	collMod := func() error {
+		return db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: collection},
+			{Key: "validator", Value: validator},
+			{Key: "validationLevel", Value: "moderate"},
+		}).Err()
+	}
+
+	err := collMod()
+	if !isCommandError(err, "NamespaceNotFound") {
+		return err
+	}
+
+	opts := options.CreateCollection().SetValidator(validator).SetValidationLevel("moderate")
+	err = db.CreateCollection(ctx, collection, opts)
+	if isCommandError(err, "NamespaceExists") {
+		// Created by another instance in the meantime
+		return collMod()
+	}
+	return err
+}
+
+// isCommandError reports whether err is a server error with the given name
+func isCommandError(err error, name string) bool {
+	var commandErr mongo.CommandError
+	return errors.As(err, &commandErr) && commandErr.Name == name
+}
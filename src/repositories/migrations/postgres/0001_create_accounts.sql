@@ -0,0 +1,11 @@
+CREATE TABLE accounts (
+    id         CHAR(24) PRIMARY KEY,
+    balance    NUMERIC(20, 2) NOT NULL DEFAULT 0,
+    name       TEXT NOT NULL,
+    email      TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX accounts_email_key ON accounts (email);
+CREATE INDEX accounts_created_at_idx ON accounts (created_at, id);
@@ -0,0 +1,15 @@
+CREATE TABLE transactions (
+    id                      CHAR(24) PRIMARY KEY,
+    transaction_type        TEXT NOT NULL CHECK (transaction_type IN ('DEPOSIT', 'WITHDRAW', 'TRANSFER')),
+    amount                  NUMERIC(20, 2) NOT NULL CHECK (amount > 0),
+    balance                 NUMERIC(20, 2) NOT NULL DEFAULT 0,
+    account_id              CHAR(24) NOT NULL REFERENCES accounts (id),
+    direction               TEXT CHECK (direction IN ('OUT', 'IN')),
+    counterparty_account_id CHAR(24),
+    linked_transaction_id   CHAR(24),
+    created_at              TIMESTAMPTZ NOT NULL,
+    updated_at              TIMESTAMPTZ
+);
+
+CREATE INDEX transactions_account_created_at_idx ON transactions (account_id, created_at DESC, id DESC);
+CREATE INDEX transactions_created_at_idx ON transactions (created_at DESC, id DESC);
@@ -0,0 +1,12 @@
+CREATE TABLE idempotency_keys (
+    id           TEXT PRIMARY KEY,
+    fingerprint  TEXT NOT NULL,
+    completed    BOOLEAN NOT NULL DEFAULT FALSE,
+    status_code  INTEGER,
+    content_type TEXT,
+    body         BYTEA,
+    created_at   TIMESTAMPTZ NOT NULL,
+    expires_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,18 @@
+CREATE TABLE journal_entries (
+    id             CHAR(24) PRIMARY KEY,
+    transaction_id CHAR(24),
+    description    TEXT NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX journal_entries_created_at_idx ON journal_entries (created_at, id);
+
+CREATE TABLE journal_legs (
+    entry_id CHAR(24) NOT NULL REFERENCES journal_entries (id),
+    position INTEGER NOT NULL,
+    account  TEXT NOT NULL,
+    amount   NUMERIC(20, 2) NOT NULL CHECK (amount <> 0),
+    PRIMARY KEY (entry_id, position)
+);
+
+CREATE INDEX journal_legs_account_idx ON journal_legs (account);
@@ -0,0 +1,2 @@
+ALTER TABLE accounts
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'FROZEN', 'CLOSED'));
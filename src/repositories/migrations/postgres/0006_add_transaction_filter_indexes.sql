@@ -0,0 +1,7 @@
+-- Compound indexes behind the filtered and sorted transaction lists. Each
+-- ends in id, the tie-breaker of every page order; descending indexes are
+-- scanned backwards for ascending pages.
+CREATE INDEX transactions_account_type_created_at_idx ON transactions (account_id, transaction_type, created_at DESC, id DESC);
+CREATE INDEX transactions_account_amount_idx ON transactions (account_id, amount DESC, id DESC);
+CREATE INDEX transactions_type_created_at_idx ON transactions (transaction_type, created_at DESC, id DESC);
+CREATE INDEX transactions_amount_idx ON transactions (amount DESC, id DESC);
@@ -0,0 +1,5 @@
+-- The subject that created the account. Accounts created before
+-- authentication have none and are only visible to admins.
+ALTER TABLE accounts ADD COLUMN owner TEXT;
+
+CREATE INDEX accounts_owner_created_at_idx ON accounts (owner, created_at, id);
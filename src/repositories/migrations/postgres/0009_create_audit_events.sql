@@ -0,0 +1,37 @@
+-- The audit trail. Events are hash-chained in sequence order and never
+-- changed: the trigger refuses updates and deletes. balances holds the
+-- before and after snapshot of every account the change touched.
+CREATE TABLE audit_events (
+    id             CHAR(24) PRIMARY KEY,
+    sequence       BIGINT NOT NULL UNIQUE,
+    actor          TEXT NOT NULL,
+    request_id     TEXT,
+    client_ip      TEXT NOT NULL,
+    endpoint       TEXT NOT NULL,
+    action         TEXT NOT NULL,
+    permission     TEXT,
+    transaction_id CHAR(24),
+    balances       JSONB NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL,
+    prev_hash      TEXT NOT NULL,
+    hash           TEXT NOT NULL
+);
+
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at, id);
+CREATE INDEX audit_events_actor_idx ON audit_events (actor, created_at, id);
+CREATE INDEX audit_events_balances_idx ON audit_events USING GIN (balances jsonb_path_ops);
+
+-- Events recorded inside transactions wait here, unchained, until they are
+-- moved into audit_events, so transactions do not queue on the chain lock
+CREATE TABLE audit_outbox (LIKE audit_events, PRIMARY KEY (id));
+CREATE INDEX audit_outbox_created_at_idx ON audit_outbox (created_at, id);
+
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
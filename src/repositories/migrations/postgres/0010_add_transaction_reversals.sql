@@ -0,0 +1,10 @@
+-- Reversals are transactions of their own that point at the transaction they
+-- give back; the original keeps a running total of what has been given back.
+ALTER TABLE transactions DROP CONSTRAINT transactions_transaction_type_check;
+ALTER TABLE transactions
+    ADD CONSTRAINT transactions_transaction_type_check CHECK (transaction_type IN ('DEPOSIT', 'WITHDRAW', 'TRANSFER', 'REVERSAL')),
+    ADD COLUMN reversal_of CHAR(24) REFERENCES transactions (id),
+    ADD COLUMN reversed_amount NUMERIC(20, 2) NOT NULL DEFAULT 0 CHECK (reversed_amount >= 0 AND reversed_amount <= amount),
+    ADD COLUMN reversal_status TEXT CHECK (reversal_status IN ('PARTIALLY_REVERSED', 'REVERSED'));
+
+CREATE INDEX transactions_reversal_of_idx ON transactions (reversal_of) WHERE reversal_of IS NOT NULL;
@@ -0,0 +1,322 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"finance_app/src/models"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// DefaultPageSize is used when a PageRequest has no limit
+	DefaultPageSize = 50
+	// MaxPageSize is the most items a single page can hold
+	MaxPageSize = 200
+)
+
+// ErrInvalidCursor is returned for page cursors this API did not issue
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SortField is a field lists can be ordered by. Items are ordered by the
+// field first and by _id after it, so every item has a unique position.
+type SortField string
+
+const (
+	SortByCreatedAt SortField = "createdAt"
+	SortByAmount    SortField = "amount"
+)
+
+// sortFieldTags identify the sort field inside an encoded cursor
+var sortFieldTags = map[SortField]byte{
+	SortByCreatedAt: 1,
+	SortByAmount:    2,
+}
+
+// PageCursor identifies the last item of a page by its sort key; the next
+// page starts right after it. It records the order it was issued for, so it
+// cannot be replayed against a list sorted another way.
+type PageCursor struct {
+	Field      SortField
+	Descending bool
+	// Value is the sort field in milliseconds since the epoch for
+	// SortByCreatedAt, or in minor units for SortByAmount
+	Value int64
+	ID    primitive.ObjectID
+}
+
+// PageRequest asks for up to Limit items following After, or the first page
+// when After is nil
+type PageRequest struct {
+	Limit int
+	After *PageCursor
+}
+
+// Size is the page size to use: the default for an unset limit, capped at
+// MaxPageSize
+func (p PageRequest) Size() int {
+	switch {
+	case p.Limit <= 0:
+		return DefaultPageSize
+	case p.Limit > MaxPageSize:
+		return MaxPageSize
+	default:
+		return p.Limit
+	}
+}
+
+// cursorLength is the field tag, the direction, the value and the _id
+const cursorLength = 1 + 1 + 8 + 12
+
+// Encode returns the cursor as an opaque, URL-safe token
+func (c PageCursor) Encode() string {
+	raw := make([]byte, cursorLength)
+	raw[0] = sortFieldTags[c.Field]
+	if c.Descending {
+		raw[1] = 1
+	}
+	binary.BigEndian.PutUint64(raw[2:], uint64(c.Value))
+	copy(raw[10:], c.ID[:])
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodePageCursor parses a token produced by PageCursor.Encode
+func DecodePageCursor(token string) (*PageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != cursorLength || raw[1] > 1 {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := &PageCursor{
+		Descending: raw[1] == 1,
+		Value:      int64(binary.BigEndian.Uint64(raw[2:])),
+	}
+	for field, tag := range sortFieldTags {
+		if raw[0] == tag {
+			cursor.Field = field
+		}
+	}
+	copy(cursor.ID[:], raw[10:])
+	if cursor.Field == "" || cursor.ID.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// compare orders two sort keys over the same field, ascending
+func (c PageCursor) compare(other PageCursor) int {
+	switch {
+	case c.Value < other.Value:
+		return -1
+	case c.Value > other.Value:
+		return 1
+	default:
+		return bytes.Compare(c.ID[:], other.ID[:])
+	}
+}
+
+// comesAfter reports whether key sorts after the cursor in its direction
+func (c *PageCursor) comesAfter(key PageCursor) bool {
+	if c.Descending {
+		return key.compare(*c) < 0
+	}
+	return key.compare(*c) > 0
+}
+
+// pageOrder is the order a list is paged in
+type pageOrder struct {
+	field      SortField
+	descending bool
+}
+
+// accountOrder lists accounts oldest first
+var accountOrder = pageOrder{field: SortByCreatedAt}
+
+// check rejects cursors issued for a different order
+func (o pageOrder) check(page PageRequest) error {
+	if page.After != nil && (page.After.Field != o.field || page.After.Descending != o.descending) {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+func (o pageOrder) cursor(value int64, id primitive.ObjectID) PageCursor {
+	return PageCursor{Field: o.field, Descending: o.descending, Value: value, ID: id}
+}
+
+// less orders two sort keys in this order's direction
+func (o pageOrder) less(a, b PageCursor) bool {
+	if o.descending {
+		return a.compare(b) > 0
+	}
+	return a.compare(b) < 0
+}
+
+func accountPageKey(account *models.Accounts) PageCursor {
+	return accountOrder.cursor(int64(account.CreatedAt), account.ID)
+}
+
+// transactionKey returns the sort key of transaction in this order
+func (o pageOrder) transactionKey(transaction *models.Transaction) PageCursor {
+	if o.field == SortByAmount {
+		return o.cursor(transaction.Amount.Minor(), transaction.ID)
+	}
+	return o.cursor(int64(transaction.CreatedAt), transaction.ID)
+}
+
+// column is the document field and SQL column of the sort field
+func (o pageOrder) column() string {
+	if o.field == SortByAmount {
+		return "amount"
+	}
+	return "created_at"
+}
+
+// mongoValue is the cursor's sort value as stored in MongoDB
+func (c *PageCursor) mongoValue() interface{} {
+	if c.Field == SortByAmount {
+		return models.NewMoneyFromMinor(c.Value)
+	}
+	return primitive.DateTime(c.Value)
+}
+
+// postgresValue is the cursor's sort value as a SQL parameter
+func (c *PageCursor) postgresValue() interface{} {
+	if c.Field == SortByAmount {
+		return models.NewMoneyFromMinor(c.Value)
+	}
+	return time.UnixMilli(c.Value).UTC()
+}
+
+// mongoPageFilter narrows filter to the items after page.After
+func mongoPageFilter(filter bson.M, page PageRequest, order pageOrder) bson.M {
+	if page.After == nil {
+		return filter
+	}
+
+	op := "$gt"
+	if order.descending {
+		op = "$lt"
+	}
+
+	column, value := order.column(), page.After.mongoValue()
+	after := bson.M{"$or": bson.A{
+		bson.M{column: bson.M{op: value}},
+		bson.M{column: value, "_id": bson.M{op: page.After.ID}},
+	}}
+
+	if len(filter) == 0 {
+		return after
+	}
+	return bson.M{"$and": bson.A{filter, after}}
+}
+
+// findMongoPage streams one page of documents matching filter in order. It
+// reads one document past the page to learn whether another page follows, and
+// returns its cursor if so.
+func findMongoPage[T any](ctx context.Context, collection *mongo.Collection, filter bson.M, page PageRequest, order pageOrder, key func(*T) PageCursor) ([]T, *PageCursor, error) {
+	if err := order.check(page); err != nil {
+		return nil, nil, err
+	}
+
+	direction := 1
+	if order.descending {
+		direction = -1
+	}
+
+	size := page.Size()
+	opts := options.Find().
+		SetSort(bson.D{{Key: order.column(), Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(size + 1))
+
+	cursor, err := collection.Find(ctx, mongoPageFilter(filter, page, order), opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	items := make([]T, 0, size)
+	for len(items) < size && cursor.Next(ctx) {
+		var item T
+		if err := cursor.Decode(&item); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode page: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	more := len(items) == size && cursor.Next(ctx)
+	if err := cursor.Err(); err != nil {
+		return nil, nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return items, nextPageCursor(items, more, key), nil
+}
+
+// postgresPageClause returns the keyset condition and ORDER BY ... LIMIT for
+// page, numbering its placeholders after the argCount already in use
+func postgresPageClause(page PageRequest, order pageOrder, argCount int) (string, string, []interface{}) {
+	op, direction := ">", "ASC"
+	if order.descending {
+		op, direction = "<", "DESC"
+	}
+
+	column := order.column()
+	tail := fmt.Sprintf(` ORDER BY %s %s, id %s LIMIT %d`, column, direction, direction, page.Size()+1)
+	if page.After == nil {
+		return "", tail, nil
+	}
+
+	condition := fmt.Sprintf(`(%s, id) %s ($%d, $%d)`, column, op, argCount+1, argCount+2)
+	return condition, tail, []interface{}{page.After.postgresValue(), page.After.ID.Hex()}
+}
+
+// trimPostgresPage drops the extra row read past the page and returns the
+// cursor of the next page if that row existed
+func trimPostgresPage[T any](items []T, page PageRequest, key func(*T) PageCursor) ([]T, *PageCursor) {
+	more := len(items) > page.Size()
+	if more {
+		items = items[:page.Size()]
+	}
+	return items, nextPageCursor(items, more, key)
+}
+
+// memoryPage cuts the page requested by page out of sorted, which must
+// already be in order
+func memoryPage[T any](sorted []T, page PageRequest, order pageOrder, key func(*T) PageCursor) ([]T, *PageCursor, error) {
+	if err := order.check(page); err != nil {
+		return nil, nil, err
+	}
+
+	start := 0
+	if page.After != nil {
+		for start < len(sorted) && !page.After.comesAfter(key(&sorted[start])) {
+			start++
+		}
+	}
+
+	end := start + page.Size()
+	if end > len(sorted) {
+		end = len(sorted)
+	}
+
+	items := append(make([]T, 0, end-start), sorted[start:end]...)
+	return items, nextPageCursor(items, end < len(sorted), key), nil
+}
+
+// nextPageCursor points past the last item when more items follow
+func nextPageCursor[T any](items []T, more bool, key func(*T) PageCursor) *PageCursor {
+	if !more || len(items) == 0 {
+		return nil
+	}
+	next := key(&items[len(items)-1])
+	return &next
+}
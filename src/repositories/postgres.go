@@ -0,0 +1,272 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PostgreSQL error codes the repositories react to
+const (
+	pgUniqueViolation      = "23505"
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+	pgUndefinedTable       = "42P01"
+)
+
+// maxPostgresAttempts bounds how often a transaction is retried after a
+// serialization failure or deadlock
+const maxPostgresAttempts = 3
+
+type pgTxKey struct{}
+
+// sqlQuerier is the part of *sql.DB and *sql.Tx the repositories use
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// PostgresTransactor runs work inside a SQL transaction
+type PostgresTransactor struct {
+	db *sql.DB
+}
+
+func NewPostgresTransactor(db *sql.DB) *PostgresTransactor {
+	return &PostgresTransactor{db: db}
+}
+
+// WithTransaction runs fn inside a SQL transaction that every repository call
+// made with the ctx passed to fn joins. Like the MongoDB driver it retries fn
+// when the database aborts the transaction because of a conflict.
+func (t *PostgresTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	// Nested calls join the transaction that is already running
+	if _, ok := ctx.Value(pgTxKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	var err error
+	for attempt := 0; attempt < maxPostgresAttempts; attempt++ {
+		err = t.runOnce(ctx, fn)
+		if !isRetryablePostgresError(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (t *PostgresTransactor) runOnce(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(context.WithValue(ctx, pgTxKey{}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// querier returns the transaction carried by ctx, or db outside one
+func querier(ctx context.Context, db *sql.DB) sqlQuerier {
+	if tx, ok := ctx.Value(pgTxKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+func postgresErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+func isRetryablePostgresError(err error) bool {
+	code := postgresErrorCode(err)
+	return code == pgSerializationFailure || code == pgDeadlockDetected
+}
+
+// parseHexID turns a CHAR(24) column back into an ObjectID
+func parseHexID(hex string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(strings.TrimSpace(hex))
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid stored ID %q: %w", hex, err)
+	}
+	return id, nil
+}
+
+// nullTime maps a zero DateTime to SQL NULL
+func nullTime(t primitive.DateTime) sql.NullTime {
+	if t == 0 {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.Time(), Valid: true}
+}
+
+func fromNullTime(t sql.NullTime) primitive.DateTime {
+	if !t.Valid {
+		return 0
+	}
+	return primitive.NewDateTimeFromTime(t.Time)
+}
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+type sqlMigration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// MigratePostgres applies the embedded schema migrations that have not run
+// yet, in version order, and records each one in schema_migrations. An
+// advisory lock keeps concurrently starting instances from racing.
+func MigratePostgres(ctx context.Context, db *sql.DB) error {
+	migrations, err := loadPostgresMigrations()
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	const lockKey = 727318
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("failed to lock schema migrations: %w", err)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	for _, migration := range migrations {
+		var applied bool
+		err := conn.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, migration.version).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("failed to check migration %d: %w", migration.version, err)
+		}
+		if applied {
+			continue
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", migration.version, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, migration.sql); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d_%s: %w", migration.version, migration.name, err)
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`,
+			migration.version, migration.name, time.Now())
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", migration.version, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", migration.version, err)
+		}
+	}
+
+	return nil
+}
+
+// PendingPostgresMigrations returns how many embedded schema migrations have
+// not been applied yet
+func PendingPostgresMigrations(ctx context.Context, db *sql.DB) (int, error) {
+	migrations, err := loadPostgresMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	applied := map[int]bool{}
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if postgresErrorCode(err) == pgUndefinedTable {
+		// Nothing has been migrated yet
+		return len(migrations), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return 0, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("cursor error: %w", err)
+	}
+
+	pending := 0
+	for _, migration := range migrations {
+		if !applied[migration.version] {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// loadPostgresMigrations reads files named <version>_<name>.sql
+func loadPostgresMigrations() ([]sqlMigration, error) {
+	files, err := fs.Glob(postgresMigrations, "migrations/postgres/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	var migrations []sqlMigration
+	for _, file := range files {
+		base := strings.TrimSuffix(file[strings.LastIndex(file, "/")+1:], ".sql")
+		prefix, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s must be named <version>_<name>.sql", file)
+		}
+
+		contents, err := postgresMigrations.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+
+		migrations = append(migrations, sqlMigration{version: version, name: name, sql: string(contents)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	return migrations, nil
+}
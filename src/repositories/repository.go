@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"context"
+	"finance_app/src/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AccountRepository stores accounts and applies balance changes
+type AccountRepository interface {
+	FindOne(ctx context.Context, id string) (*models.Accounts, error)
+	Credit(ctx context.Context, id string, amount models.Money) (*models.Accounts, error)
+	Debit(ctx context.Context, id string, amount models.Money) (*models.Accounts, error)
+	GetAllAccounts(ctx context.Context, page PageRequest) ([]models.Accounts, *PageCursor, error)
+	GetByOwner(ctx context.Context, owner string, page PageRequest) ([]models.Accounts, *PageCursor, error)
+	ForEachAccount(ctx context.Context, fn func(*models.Accounts) error) error
+	CreateAccount(ctx context.Context, account *models.Accounts) error
+	UpdateDetails(ctx context.Context, id string, update models.AccountUpdate) (*models.Accounts, error)
+	UpdateStatus(ctx context.Context, id string, status models.AccountStatus) (*models.Accounts, error)
+}
+
+// TransactionRepository stores the transaction history of accounts
+type TransactionRepository interface {
+	Create(ctx context.Context, transaction *models.Transaction) error
+	GetAllTransactions(ctx context.Context, query TransactionQuery) ([]models.Transaction, *PageCursor, error)
+	GetByID(ctx context.Context, id string) (*models.Transaction, error)
+	GetByAccountID(ctx context.Context, accountID string, query TransactionQuery) ([]*models.Transaction, *PageCursor, error)
+	ForEachByAccountID(ctx context.Context, accountID string, fn func(*models.Transaction) error) error
+	SetBalance(ctx context.Context, id primitive.ObjectID, balance models.Money) error
+	AddReversal(ctx context.Context, id primitive.ObjectID, amount models.Money) (*models.Transaction, error)
+	GetReversals(ctx context.Context, id primitive.ObjectID) ([]models.Transaction, error)
+}
+
+// IdempotencyRepository remembers Idempotency-Key requests and their responses
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, key, fingerprint string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error
+	Release(ctx context.Context, key string) error
+}
+
+// LedgerRepository stores the double-entry journal underneath account
+// balances
+type LedgerRepository interface {
+	Post(ctx context.Context, entry *models.JournalEntry) error
+	Balance(ctx context.Context, account string) (models.Money, error)
+	ForEachEntry(ctx context.Context, fn func(*models.JournalEntry) error) error
+}
+
+// APIKeyRepository stores API keys, looked up by the hash of the key
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	FindByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	List(ctx context.Context) ([]models.APIKey, error)
+	Revoke(ctx context.Context, id string) (*models.APIKey, error)
+	MarkUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error
+}
+
+// AuditRepository stores the audit trail. Events can only be appended; there
+// is no way to change or remove one. Events appended inside a transaction may
+// wait in an outbox until ChainPending links them into the trail, so that
+// transactions do not queue behind each other for the end of the chain.
+type AuditRepository interface {
+	Append(ctx context.Context, event *models.AuditEvent) error
+	ChainPending(ctx context.Context) (int, error)
+	Find(ctx context.Context, query AuditQuery) ([]models.AuditEvent, *PageCursor, error)
+	ForEachEvent(ctx context.Context, fn func(*models.AuditEvent) error) error
+}
+
+// Transactor runs fn so that every repository call made with the ctx passed
+// to fn commits or rolls back as one unit
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+var (
+	_ AccountRepository     = (*AccountsMongoRepository)(nil)
+	_ TransactionRepository = (*TransactionMongoRepository)(nil)
+	_ IdempotencyRepository = (*IdempotencyMongoRepository)(nil)
+	_ LedgerRepository      = (*LedgerMongoRepository)(nil)
+	_ APIKeyRepository      = (*APIKeyMongoRepository)(nil)
+	_ AuditRepository       = (*AuditMongoRepository)(nil)
+	_ Transactor            = (*MongoTransactor)(nil)
+
+	_ AccountRepository     = (*AccountsMemoryRepository)(nil)
+	_ TransactionRepository = (*TransactionMemoryRepository)(nil)
+	_ IdempotencyRepository = (*IdempotencyMemoryRepository)(nil)
+	_ LedgerRepository      = (*LedgerMemoryRepository)(nil)
+	_ APIKeyRepository      = (*APIKeyMemoryRepository)(nil)
+	_ AuditRepository       = (*AuditMemoryRepository)(nil)
+	_ Transactor            = (*MemoryTransactor)(nil)
+
+	_ AccountRepository     = (*AccountsPostgresRepository)(nil)
+	_ TransactionRepository = (*TransactionPostgresRepository)(nil)
+	_ IdempotencyRepository = (*IdempotencyPostgresRepository)(nil)
+	_ LedgerRepository      = (*LedgerPostgresRepository)(nil)
+	_ APIKeyRepository      = (*APIKeyPostgresRepository)(nil)
+	_ AuditRepository       = (*AuditPostgresRepository)(nil)
+	_ Transactor            = (*PostgresTransactor)(nil)
+)
@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"errors"
+	"finance_app/src/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TransactionQuery filters, sorts and pages a transaction list. The zero
+// value matches every transaction, newest first.
+type TransactionQuery struct {
+	// Type keeps only transactions of this type when set
+	Type models.TransactionType
+	// AccountIDs keeps only transactions of these accounts when not empty
+	AccountIDs []primitive.ObjectID
+	// From and To bound created_at, both inclusive
+	From *time.Time
+	To   *time.Time
+	// MinAmount and MaxAmount bound the amount, both inclusive
+	MinAmount *models.Money
+	MaxAmount *models.Money
+	// SortBy defaults to SortByCreatedAt
+	SortBy    SortField
+	Ascending bool
+	Page      PageRequest
+}
+
+// Validate checks the filters and that the page cursor was issued for the
+// same sort order
+func (q TransactionQuery) Validate() error {
+	switch q.Type {
+	case "", models.Deposit, models.Withdraw, models.Transfer, models.Reversal:
+	default:
+		return fmt.Errorf("invalid transaction type: %s", q.Type)
+	}
+
+	switch q.SortBy {
+	case "", SortByCreatedAt, SortByAmount:
+	default:
+		return fmt.Errorf("invalid sort field: %s", q.SortBy)
+	}
+
+	if q.From != nil && q.To != nil && q.To.Before(*q.From) {
+		return errors.New("date range ends before it starts")
+	}
+
+	if (q.MinAmount != nil && q.MinAmount.IsNegative()) || (q.MaxAmount != nil && q.MaxAmount.IsNegative()) {
+		return errors.New("amount bounds cannot be negative")
+	}
+
+	if q.MinAmount != nil && q.MaxAmount != nil && q.MaxAmount.LessThan(*q.MinAmount) {
+		return errors.New("minimum amount is greater than maximum amount")
+	}
+
+	return q.order().check(q.Page)
+}
+
+// order is the order the query's pages are read in
+func (q TransactionQuery) order() pageOrder {
+	field := q.SortBy
+	if field == "" {
+		field = SortByCreatedAt
+	}
+	return pageOrder{field: field, descending: !q.Ascending}
+}
+
+// matches applies the filters to one transaction, with created_at compared at
+// the millisecond precision it is stored with
+func (q TransactionQuery) matches(t *models.Transaction) bool {
+	if q.Type != "" && t.TransactionType != q.Type {
+		return false
+	}
+	if len(q.AccountIDs) > 0 && !containsID(q.AccountIDs, t.AccountId) {
+		return false
+	}
+	if q.From != nil && t.CreatedAt < primitive.NewDateTimeFromTime(*q.From) {
+		return false
+	}
+	if q.To != nil && t.CreatedAt > primitive.NewDateTimeFromTime(*q.To) {
+		return false
+	}
+	if q.MinAmount != nil && t.Amount.LessThan(*q.MinAmount) {
+		return false
+	}
+	if q.MaxAmount != nil && q.MaxAmount.LessThan(t.Amount) {
+		return false
+	}
+	return true
+}
+
+// mongoFilter adds the filters to filter
+func (q TransactionQuery) mongoFilter(filter bson.M) bson.M {
+	if q.Type != "" {
+		filter["transactionType"] = q.Type
+	}
+	if len(q.AccountIDs) > 0 {
+		filter["accountId"] = bson.M{"$in": q.AccountIDs}
+	}
+
+	createdAt := bson.M{}
+	if q.From != nil {
+		createdAt["$gte"] = primitive.NewDateTimeFromTime(*q.From)
+	}
+	if q.To != nil {
+		createdAt["$lte"] = primitive.NewDateTimeFromTime(*q.To)
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+
+	amount := bson.M{}
+	if q.MinAmount != nil {
+		amount["$gte"] = *q.MinAmount
+	}
+	if q.MaxAmount != nil {
+		amount["$lte"] = *q.MaxAmount
+	}
+	if len(amount) > 0 {
+		filter["amount"] = amount
+	}
+
+	return filter
+}
+
+// postgresConditions adds the filters to conditions, numbering their
+// placeholders after the args already in use
+func (q TransactionQuery) postgresConditions(conditions []string, args []interface{}) ([]string, []interface{}) {
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if q.Type != "" {
+		add(`transaction_type = $%d`, string(q.Type))
+	}
+	if len(q.AccountIDs) > 0 {
+		placeholders := make([]string, len(q.AccountIDs))
+		for i, id := range q.AccountIDs {
+			args = append(args, id.Hex())
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		conditions = append(conditions, `account_id IN (`+strings.Join(placeholders, ", ")+`)`)
+	}
+	if q.From != nil {
+		add(`created_at >= $%d`, primitive.NewDateTimeFromTime(*q.From).Time())
+	}
+	if q.To != nil {
+		add(`created_at <= $%d`, primitive.NewDateTimeFromTime(*q.To).Time())
+	}
+	if q.MinAmount != nil {
+		add(`amount >= $%d`, *q.MinAmount)
+	}
+	if q.MaxAmount != nil {
+		add(`amount <= $%d`, *q.MaxAmount)
+	}
+
+	return conditions, args
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
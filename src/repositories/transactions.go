@@ -13,17 +13,32 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type TransactionMongoRepository struct {
-	collection *mongo.Collection
+// ReversalError is returned when a reversal asks for more than is left to
+// reverse of a transaction, or the transaction cannot be reversed at all
+type ReversalError struct {
+	TransactionID string
+	Requested     models.Money
+	Reversible    models.Money
 }
 
-func NewTransactionMongoRepository(db *mongo.Database) *TransactionMongoRepository {
-	return &TransactionMongoRepository{
-		collection: db.Collection("transactions"),
+func (e *ReversalError) Error() string {
+	if !e.Reversible.IsPositive() {
+		return fmt.Sprintf("transaction %s cannot be reversed any further", e.TransactionID)
 	}
+	return fmt.Sprintf("cannot reverse %s of transaction %s, only %s is left to reverse", e.Requested, e.TransactionID, e.Reversible)
 }
 
-func (r *TransactionMongoRepository) Create(ctx context.Context, transaction *models.Transaction) error {
+// reversalStatus is the status of a transaction of amount once reversed of it
+// has been given back
+func reversalStatus(amount, reversed models.Money) models.ReversalStatus {
+	if reversed.Cmp(amount) >= 0 {
+		return models.FullyReversed
+	}
+	return models.PartiallyReversed
+}
+
+// validateTransaction checks a transaction before it is stored
+func validateTransaction(transaction *models.Transaction) error {
 	// Validate required fields
 	if transaction == nil {
 		return errors.New("transaction cannot be nil")
@@ -33,7 +48,7 @@ func (r *TransactionMongoRepository) Create(ctx context.Context, transaction *mo
 		return errors.New("transaction type is required")
 	}
 
-	if transaction.Amount <= 0 {
+	if !transaction.Amount.IsPositive() {
 		return errors.New("amount must be greater than 0")
 	}
 
@@ -43,12 +58,50 @@ func (r *TransactionMongoRepository) Create(ctx context.Context, transaction *mo
 
 	// Enforce enum validation
 	switch transaction.TransactionType {
-	case models.Deposit, models.Withdraw, models.Transfer:
+	case models.Deposit, models.Withdraw, models.Transfer, models.Reversal:
 		// Valid transaction type
 	default:
 		return fmt.Errorf("invalid transaction type: %s", transaction.TransactionType)
 	}
 
+	// Transfers must say which side they are and who the other side is
+	if transaction.TransactionType == models.Transfer {
+		if transaction.Direction != models.TransferOut && transaction.Direction != models.TransferIn {
+			return fmt.Errorf("invalid transfer direction: %s", transaction.Direction)
+		}
+		if transaction.CounterpartyAccountId == nil || transaction.CounterpartyAccountId.IsZero() {
+			return errors.New("counterparty account ID is required for transfers")
+		}
+	}
+
+	// Reversals must say which way the money went and what they give back
+	if transaction.TransactionType == models.Reversal {
+		if transaction.Direction != models.TransferOut && transaction.Direction != models.TransferIn {
+			return fmt.Errorf("invalid reversal direction: %s", transaction.Direction)
+		}
+		if transaction.ReversalOf == nil || transaction.ReversalOf.IsZero() {
+			return errors.New("reversed transaction ID is required for reversals")
+		}
+	}
+
+	return nil
+}
+
+type TransactionMongoRepository struct {
+	collection *mongo.Collection
+}
+
+func NewTransactionMongoRepository(db *mongo.Database) *TransactionMongoRepository {
+	return &TransactionMongoRepository{
+		collection: db.Collection("transactions"),
+	}
+}
+
+func (r *TransactionMongoRepository) Create(ctx context.Context, transaction *models.Transaction) error {
+	if err := validateTransaction(transaction); err != nil {
+		return err
+	}
+
 	// Set creation timestamp
 	if transaction.ID.IsZero() {
 		transaction.ID = primitive.NewObjectID()
@@ -69,33 +122,20 @@ func (r *TransactionMongoRepository) Create(ctx context.Context, transaction *mo
 	return nil
 }
 
-func (r *TransactionMongoRepository) GetAllTransactions(ctx context.Context) ([]models.Transaction, error) {
-	var transactions []models.Transaction
-
-	// Find all transactions, sorted by creation date (newest first)
-	opts := options.Find().SetSort(bson.D{{"created_at", -1}})
-	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
-	}
-	defer cursor.Close(ctx)
-
-	// Decode all transactions
-	if err = cursor.All(ctx, &transactions); err != nil {
-		return nil, fmt.Errorf("failed to decode transactions: %w", err)
-	}
-
-	// Check for cursor errors
-	if err = cursor.Err(); err != nil {
-		return nil, fmt.Errorf("cursor error: %w", err)
+// GetAllTransactions returns one page of the transactions matching query, and
+// the cursor of the next page if there is one
+func (r *TransactionMongoRepository) GetAllTransactions(ctx context.Context, query TransactionQuery) ([]models.Transaction, *PageCursor, error) {
+	if err := query.Validate(); err != nil {
+		return nil, nil, err
 	}
 
-	// Return empty slice instead of nil if no transactions found
-	if transactions == nil {
-		transactions = []models.Transaction{}
+	order := query.order()
+	transactions, next, err := findMongoPage(ctx, r.collection, query.mongoFilter(bson.M{}), query.Page, order, order.transactionKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch transactions: %w", err)
 	}
 
-	return transactions, nil
+	return transactions, next, nil
 }
 
 func (r *TransactionMongoRepository) GetByID(ctx context.Context, id string) (*models.Transaction, error) {
@@ -122,35 +162,159 @@ func (r *TransactionMongoRepository) GetByID(ctx context.Context, id string) (*m
 	return &transaction, nil
 }
 
-func (r *TransactionMongoRepository) GetByAccountID(ctx context.Context, accountID string) ([]*models.Transaction, error) {
+// GetByAccountID returns one page of an account's transactions matching
+// query, and the cursor of the next page if there is one
+func (r *TransactionMongoRepository) GetByAccountID(ctx context.Context, accountID string, query TransactionQuery) ([]*models.Transaction, *PageCursor, error) {
 	// Validate and parse account ID
+	objID, err := parseTransactionAccountID(accountID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := query.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	order := query.order()
+	filter := query.mongoFilter(bson.M{"accountId": objID})
+	transactions, next, err := findMongoPage(ctx, r.collection, filter, query.Page, order, order.transactionKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch transactions for account: %w", err)
+	}
+
+	return transactionPointers(transactions), next, nil
+}
+
+// ForEachByAccountID streams an account's transactions oldest first, in
+// created_at order with _id as the tie-breaker, without loading them all
+func (r *TransactionMongoRepository) ForEachByAccountID(ctx context.Context, accountID string, fn func(*models.Transaction) error) error {
 	if accountID == "" {
-		return nil, errors.New("account ID cannot be empty")
+		return errors.New("account ID cannot be empty")
 	}
 
 	objID, err := primitive.ObjectIDFromHex(accountID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid account ID format: %w", err)
+		return fmt.Errorf("invalid account ID format: %w", err)
 	}
 
-	// Find transactions for account, sorted by date (newest first)
-	opts := options.Find().SetSort(bson.D{{"created_at", -1}})
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
 	cursor, err := r.collection.Find(ctx, bson.M{"accountId": objID}, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch transactions for account: %w", err)
+		return fmt.Errorf("failed to fetch transactions for account: %w", err)
 	}
 	defer cursor.Close(ctx)
 
-	// Decode all transactions
-	var transactions []*models.Transaction
-	if err = cursor.All(ctx, &transactions); err != nil {
-		return nil, fmt.Errorf("failed to decode transactions: %w", err)
+	for cursor.Next(ctx) {
+		var transaction models.Transaction
+		if err := cursor.Decode(&transaction); err != nil {
+			return fmt.Errorf("failed to decode transaction: %w", err)
+		}
+		if err := fn(&transaction); err != nil {
+			return err
+		}
 	}
 
-	// Return empty slice instead of nil if no transactions found
-	if transactions == nil {
-		transactions = []*models.Transaction{}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor error: %w", err)
 	}
 
-	return transactions, nil
+	return nil
+}
+
+// SetBalance overwrites the running balance stored on a transaction
+func (r *TransactionMongoRepository) SetBalance(ctx context.Context, id primitive.ObjectID, balance models.Money) error {
+	update := bson.M{"$set": bson.M{
+		"balance":    balance,
+		"updated_at": primitive.NewDateTimeFromTime(time.Now()),
+	}}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update transaction balance: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("transaction not found with ID: %s", id.Hex())
+	}
+
+	return nil
+}
+
+// AddReversal atomically adds amount to what has been reversed of the
+// transaction and marks it partially or fully reversed. It returns a
+// *ReversalError and changes nothing when amount is more than is left to
+// reverse, or the transaction is itself a reversal.
+func (r *TransactionMongoRepository) AddReversal(ctx context.Context, id primitive.ObjectID, amount models.Money) (*models.Transaction, error) {
+	if !amount.IsPositive() {
+		return nil, errors.New("amount must be greater than 0")
+	}
+
+	// Transactions stored before reversals existed have no reversedAmount
+	reversed := bson.M{"$ifNull": bson.A{"$reversedAmount", models.Money{}}}
+	filter := bson.M{
+		"_id":             id,
+		"transactionType": bson.M{"$ne": models.Reversal},
+		"$expr":           bson.M{"$lte": bson.A{bson.M{"$add": bson.A{reversed, amount}}, "$amount"}},
+	}
+
+	update := bson.A{
+		bson.M{"$set": bson.M{
+			"reversedAmount": bson.M{"$add": bson.A{reversed, amount}},
+			"updated_at":     primitive.NewDateTimeFromTime(time.Now()),
+		}},
+		bson.M{"$set": bson.M{
+			"reversalStatus": bson.M{"$cond": bson.A{
+				bson.M{"$gte": bson.A{"$reversedAmount", "$amount"}},
+				models.FullyReversed,
+				models.PartiallyReversed,
+			}},
+		}},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var transaction models.Transaction
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&transaction)
+	if err == nil {
+		return &transaction, nil
+	}
+
+	if err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to reverse transaction: %w", err)
+	}
+
+	// Nothing matched: the transaction is missing or the guard failed
+	current, findErr := r.GetByID(ctx, id.Hex())
+	if findErr != nil {
+		return nil, findErr
+	}
+
+	return nil, &ReversalError{TransactionID: id.Hex(), Requested: amount, Reversible: current.ReversibleAmount()}
+}
+
+// GetReversals returns the reversals of a transaction, oldest first
+func (r *TransactionMongoRepository) GetReversals(ctx context.Context, id primitive.ObjectID) ([]models.Transaction, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"reversalOf": id}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reversals: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	reversals := []models.Transaction{}
+	if err := cursor.All(ctx, &reversals); err != nil {
+		return nil, fmt.Errorf("failed to decode reversals: %w", err)
+	}
+
+	return reversals, nil
+}
+
+// transactionPointers adapts a page to the []*models.Transaction that
+// GetByAccountID returns
+func transactionPointers(transactions []models.Transaction) []*models.Transaction {
+	pointers := make([]*models.Transaction, len(transactions))
+	for i := range transactions {
+		pointers[i] = &transactions[i]
+	}
+	return pointers
 }
@@ -0,0 +1,234 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"finance_app/src/models"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TransactionMemoryRepository is a thread-safe in-memory TransactionRepository
+// with the same validation, ordering and errors as TransactionMongoRepository
+type TransactionMemoryRepository struct {
+	mu           sync.RWMutex
+	transactions map[primitive.ObjectID]*models.Transaction
+}
+
+func NewTransactionMemoryRepository() *TransactionMemoryRepository {
+	return &TransactionMemoryRepository{
+		transactions: make(map[primitive.ObjectID]*models.Transaction),
+	}
+}
+
+func (r *TransactionMemoryRepository) Create(ctx context.Context, transaction *models.Transaction) error {
+	if err := validateTransaction(transaction); err != nil {
+		return err
+	}
+
+	if transaction.ID.IsZero() {
+		transaction.ID = primitive.NewObjectID()
+	}
+	transaction.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.transactions[transaction.ID]; exists {
+		return fmt.Errorf("failed to create transaction: duplicate ID %s", transaction.ID.Hex())
+	}
+
+	stored := *transaction
+	r.transactions[stored.ID] = &stored
+
+	id := stored.ID
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.transactions, id)
+	})
+
+	return nil
+}
+
+func (r *TransactionMemoryRepository) GetAllTransactions(ctx context.Context, query TransactionQuery) ([]models.Transaction, *PageCursor, error) {
+	if err := query.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	order := query.order()
+	matched := r.matching(query.matches, order)
+
+	transactions := make([]models.Transaction, 0, len(matched))
+	for _, transaction := range matched {
+		transactions = append(transactions, *transaction)
+	}
+
+	return memoryPage(transactions, query.Page, order, order.transactionKey)
+}
+
+func (r *TransactionMemoryRepository) GetByID(ctx context.Context, id string) (*models.Transaction, error) {
+	if id == "" {
+		return nil, errors.New("transaction ID cannot be empty")
+	}
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction ID format: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	transaction, ok := r.transactions[objID]
+	if !ok {
+		return nil, fmt.Errorf("transaction not found with ID: %s", id)
+	}
+
+	copied := *transaction
+	return &copied, nil
+}
+
+func (r *TransactionMemoryRepository) GetByAccountID(ctx context.Context, accountID string, query TransactionQuery) ([]*models.Transaction, *PageCursor, error) {
+	objID, err := parseTransactionAccountID(accountID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := query.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	order := query.order()
+	matched := r.matching(func(t *models.Transaction) bool { return t.AccountId == objID && query.matches(t) }, order)
+	return memoryPage(matched, query.Page, order, func(t **models.Transaction) PageCursor {
+		return order.transactionKey(*t)
+	})
+}
+
+func (r *TransactionMemoryRepository) ForEachByAccountID(ctx context.Context, accountID string, fn func(*models.Transaction) error) error {
+	objID, err := parseTransactionAccountID(accountID)
+	if err != nil {
+		return err
+	}
+
+	oldestFirst := pageOrder{field: SortByCreatedAt}
+	for _, transaction := range r.matching(func(t *models.Transaction) bool { return t.AccountId == objID }, oldestFirst) {
+		if err := fn(transaction); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *TransactionMemoryRepository) SetBalance(ctx context.Context, id primitive.ObjectID, balance models.Money) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transaction, ok := r.transactions[id]
+	if !ok {
+		return fmt.Errorf("transaction not found with ID: %s", id.Hex())
+	}
+
+	previous := transaction.Balance
+	transaction.Balance = balance
+	transaction.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if transaction, ok := r.transactions[id]; ok {
+			transaction.Balance = previous
+		}
+	})
+
+	return nil
+}
+
+func (r *TransactionMemoryRepository) AddReversal(ctx context.Context, id primitive.ObjectID, amount models.Money) (*models.Transaction, error) {
+	if !amount.IsPositive() {
+		return nil, errors.New("amount must be greater than 0")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transaction, ok := r.transactions[id]
+	if !ok {
+		return nil, fmt.Errorf("transaction not found with ID: %s", id.Hex())
+	}
+
+	if reversible := transaction.ReversibleAmount(); reversible.LessThan(amount) {
+		return nil, &ReversalError{TransactionID: id.Hex(), Requested: amount, Reversible: reversible}
+	}
+
+	previous := *transaction
+	transaction.ReversedAmount = transaction.ReversedAmount.Add(amount)
+	transaction.ReversalStatus = reversalStatus(transaction.Amount, transaction.ReversedAmount)
+	transaction.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	onRollback(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if transaction, ok := r.transactions[id]; ok {
+			transaction.ReversedAmount = previous.ReversedAmount
+			transaction.ReversalStatus = previous.ReversalStatus
+			transaction.UpdatedAt = previous.UpdatedAt
+		}
+	})
+
+	copied := *transaction
+	return &copied, nil
+}
+
+func (r *TransactionMemoryRepository) GetReversals(ctx context.Context, id primitive.ObjectID) ([]models.Transaction, error) {
+	oldestFirst := pageOrder{field: SortByCreatedAt}
+	matched := r.matching(func(t *models.Transaction) bool { return t.ReversalOf != nil && *t.ReversalOf == id }, oldestFirst)
+
+	reversals := make([]models.Transaction, 0, len(matched))
+	for _, reversal := range matched {
+		reversals = append(reversals, *reversal)
+	}
+	return reversals, nil
+}
+
+// matching returns copies of the transactions accepted by keep, sorted in
+// order
+func (r *TransactionMemoryRepository) matching(keep func(*models.Transaction) bool, order pageOrder) []*models.Transaction {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	transactions := []*models.Transaction{}
+	for _, transaction := range r.transactions {
+		if keep(transaction) {
+			copied := *transaction
+			transactions = append(transactions, &copied)
+		}
+	}
+
+	sort.Slice(transactions, func(i, j int) bool {
+		return order.less(order.transactionKey(transactions[i]), order.transactionKey(transactions[j]))
+	})
+
+	return transactions
+}
+
+// parseTransactionAccountID validates an account ID the same way
+// TransactionMongoRepository.GetByAccountID does
+func parseTransactionAccountID(accountID string) (primitive.ObjectID, error) {
+	if accountID == "" {
+		return primitive.NilObjectID, errors.New("account ID cannot be empty")
+	}
+
+	objID, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid account ID format: %w", err)
+	}
+
+	return objID, nil
+}
@@ -0,0 +1,356 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"finance_app/src/models"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const transactionColumns = "id, transaction_type, amount, balance, account_id, direction, counterparty_account_id, linked_transaction_id, reversal_of, reversed_amount, reversal_status, created_at, updated_at"
+
+// postgresStreamBatch is how many rows ForEachByAccountID reads per query
+const postgresStreamBatch = 500
+
+type TransactionPostgresRepository struct {
+	db *sql.DB
+}
+
+func NewTransactionPostgresRepository(db *sql.DB) *TransactionPostgresRepository {
+	return &TransactionPostgresRepository{db: db}
+}
+
+func scanTransaction(row rowScanner) (*models.Transaction, error) {
+	var (
+		transaction  models.Transaction
+		id           string
+		accountID    string
+		direction    sql.NullString
+		counterparty sql.NullString
+		linked       sql.NullString
+		reversalOf   sql.NullString
+		status       sql.NullString
+		createdAt    time.Time
+		updatedAt    sql.NullTime
+	)
+
+	err := row.Scan(&id, &transaction.TransactionType, &transaction.Amount, &transaction.Balance, &accountID,
+		&direction, &counterparty, &linked, &reversalOf, &transaction.ReversedAmount, &status, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if transaction.ID, err = parseHexID(id); err != nil {
+		return nil, err
+	}
+	if transaction.AccountId, err = parseHexID(accountID); err != nil {
+		return nil, err
+	}
+	if counterparty.Valid {
+		objID, err := parseHexID(counterparty.String)
+		if err != nil {
+			return nil, err
+		}
+		transaction.CounterpartyAccountId = &objID
+	}
+	if linked.Valid {
+		objID, err := parseHexID(linked.String)
+		if err != nil {
+			return nil, err
+		}
+		transaction.LinkedTransactionId = &objID
+	}
+	if reversalOf.Valid {
+		objID, err := parseHexID(reversalOf.String)
+		if err != nil {
+			return nil, err
+		}
+		transaction.ReversalOf = &objID
+	}
+
+	transaction.Direction = models.TransferDirection(direction.String)
+	transaction.ReversalStatus = models.ReversalStatus(status.String)
+	transaction.CreatedAt = primitive.NewDateTimeFromTime(createdAt)
+	transaction.UpdatedAt = fromNullTime(updatedAt)
+
+	return &transaction, nil
+}
+
+// nullHexID maps an optional ObjectID to a nullable CHAR(24)
+func nullHexID(id *primitive.ObjectID) sql.NullString {
+	if id == nil || id.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: id.Hex(), Valid: true}
+}
+
+func (r *TransactionPostgresRepository) Create(ctx context.Context, transaction *models.Transaction) error {
+	if err := validateTransaction(transaction); err != nil {
+		return err
+	}
+
+	// Set creation timestamp
+	if transaction.ID.IsZero() {
+		transaction.ID = primitive.NewObjectID()
+	}
+	transaction.CreatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	direction := sql.NullString{String: string(transaction.Direction), Valid: transaction.Direction != ""}
+	status := sql.NullString{String: string(transaction.ReversalStatus), Valid: transaction.ReversalStatus != ""}
+
+	_, err := querier(ctx, r.db).ExecContext(ctx,
+		`INSERT INTO transactions (`+transactionColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		transaction.ID.Hex(), transaction.TransactionType, transaction.Amount, transaction.Balance,
+		transaction.AccountId.Hex(), direction, nullHexID(transaction.CounterpartyAccountId),
+		nullHexID(transaction.LinkedTransactionId), nullHexID(transaction.ReversalOf), transaction.ReversedAmount,
+		status, transaction.CreatedAt.Time(), nullTime(transaction.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetAllTransactions returns one page of the transactions matching query, and
+// the cursor of the next page if there is one
+func (r *TransactionPostgresRepository) GetAllTransactions(ctx context.Context, query TransactionQuery) ([]models.Transaction, *PageCursor, error) {
+	if err := query.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	transactions, next, err := r.queryPage(ctx, nil, nil, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch transactions: %w", err)
+	}
+
+	return transactions, next, nil
+}
+
+// queryPage reads one page of the transactions matching both conditions and
+// the filters of q
+func (r *TransactionPostgresRepository) queryPage(ctx context.Context, conditions []string, args []interface{}, q TransactionQuery) ([]models.Transaction, *PageCursor, error) {
+	order := q.order()
+	page := q.Page
+
+	conditions, args = q.postgresConditions(conditions, args)
+	condition, tail, pageArgs := postgresPageClause(page, order, len(args))
+	if condition != "" {
+		conditions = append(conditions, condition)
+	}
+
+	query := `SELECT ` + transactionColumns + ` FROM transactions`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+
+	rows, err := querier(ctx, r.db).QueryContext(ctx, query+tail, append(args, pageArgs...)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	transactions := make([]models.Transaction, 0, page.Size()+1)
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode transactions: %w", err)
+		}
+		transactions = append(transactions, *transaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	transactions, next := trimPostgresPage(transactions, page, order.transactionKey)
+	return transactions, next, nil
+}
+
+func (r *TransactionPostgresRepository) GetByID(ctx context.Context, id string) (*models.Transaction, error) {
+	// Validate and parse ID
+	if id == "" {
+		return nil, errors.New("transaction ID cannot be empty")
+	}
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction ID format: %w", err)
+	}
+
+	row := querier(ctx, r.db).QueryRowContext(ctx, `SELECT `+transactionColumns+` FROM transactions WHERE id = $1`, objID.Hex())
+
+	transaction, err := scanTransaction(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("transaction not found with ID: %s", id)
+		}
+		return nil, fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+
+	return transaction, nil
+}
+
+// GetByAccountID returns one page of an account's transactions matching
+// query, and the cursor of the next page if there is one
+func (r *TransactionPostgresRepository) GetByAccountID(ctx context.Context, accountID string, query TransactionQuery) ([]*models.Transaction, *PageCursor, error) {
+	objID, err := parseTransactionAccountID(accountID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := query.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	transactions, next, err := r.queryPage(ctx, []string{`account_id = $1`}, []interface{}{objID.Hex()}, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch transactions for account: %w", err)
+	}
+
+	return transactionPointers(transactions), next, nil
+}
+
+// ForEachByAccountID streams an account's transactions oldest first, in
+// created_at order with id as the tie-breaker. It reads keyset-paginated
+// batches and closes each result set before calling fn, because a SQL
+// transaction cannot run fn's own queries while rows are still open.
+func (r *TransactionPostgresRepository) ForEachByAccountID(ctx context.Context, accountID string, fn func(*models.Transaction) error) error {
+	objID, err := parseTransactionAccountID(accountID)
+	if err != nil {
+		return err
+	}
+
+	var last *models.Transaction
+	for {
+		batch, err := r.accountBatch(ctx, objID, last)
+		if err != nil {
+			return err
+		}
+
+		for _, transaction := range batch {
+			if err := fn(transaction); err != nil {
+				return err
+			}
+		}
+
+		if len(batch) < postgresStreamBatch {
+			return nil
+		}
+		last = batch[len(batch)-1]
+	}
+}
+
+// accountBatch returns the next batch of an account's transactions after last
+func (r *TransactionPostgresRepository) accountBatch(ctx context.Context, accountID primitive.ObjectID, last *models.Transaction) ([]*models.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE account_id = $1`
+	args := []interface{}{accountID.Hex()}
+	if last != nil {
+		query += ` AND (created_at, id) > ($2, $3)`
+		args = append(args, last.CreatedAt.Time(), last.ID.Hex())
+	}
+	query += fmt.Sprintf(` ORDER BY created_at, id LIMIT %d`, postgresStreamBatch)
+
+	rows, err := querier(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions for account: %w", err)
+	}
+	defer rows.Close()
+
+	var batch []*models.Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode transaction: %w", err)
+		}
+		batch = append(batch, transaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return batch, nil
+}
+
+// SetBalance overwrites the running balance stored on a transaction
+func (r *TransactionPostgresRepository) SetBalance(ctx context.Context, id primitive.ObjectID, balance models.Money) error {
+	result, err := querier(ctx, r.db).ExecContext(ctx,
+		`UPDATE transactions SET balance = $2, updated_at = $3 WHERE id = $1`, id.Hex(), balance, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to update transaction balance: %w", err)
+	}
+
+	matched, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update transaction balance: %w", err)
+	}
+	if matched == 0 {
+		return fmt.Errorf("transaction not found with ID: %s", id.Hex())
+	}
+
+	return nil
+}
+
+// AddReversal atomically adds amount to what has been reversed of the
+// transaction, guarded like TransactionMongoRepository.AddReversal
+func (r *TransactionPostgresRepository) AddReversal(ctx context.Context, id primitive.ObjectID, amount models.Money) (*models.Transaction, error) {
+	if !amount.IsPositive() {
+		return nil, errors.New("amount must be greater than 0")
+	}
+
+	// The right-hand sides of SET all see the row as it was
+	row := querier(ctx, r.db).QueryRowContext(ctx,
+		`UPDATE transactions SET reversed_amount = reversed_amount + $2,
+			reversal_status = CASE WHEN reversed_amount + $2 >= amount THEN $4 ELSE $5 END,
+			updated_at = $3
+		WHERE id = $1 AND transaction_type <> $6 AND reversed_amount + $2 <= amount
+		RETURNING `+transactionColumns,
+		id.Hex(), amount, time.Now(), models.FullyReversed, models.PartiallyReversed, models.Reversal)
+
+	transaction, err := scanTransaction(row)
+	if err == nil {
+		return transaction, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to reverse transaction: %w", err)
+	}
+
+	// Nothing matched: the transaction is missing or the guard failed
+	current, findErr := r.GetByID(ctx, id.Hex())
+	if findErr != nil {
+		return nil, findErr
+	}
+
+	return nil, &ReversalError{TransactionID: id.Hex(), Requested: amount, Reversible: current.ReversibleAmount()}
+}
+
+// GetReversals returns the reversals of a transaction, oldest first
+func (r *TransactionPostgresRepository) GetReversals(ctx context.Context, id primitive.ObjectID) ([]models.Transaction, error) {
+	rows, err := querier(ctx, r.db).QueryContext(ctx,
+		`SELECT `+transactionColumns+` FROM transactions WHERE reversal_of = $1 ORDER BY created_at, id`, id.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reversals: %w", err)
+	}
+	defer rows.Close()
+
+	reversals := []models.Transaction{}
+	for rows.Next() {
+		reversal, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode reversals: %w", err)
+		}
+		reversals = append(reversals, *reversal)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	return reversals, nil
+}
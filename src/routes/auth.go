@@ -0,0 +1,128 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"finance_app/src/audit"
+	"finance_app/src/auth"
+	"finance_app/src/repositories"
+	"finance_app/src/utils"
+	"finance_app/src/utils/types"
+)
+
+// apiKeyUseInterval is how stale a key's last-used time may get before a
+// request records it again, so busy clients don't write on every request
+const apiKeyUseInterval = time.Minute
+
+// Authenticate requires a valid JWT bearer token, or an API key in the
+// X-API-Key header, and puts the principal it was issued for into the request
+// context. A nil verifier disables authentication, and every request then
+// runs as auth.Anonymous.
+func Authenticate(verifier *auth.Verifier, keys repositories.APIKeyRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if verifier == nil {
+				next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), auth.Anonymous)))
+				return
+			}
+
+			if key := r.Header.Get("X-API-Key"); key != "" && keys != nil {
+				principal, err := apiKeyPrincipal(r, keys, key)
+				if err != nil {
+					if !errors.Is(err, repositories.ErrAPIKeyNotFound) {
+						logrus.WithContext(r.Context()).Error("Failed to look up API key: ", err)
+					}
+					utils.SendJSONResponse(w, http.StatusUnauthorized, types.APIResponse{
+						Success: false,
+						Error:   "Invalid or expired API key",
+					})
+					return
+				}
+
+				trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("enduser.id", principal.Subject))
+				next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+				return
+			}
+
+			scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !strings.EqualFold(scheme, "Bearer") || token == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+				utils.SendJSONResponse(w, http.StatusUnauthorized, types.APIResponse{
+					Success: false,
+					Error:   "Bearer token is required",
+				})
+				return
+			}
+
+			principal, err := verifier.Verify(strings.TrimSpace(token))
+			if err != nil {
+				logrus.WithContext(r.Context()).Info("Rejected bearer token: ", err)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				utils.SendJSONResponse(w, http.StatusUnauthorized, types.APIResponse{
+					Success: false,
+					Error:   "Invalid or expired token",
+				})
+				return
+			}
+
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("enduser.id", principal.Subject))
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// apiKeyPrincipal returns the principal of an active API key, and records
+// that the key was used
+func apiKeyPrincipal(r *http.Request, keys repositories.APIKeyRepository, plaintext string) (auth.Principal, error) {
+	key, err := keys.FindByHash(r.Context(), auth.HashAPIKey(plaintext))
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	now := time.Now()
+	if !key.Active(now) {
+		return auth.Principal{}, repositories.ErrAPIKeyNotFound
+	}
+
+	if key.LastUsedAt == nil || now.Sub(key.LastUsedAt.Time()) > apiKeyUseInterval {
+		if err := keys.MarkUsed(r.Context(), key.ID, now); err != nil {
+			logrus.WithContext(r.Context()).Warn("Failed to record API key use: ", err)
+		}
+	}
+
+	principal := auth.Principal{
+		Subject:  "api-key:" + key.ID.Hex(),
+		APIKeyID: key.ID.Hex(),
+		Scopes:   key.Scopes,
+	}
+	if principal.HasScope(auth.ScopeAdmin) {
+		principal.Roles = []string{auth.RoleAdmin}
+	}
+	return principal, nil
+}
+
+// RequirePermission lets through only principals granted permission, and
+// rejects the others with 403, recording the denial in trail. It must run
+// after Authenticate.
+func RequirePermission(trail repositories.AuditRepository, permission auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := auth.FromContext(r.Context())
+			if !principal.Can(permission) {
+				audit.AccessDenied(r, trail, principal, permission)
+				utils.SendJSONResponse(w, http.StatusForbidden, types.APIResponse{
+					Success: false,
+					Error:   "Requires the " + string(permission) + " permission",
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
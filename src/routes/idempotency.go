@@ -0,0 +1,168 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sirupsen/logrus"
+
+	"finance_app/src/auth"
+	"finance_app/src/models"
+	"finance_app/src/repositories"
+	"finance_app/src/utils"
+	"finance_app/src/utils/types"
+)
+
+const (
+	idempotencyHeader       = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBodySize   = 1 << 20
+)
+
+// Idempotency makes a POST safe to retry. The first request with a given
+// Idempotency-Key runs normally and its response is stored; a retry with the
+// same key and body gets that response back without running the handler, and
+// a retry with a different body is rejected with 409. Requests without the
+// header are passed through untouched. Keys are per caller, so it must run
+// after Authenticate.
+func Idempotency(repo repositories.IdempotencyRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotencyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+					Success: false,
+					Error:   "Idempotency-Key must be at most 255 characters",
+				})
+				return
+			}
+
+			// One byte past the limit tells an oversized body from one that
+			// fits exactly, so it is never stored cut short
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBodySize+1))
+			if err != nil {
+				utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+					Success: false,
+					Error:   "Invalid request body",
+				})
+				return
+			}
+			if len(body) > maxIdempotentBodySize {
+				utils.SendJSONResponse(w, http.StatusRequestEntityTooLarge, types.APIResponse{
+					Success: false,
+					Error:   "Request body is too large",
+				})
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// Keys are scoped to the caller so nobody can replay another
+			// caller's response by guessing their key
+			scopedKey := r.Method + " " + r.URL.Path + " " + key
+			if principal, ok := auth.FromContext(r.Context()); ok && principal.Subject != "" {
+				scopedKey = principal.Subject + " " + scopedKey
+			}
+			fingerprint := requestFingerprint(r, body)
+
+			ctx := r.Context()
+			existing, err := repo.Reserve(ctx, scopedKey, fingerprint)
+			if err != nil {
+				logrus.WithContext(r.Context()).Error("Failed to reserve idempotency key: ", err)
+				utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
+					Success: false,
+					Error:   "Failed to process Idempotency-Key",
+				})
+				return
+			}
+
+			if existing != nil {
+				replayIdempotentResponse(w, existing, fingerprint)
+				return
+			}
+
+			// Record the outcome even if the client has gone away meanwhile
+			storeCtx := context.WithoutCancel(ctx)
+
+			// A handler that panics never finished, so its key is freed for
+			// a retry before the panic carries on to Recoverer
+			defer func() {
+				if rec := recover(); rec != nil {
+					if err := repo.Release(storeCtx, scopedKey); err != nil {
+						logrus.WithContext(r.Context()).Error("Failed to release idempotency key: ", err)
+					}
+					panic(rec)
+				}
+			}()
+
+			var captured bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&captured)
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			// Server errors and rate limited requests are not replayed so the
+			// client can retry them
+			if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+				if err := repo.Release(storeCtx, scopedKey); err != nil {
+					logrus.WithContext(r.Context()).Error("Failed to release idempotency key: ", err)
+				}
+				return
+			}
+
+			if err := repo.Complete(storeCtx, scopedKey, status, ww.Header().Get("Content-Type"), captured.Bytes()); err != nil {
+				logrus.WithContext(r.Context()).Error("Failed to store idempotent response: ", err)
+			}
+		})
+	}
+}
+
+// replayIdempotentResponse answers a request whose key has been seen before
+func replayIdempotentResponse(w http.ResponseWriter, existing *models.IdempotencyRecord, fingerprint string) {
+	if existing.Fingerprint != fingerprint {
+		utils.SendJSONResponse(w, http.StatusConflict, types.APIResponse{
+			Success: false,
+			Error:   "Idempotency-Key was already used with a different request",
+		})
+		return
+	}
+
+	if !existing.Completed {
+		utils.SendJSONResponse(w, http.StatusConflict, types.APIResponse{
+			Success: false,
+			Error:   "A request with this Idempotency-Key is still being processed",
+		})
+		return
+	}
+
+	if existing.ContentType != "" {
+		w.Header().Set("Content-Type", existing.ContentType)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(existing.StatusCode)
+	if _, err := w.Write(existing.Body); err != nil {
+		logrus.Error("Failed to write replayed response: ", err)
+	}
+}
+
+// requestFingerprint identifies a request by its method, path and body
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
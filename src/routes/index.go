@@ -9,22 +9,38 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/sirupsen/logrus"
 
+	"finance_app/src/auth"
+	"finance_app/src/config"
 	"finance_app/src/handlers"
+	"finance_app/src/metrics"
+	"finance_app/src/tracing"
 )
 
-func Routes(router chi.Router, h *handlers.AppHandler) {
-	// Add middleware
+func Routes(router chi.Router, h *handlers.AppHandler, cfg config.ServerConfig) {
+	// Add middleware. Tracking comes first so shutdown waits for every
+	// handler, including ones that panic or time out.
+	router.Use(h.Lifecycle.Track)
 	router.Use(middleware.RequestID)
-	router.Use(middleware.Logger)
+	// Invalid proxies were refused when the configuration was validated
+	proxies, _ := config.ParseTrustedProxies(cfg.TrustedProxies)
+	router.Use(RealIP(proxies))
+	router.Use(tracing.Middleware)
+	// Outside Recoverer so requests that panic are counted as 500s
+	router.Use(metrics.Middleware)
+	router.Use(requestLogger)
 	router.Use(middleware.Recoverer)
-	router.Use(middleware.Timeout(60 * time.Second))
+	router.Use(middleware.Timeout(cfg.RequestTimeout))
 
 	// Add CORS middleware
+	allowOrigin := corsOrigin(cfg.CORSOrigins)
 	router.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+			if origin := allowOrigin(w, r); origin != "" {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-API-Key, Idempotency-Key")
+			w.Header().Set("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After")
 
 			if r.Method == "OPTIONS" {
 				w.WriteHeader(http.StatusOK)
@@ -35,31 +51,108 @@ func Routes(router chi.Router, h *handlers.AppHandler) {
 		})
 	})
 
+	idempotent := Idempotency(h.IdempotencyRepository)
+	perAccount := RateLimitAccount(h.RateLimiter, h.RateLimits, router)
+
+	// Probes for orchestrators, outside the versioned API
+	router.Get("/livez", h.HealthService.Live)
+	router.Get("/readyz", h.HealthService.Ready)
+	// Without an admin port, metrics are served with the API to admins only
+	if cfg.MetricsPort == 0 {
+		router.With(Authenticate(h.Auth, h.APIKeyRepository), RequirePermission(h.AuditRepository, auth.PermissionReadMetrics)).
+			Handle("/metrics", metrics.Handler())
+	}
+
 	router.Route("/api/v1", func(r chi.Router) {
+		// The health check stays open for load balancers
 		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 			resp := map[string]interface{}{
 				"message":   "OK",
 				"timestamp": time.Now(),
 				"status":    "healthy",
 			}
+			status := http.StatusOK
+			// Fail first during shutdown so load balancers stop sending traffic
+			if h.Lifecycle.Draining() {
+				resp["message"] = "Shutting down"
+				resp["status"] = "draining"
+				status = http.StatusServiceUnavailable
+			}
 			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
+			w.WriteHeader(status)
 			if err := json.NewEncoder(w).Encode(resp); err != nil {
 				logrus.Error("Failed to encode health response: ", err)
 			}
 		})
 
-		r.Route("/transactions", func(sub chi.Router) {
-			sub.Get("/", h.TransactionService.GetAllTransactions)
-			sub.Post("/", h.TransactionService.CreateTransaction)
-			sub.Get("/{id}", h.TransactionService.GetTransactionByID)
-			sub.Get("/account/{accountId}", h.TransactionService.GetTransactionsByAccountID)
-		})
+		// Everything else needs a bearer token or an API key, and the
+		// permission for the route. Client IPs are rate limited first, so
+		// failed authentication counts, and clients again once known.
+		r.Group(func(r chi.Router) {
+			r.Use(RateLimitIP(h.RateLimiter, h.RateLimits, router))
+			r.Use(Authenticate(h.Auth, h.APIKeyRepository))
+			r.Use(RateLimit(h.RateLimiter, h.RateLimits, router))
+			can := func(permission auth.Permission) func(http.Handler) http.Handler {
+				return RequirePermission(h.AuditRepository, permission)
+			}
+
+			r.Route("/transactions", func(sub chi.Router) {
+				sub.With(can(auth.PermissionReadTransactions)).Get("/", h.TransactionService.GetAllTransactions)
+				sub.With(can(auth.PermissionCreateTransactions), perAccount, idempotent).Post("/", h.TransactionService.CreateTransaction)
+				sub.With(can(auth.PermissionReadTransactions)).Get("/{id}", h.TransactionService.GetTransactionByID)
+				sub.With(can(auth.PermissionReverseTransactions), idempotent).Post("/{id}/reverse", h.TransactionService.ReverseTransaction)
+				sub.With(can(auth.PermissionReadTransactions)).Get("/account/{accountId}", h.TransactionService.GetTransactionsByAccountID)
+			})
 
-		r.Route("/accounts", func(sub chi.Router) {
-			sub.Get("/", h.AccountService.GetAllAccounts)
-			sub.Post("/", h.AccountService.CreateAccount)
-			sub.Get("/{id}", h.AccountService.GetAccountByID)
+			r.Route("/accounts", func(sub chi.Router) {
+				sub.With(can(auth.PermissionReadAccounts)).Get("/", h.AccountService.GetAllAccounts)
+				sub.With(can(auth.PermissionCreateAccounts), idempotent).Post("/", h.AccountService.CreateAccount)
+				sub.With(can(auth.PermissionReadAccounts)).Get("/{id}", h.AccountService.GetAccountByID)
+				sub.With(can(auth.PermissionUpdateAccounts)).Patch("/{id}", h.AccountService.UpdateAccount)
+				sub.With(can(auth.PermissionCloseAccounts)).Delete("/{id}", h.AccountService.CloseAccount)
+				sub.With(can(auth.PermissionFreezeAccounts)).Post("/{id}/freeze", h.AccountService.FreezeAccount)
+				sub.With(can(auth.PermissionUnfreezeAccounts)).Post("/{id}/unfreeze", h.AccountService.UnfreezeAccount)
+				sub.With(can(auth.PermissionCloseAccounts)).Post("/{id}/close", h.AccountService.CloseAccount)
+			})
+
+			r.Route("/ledger", func(sub chi.Router) {
+				sub.Use(can(auth.PermissionCheckLedger))
+				sub.Get("/invariants", h.LedgerService.CheckInvariants)
+			})
+
+			r.Route("/api-keys", func(sub chi.Router) {
+				sub.Use(can(auth.PermissionManageAPIKeys))
+				sub.Post("/", h.APIKeyService.CreateAPIKey)
+				sub.Get("/", h.APIKeyService.GetAllAPIKeys)
+				sub.Delete("/{id}", h.APIKeyService.RevokeAPIKey)
+			})
+
+			r.Route("/audit", func(sub chi.Router) {
+				sub.Use(can(auth.PermissionReadAudit))
+				sub.Get("/events", h.AuditService.GetAuditEvents)
+				sub.Get("/verify", h.AuditService.CheckChain)
+			})
 		})
 	})
 }
+
+// corsOrigin returns the Access-Control-Allow-Origin value for a request: "*"
+// when any origin is allowed, otherwise the request's origin if it is listed
+func corsOrigin(origins []string) func(w http.ResponseWriter, r *http.Request) string {
+	allowed := map[string]bool{}
+	for _, origin := range origins {
+		if origin == "*" {
+			return func(http.ResponseWriter, *http.Request) string { return "*" }
+		}
+		allowed[origin] = true
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) string {
+		// The response depends on the request origin, so caches must key on it
+		w.Header().Add("Vary", "Origin")
+		if origin := r.Header.Get("Origin"); allowed[origin] {
+			return origin
+		}
+		return ""
+	}
+}
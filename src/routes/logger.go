@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+// requestLogger logs every request through logrus with the request context,
+// so the line carries the same request and trace IDs as the handler's logs
+func requestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			logrus.WithContext(r.Context()).WithFields(logrus.Fields{
+				"method":      r.Method,
+				"path":        r.URL.Path,
+				"status":      status,
+				"bytes":       ww.BytesWritten(),
+				"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+				"remote_addr": r.RemoteAddr,
+			}).Info("Request handled")
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
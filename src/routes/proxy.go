@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RealIP sets the remote address of requests relayed by one of proxies to
+// the client address the proxy reports in X-Forwarded-For, or else in
+// X-Real-IP. Anyone can send those headers, so requests from elsewhere keep
+// the address they came from, and clients cannot choose the IP they are rate
+// limited and audited as. Without proxies it does nothing.
+func RealIP(proxies []*net.IPNet) func(http.Handler) http.Handler {
+	trusted := func(ip net.IP) bool {
+		for _, proxy := range proxies {
+			if proxy.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		if len(proxies) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if peer := net.ParseIP(clientIP(r)); peer != nil && trusted(peer) {
+				if ip := forwardedClient(r, trusted); ip != nil {
+					r.RemoteAddr = ip.String()
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClient returns the client address proxies reported for r, or nil
+// when they reported none that parses. Each proxy appends the address it was
+// called from to X-Forwarded-For, so the client is the rightmost address
+// that is not a trusted proxy; the ones left of it could be made up.
+func forwardedClient(r *http.Request, trusted func(net.IP) bool) net.IP {
+	if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		hops := strings.Split(strings.Join(values, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				return nil
+			}
+			if i == 0 || !trusted(ip) {
+				return ip
+			}
+		}
+	}
+	return net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP")))
+}
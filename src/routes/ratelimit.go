@@ -0,0 +1,147 @@
+package routes
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+
+	"finance_app/src/auth"
+	"finance_app/src/metrics"
+	"finance_app/src/ratelimit"
+	"finance_app/src/utils"
+	"finance_app/src/utils/types"
+)
+
+// RateLimit limits each client to the rule of the route it calls, answering
+// 429 once the window is full. Clients are told apart by API key, then user,
+// then IP, so it must run after Authenticate. Routing has not happened yet,
+// so the route is looked up in routes. A nil limiter disables it.
+func RateLimit(limiter ratelimit.Limiter, policy ratelimit.Policy, routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern := routePattern(routes, r)
+			bucket, rule := policy.Rule(r.Method + " " + pattern)
+			if allowRequest(w, r, limiter, bucket+" "+rateLimitClient(r), rule, pattern) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// RateLimitIP limits each client IP to the policy's PerIP rule across every
+// route. It runs before Authenticate, so requests with bad credentials are
+// limited too and cannot be used to guess tokens or keys without end. A nil
+// limiter or an empty rule disables it.
+func RateLimitIP(limiter ratelimit.Limiter, policy ratelimit.Policy, routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil || policy.PerIP.Requests <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern := routePattern(routes, r)
+			if allowRequest(w, r, limiter, "per-ip ip:"+clientIP(r), policy.PerIP, pattern) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// RateLimitAccount limits the transactions on each account to the policy's
+// PerAccount rule. Clients are limited on their own as well, but many of
+// them acting on one account share its window. The handler charges the
+// window with ratelimit.ChargeAccount once it has found an account the
+// caller may use, so nobody can use up the window of an account that is not
+// theirs. A nil limiter or an empty rule disables it.
+func RateLimitAccount(limiter ratelimit.Limiter, policy ratelimit.Policy, routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil || policy.PerAccount.Requests <= 0 {
+			return next
+		}
+
+		limit := ratelimit.AccountLimit(func(w http.ResponseWriter, r *http.Request, accountID string) bool {
+			return allowRequest(w, r, limiter, "per-account account:"+accountID, policy.PerAccount, routePattern(routes, r))
+		})
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(ratelimit.WithAccountLimit(r.Context(), limit)))
+		})
+	}
+}
+
+// allowRequest counts r against key under rule and sets the RateLimit
+// headers. Once the window is full it answers 429 and returns false.
+func allowRequest(w http.ResponseWriter, r *http.Request, limiter ratelimit.Limiter, key string, rule ratelimit.Rule, pattern string) bool {
+	result, err := limiter.Allow(r.Context(), key, rule)
+	if err != nil {
+		// Rather serve unlimited than not at all
+		logrus.WithContext(r.Context()).Error("Failed to check rate limit: ", err)
+		return true
+	}
+
+	reset := ceilSeconds(result.Reset)
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", reset)
+	w.Header().Set("RateLimit-Policy", strconv.Itoa(rule.Requests)+";w="+ceilSeconds(rule.Window))
+
+	if !result.Allowed {
+		metrics.RateLimited.WithLabelValues(r.Method, pattern).Inc()
+		w.Header().Set("Retry-After", reset)
+		utils.SendJSONResponse(w, http.StatusTooManyRequests, types.APIResponse{
+			Success: false,
+			Error:   "Rate limit exceeded, retry in " + reset + "s",
+		})
+		return false
+	}
+	return true
+}
+
+// routePattern returns the chi route pattern r will be routed to, without
+// the trailing slash of sub-router roots
+func routePattern(routes chi.Routes, r *http.Request) string {
+	pattern := routes.Find(chi.NewRouteContext(), r.Method, r.URL.Path)
+	if pattern == "" {
+		return "unmatched"
+	}
+	if len(pattern) > 1 {
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	return pattern
+}
+
+// rateLimitClient names the client a request is counted against
+func rateLimitClient(r *http.Request) string {
+	principal, _ := auth.FromContext(r.Context())
+	switch {
+	case principal.APIKeyID != "":
+		return "key:" + principal.APIKeyID
+	case principal.Subject != "":
+		return "user:" + principal.Subject
+	}
+	return "ip:" + clientIP(r)
+}
+
+// clientIP is the address the request came from
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds formats d in whole seconds, rounded up so clients waiting that
+// long are never early
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
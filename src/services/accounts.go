@@ -1,35 +1,81 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"finance_app/src/audit"
+	"finance_app/src/auth"
 	"finance_app/src/models"
 	"finance_app/src/repositories"
 	"finance_app/src/utils"
 	"finance_app/src/utils/types"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
 )
 
 type CreateAccountRequest struct {
-	Balance float64 `json:"initialBalance"`
-	Name    string  `json:"name"`
-	Email   string  `json:"email"`
+	Balance models.Money `json:"initialBalance"`
+	Name    string       `json:"name"`
+	Email   string       `json:"email"`
 }
 
 type AccountHandler struct {
-	TransactionsRepo repositories.TransactionMongoRepository
-	AccountsRepo     repositories.AccountsMongoRepository
+	TransactionsRepo repositories.TransactionRepository
+	AccountsRepo     repositories.AccountRepository
+	LedgerRepo       repositories.LedgerRepository
+	AuditRepo        repositories.AuditRepository
+	Transactor       repositories.Transactor
 }
 
-// GetAllAccounts handles GET /api/v1/accounts
+// TotalBalance sums the balances of every account
+func (h *AccountHandler) TotalBalance(ctx context.Context) (models.Money, error) {
+	var total models.Money
+	err := h.AccountsRepo.ForEachAccount(ctx, func(account *models.Accounts) error {
+		var err error
+		total, err = total.CheckedAdd(account.Balance)
+		return err
+	})
+	return total, err
+}
+
+// GetAllAccounts handles GET /api/v1/accounts. Admins and API keys get every
+// account, everyone else the accounts they own.
 func (h *AccountHandler) GetAllAccounts(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r, h.AuditRepo, auth.PermissionReadAccounts) {
+		return
+	}
+
 	ctx := r.Context()
 
-	accounts, err := h.AccountsRepo.GetAllAccounts(ctx)
+	page, err := parsePageRequest(r)
 	if err != nil {
-		logrus.Error("Failed to get transactions: ", err)
+		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var accounts []models.Accounts
+	var next *repositories.PageCursor
+	if principal, _ := auth.FromContext(ctx); principal.AllAccounts() {
+		accounts, next, err = h.AccountsRepo.GetAllAccounts(ctx, page)
+	} else {
+		accounts, next, err = h.AccountsRepo.GetByOwner(ctx, principal.Subject, page)
+	}
+	if errors.Is(err, repositories.ErrInvalidCursor) {
+		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		logrus.WithContext(r.Context()).Error("Failed to get transactions: ", err)
 		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
 			Success: false,
 			Error:   "Failed to fetch transactions",
@@ -38,14 +84,19 @@ func (h *AccountHandler) GetAllAccounts(w http.ResponseWriter, r *http.Request)
 	}
 
 	utils.SendJSONResponse(w, http.StatusOK, types.APIResponse{
-		Success: true,
-		Data:    accounts,
-		Message: "Accounts fetched successfully",
+		Success:    true,
+		Data:       accounts,
+		Message:    "Accounts fetched successfully",
+		NextCursor: encodeNextCursor(next),
 	})
 }
 
 // CreateAccount handles POST /api/v1/accounts
 func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r, h.AuditRepo, auth.PermissionCreateAccounts) {
+		return
+	}
+
 	ctx := r.Context()
 
 	// Parse request body
@@ -76,19 +127,62 @@ func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Balance.IsNegative() {
+		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "initialBalance cannot be negative",
+		})
+		return
+	}
+
+	// Customers fund their accounts with deposits, so only staff may open one
+	// that already holds money
+	principal, _ := auth.FromContext(ctx)
+	if !req.Balance.IsZero() && !principal.AllAccounts() {
+		utils.SendJSONResponse(w, http.StatusForbidden, types.APIResponse{
+			Success: false,
+			Error:   "Only staff may open an account with an initial balance",
+		})
+		return
+	}
+
 	account := models.Accounts{
 		Balance: req.Balance,
 		Name:    req.Name,
 		Email:   req.Email,
+		Owner:   principal.Subject,
 	}
 
-	err := h.AccountsRepo.CreateAccount(ctx, &account)
+	// The initial balance is money coming in, so it is posted against cash in
+	// in the same transaction that creates the account and records it
+	err := h.Transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.AccountsRepo.CreateAccount(ctx, &account); err != nil {
+			return err
+		}
+
+		if !account.Balance.IsZero() {
+			entry := models.NewTransferEntry("opening balance", models.LedgerCashIn, account.ID.Hex(), account.Balance)
+			if err := h.LedgerRepo.Post(ctx, entry); err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(ctx, h.AuditRepo, audit.NewEvent(r, models.AuditAccountCreated, models.BalanceSnapshot{
+			AccountId: account.ID.Hex(),
+			After:     account.Balance,
+		}))
+	})
 
 	if err != nil {
-		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+		status := accountChangeStatus(err)
+		message := err.Error()
+		if status == http.StatusInternalServerError {
+			logrus.WithContext(r.Context()).Error("Failed to create account: ", err)
+			message = "Failed to create account"
+		}
+		utils.SendJSONResponse(w, status, types.APIResponse{
 			Success: false,
-			Error:   err.Error(),
-			Data:    account,
+			Error:   message,
 		})
 		return
 	}
@@ -102,6 +196,10 @@ func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 
 // GetAccountByID handles GET /api/v1/accounts/{id}
 func (h *AccountHandler) GetAccountByID(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r, h.AuditRepo, auth.PermissionReadAccounts) {
+		return
+	}
+
 	ctx := r.Context()
 	id := chi.URLParam(r, "id")
 
@@ -113,10 +211,16 @@ func (h *AccountHandler) GetAccountByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	account, err := h.AccountsRepo.FindOne(ctx, id)
-
+	account, err := findAccessibleAccount(ctx, h.AccountsRepo, id)
+	if errors.Is(err, repositories.ErrAccountNotFound) {
+		utils.SendJSONResponse(w, http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   "Account not found",
+		})
+		return
+	}
 	if err != nil {
-		logrus.Error("Failed to get account: ", err)
+		logrus.WithContext(r.Context()).Error("Failed to get account: ", err)
 		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
 			Success: false,
 			Error:   "Failed to fetch account",
@@ -130,3 +234,128 @@ func (h *AccountHandler) GetAccountByID(w http.ResponseWriter, r *http.Request)
 		Message: "Account fetched successfully",
 	})
 }
+
+// UpdateAccount handles PATCH /api/v1/accounts/{id}
+func (h *AccountHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r, h.AuditRepo, auth.PermissionUpdateAccounts) {
+		return
+	}
+
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	var req models.AccountUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	account, err := findAccessibleAccount(ctx, h.AccountsRepo, id)
+	if err == nil {
+		err = h.Transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			if account, err = h.AccountsRepo.UpdateDetails(ctx, id, req); err != nil {
+				return err
+			}
+			return recordAudit(ctx, h.AuditRepo, audit.NewEvent(r, models.AuditAccountUpdated, balanceSnapshot(account, models.Money{})))
+		})
+	}
+	if err != nil {
+		status := accountChangeStatus(err)
+		message := err.Error()
+		if status == http.StatusInternalServerError {
+			logrus.WithContext(r.Context()).Error("Failed to update account: ", err)
+			message = "Failed to update account"
+		}
+		utils.SendJSONResponse(w, status, types.APIResponse{
+			Success: false,
+			Error:   message,
+		})
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    account,
+		Message: "Account updated successfully",
+	})
+}
+
+// FreezeAccount handles POST /api/v1/accounts/{id}/freeze
+func (h *AccountHandler) FreezeAccount(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, auth.PermissionFreezeAccounts, models.AccountFrozen, models.AuditAccountFrozen, "Account frozen successfully")
+}
+
+// UnfreezeAccount handles POST /api/v1/accounts/{id}/unfreeze
+func (h *AccountHandler) UnfreezeAccount(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, auth.PermissionUnfreezeAccounts, models.AccountActive, models.AuditAccountUnfrozen, "Account unfrozen successfully")
+}
+
+// CloseAccount handles POST /api/v1/accounts/{id}/close and
+// DELETE /api/v1/accounts/{id}. Closed accounts are kept with their history
+// instead of being removed.
+func (h *AccountHandler) CloseAccount(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, auth.PermissionCloseAccounts, models.AccountClosed, models.AuditAccountClosed, "Account closed successfully")
+}
+
+func (h *AccountHandler) changeStatus(w http.ResponseWriter, r *http.Request, permission auth.Permission, status models.AccountStatus, action, message string) {
+	if !authorize(w, r, h.AuditRepo, permission) {
+		return
+	}
+
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	account, err := findAccessibleAccount(ctx, h.AccountsRepo, id)
+	if err == nil {
+		err = h.Transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			if account, err = h.AccountsRepo.UpdateStatus(ctx, id, status); err != nil {
+				return err
+			}
+			return recordAudit(ctx, h.AuditRepo, audit.NewEvent(r, action, balanceSnapshot(account, models.Money{})))
+		})
+	}
+	if err != nil {
+		code := accountChangeStatus(err)
+		reason := err.Error()
+		if code == http.StatusInternalServerError {
+			logrus.WithContext(r.Context()).Error("Failed to change account status: ", err)
+			reason = "Failed to change account status"
+		}
+		utils.SendJSONResponse(w, code, types.APIResponse{
+			Success: false,
+			Error:   reason,
+		})
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    account,
+		Message: message,
+	})
+}
+
+// accountChangeStatus maps a failed account change to an HTTP status: 404 for
+// unknown accounts, 409 when the lifecycle state forbids it, 400 for invalid
+// input and 500 otherwise
+func accountChangeStatus(err error) int {
+	var statusErr *repositories.AccountStatusError
+	switch {
+	case errors.Is(err, repositories.ErrAccountNotFound):
+		return http.StatusNotFound
+	case errors.As(err, &statusErr), errors.Is(err, repositories.ErrBalanceNotZero):
+		return http.StatusConflict
+	case errors.Is(err, repositories.ErrDuplicateEmail),
+		strings.Contains(err.Error(), "account ID"),
+		strings.Contains(err.Error(), "cannot be empty"),
+		strings.Contains(err.Error(), "nothing to update"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
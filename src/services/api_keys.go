@@ -0,0 +1,173 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"finance_app/src/auth"
+	"finance_app/src/models"
+	"finance_app/src/repositories"
+	"finance_app/src/utils"
+	"finance_app/src/utils/types"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultAPIKeyLifetime is how long a key lives when no expiry is given
+const defaultAPIKeyLifetime = 90 * 24 * time.Hour
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresAt defaults to 90 days from now
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+type APIKeyHandler struct {
+	APIKeysRepo repositories.APIKeyRepository
+}
+
+// CreateAPIKey handles POST /api/v1/api-keys. The key is only in this
+// response; afterwards only its prefix can be seen.
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	key, err := newAPIKey(req)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	plaintext, err := auth.GenerateAPIKey()
+	if err != nil {
+		logrus.WithContext(r.Context()).Error("Failed to generate API key: ", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   "Failed to create API key",
+		})
+		return
+	}
+
+	principal, _ := auth.FromContext(ctx)
+	key.Prefix = plaintext[:auth.APIKeyPrefixLength]
+	key.Hash = auth.HashAPIKey(plaintext)
+	key.CreatedBy = principal.Subject
+
+	if err := h.APIKeysRepo.Create(ctx, key); err != nil {
+		logrus.WithContext(r.Context()).Error("Failed to create API key: ", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   "Failed to create API key",
+		})
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, types.APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"apiKey": key,
+			"key":    plaintext,
+		},
+		Message: "API key created; store the key now, it is not shown again",
+	})
+}
+
+// newAPIKey validates req and returns the key it describes
+func newAPIKey(req CreateAPIKeyRequest) (*models.APIKey, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	var scopes []string
+	seen := map[string]bool{}
+	for _, scope := range req.Scopes {
+		if !auth.ValidScope(scope) {
+			return nil, errors.New("unknown scope: " + scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	expiresAt := time.Now().Add(defaultAPIKeyLifetime)
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			return nil, errors.New("expiresAt must be in the future")
+		}
+		expiresAt = *req.ExpiresAt
+	}
+
+	return &models.APIKey{
+		Name:      name,
+		Scopes:    scopes,
+		ExpiresAt: primitive.NewDateTimeFromTime(expiresAt),
+	}, nil
+}
+
+// GetAllAPIKeys handles GET /api/v1/api-keys
+func (h *APIKeyHandler) GetAllAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.APIKeysRepo.List(r.Context())
+	if err != nil {
+		logrus.WithContext(r.Context()).Error("Failed to get API keys: ", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   "Failed to fetch API keys",
+		})
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    keys,
+		Message: "API keys fetched successfully",
+	})
+}
+
+// RevokeAPIKey handles DELETE /api/v1/api-keys/{id}. Revoked keys are kept so
+// the list still shows who had access.
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	key, err := h.APIKeysRepo.Revoke(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, repositories.ErrAPIKeyNotFound):
+			status = http.StatusNotFound
+		case strings.Contains(err.Error(), "invalid API key ID"):
+			status = http.StatusBadRequest
+		default:
+			logrus.WithContext(r.Context()).Error("Failed to revoke API key: ", err)
+		}
+		utils.SendJSONResponse(w, status, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    key,
+		Message: "API key revoked successfully",
+	})
+}
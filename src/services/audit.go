@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"finance_app/src/models"
+	"finance_app/src/repositories"
+	"finance_app/src/utils"
+	"finance_app/src/utils/types"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AuditChainReport is the outcome of checking the audit trail's hash chain
+type AuditChainReport struct {
+	Intact bool `json:"intact"`
+	Events int  `json:"events"`
+	// BrokenAt is the sequence of the first event that does not follow from
+	// the one before it, and Reason says why
+	BrokenAt int64  `json:"brokenAt,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	// LastSequence and LastHash identify the newest event. The chain cannot
+	// tell that events were cut off its end, so keep them somewhere else to
+	// compare against later.
+	LastSequence int64  `json:"lastSequence"`
+	LastHash     string `json:"lastHash"`
+}
+
+type AuditHandler struct {
+	AuditRepo repositories.AuditRepository
+}
+
+// recordAudit appends event to the audit trail. Call it with the ctx of the
+// transaction making the change it describes, so that a failure rolls the
+// change back and no change commits without its event.
+func recordAudit(ctx context.Context, trail repositories.AuditRepository, event *models.AuditEvent) error {
+	if err := trail.Append(ctx, event); err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return nil
+}
+
+// ChainAuditEvents chains the events waiting in trail's outbox every interval
+// until ctx is done
+func ChainAuditEvents(ctx context.Context, trail repositories.AuditRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := trail.ChainPending(ctx); err != nil && ctx.Err() == nil {
+			logrus.Error("Failed to chain queued audit events: ", err)
+		}
+	}
+}
+
+// chainPending chains queued events before the trail is read, so readers see
+// every change that committed. Failing that, they see the trail as it is.
+func (h *AuditHandler) chainPending(ctx context.Context) {
+	if _, err := h.AuditRepo.ChainPending(ctx); err != nil {
+		logrus.WithContext(ctx).Warn("Failed to chain queued audit events: ", err)
+	}
+}
+
+// balanceSnapshot records the balance of account after a change that moved
+// delta into it; a zero delta for changes that leave the balance alone
+func balanceSnapshot(account *models.Accounts, delta models.Money) models.BalanceSnapshot {
+	before := account.Balance.Sub(delta)
+	return models.BalanceSnapshot{AccountId: account.ID.Hex(), Before: &before, After: account.Balance}
+}
+
+// GetAuditEvents handles GET /api/v1/audit/events
+func (h *AuditHandler) GetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	query, err := parseAuditQuery(r)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	h.chainPending(r.Context())
+	events, next, err := h.AuditRepo.Find(r.Context(), query)
+	if errors.Is(err, repositories.ErrInvalidCursor) {
+		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		logrus.WithContext(r.Context()).Error("Failed to get audit events: ", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   "Failed to fetch audit events",
+		})
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, types.APIResponse{
+		Success:    true,
+		Data:       events,
+		Message:    "Audit events fetched successfully",
+		NextCursor: encodeNextCursor(next),
+	})
+}
+
+// CheckChain handles GET /api/v1/audit/verify
+func (h *AuditHandler) CheckChain(w http.ResponseWriter, r *http.Request) {
+	report, err := h.VerifyChain(r.Context())
+	if err != nil {
+		logrus.WithContext(r.Context()).Error("Failed to verify the audit trail: ", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   "Failed to verify the audit trail",
+		})
+		return
+	}
+
+	if !report.Intact {
+		logrus.WithContext(r.Context()).WithField("report", report).Error("Audit trail has been tampered with")
+		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Data:    report,
+			Error:   "Audit trail has been tampered with",
+		})
+		return
+	}
+
+	utils.SendJSONResponse(w, http.StatusOK, types.APIResponse{
+		Success: true,
+		Data:    report,
+		Message: "Audit trail is intact",
+	})
+}
+
+// VerifyChain walks the audit trail in sequence order and checks that the
+// sequence has no gaps, that each event points at the hash of the one before
+// it and that each hash matches the event's contents. Any edited, removed or
+// reordered event fails one of them.
+func (h *AuditHandler) VerifyChain(ctx context.Context) (*AuditChainReport, error) {
+	report := &AuditChainReport{}
+	h.chainPending(ctx)
+
+	err := h.AuditRepo.ForEachEvent(ctx, func(event *models.AuditEvent) error {
+		report.Events++
+
+		if report.Reason == "" {
+			switch {
+			case event.Sequence != report.LastSequence+1:
+				report.Reason = fmt.Sprintf("expected sequence %d", report.LastSequence+1)
+			case event.PrevHash != report.LastHash:
+				report.Reason = "previous hash does not match the previous event"
+			case event.ComputeHash() != event.Hash:
+				report.Reason = "hash does not match the event"
+			}
+			if report.Reason != "" {
+				report.BrokenAt = event.Sequence
+			}
+		}
+
+		report.LastSequence = event.Sequence
+		report.LastHash = event.Hash
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the audit trail: %w", err)
+	}
+
+	report.Intact = report.Reason == ""
+	return report, nil
+}
+
+// parseAuditQuery reads the filters of the audit event list:
+//
+//	actor      subject of the caller that made the change
+//	accountId  an account the change touched
+//	from, to   RFC 3339 timestamps or dates, both inclusive
+//
+// plus limit and cursor as read by parsePageRequest
+func parseAuditQuery(r *http.Request) (repositories.AuditQuery, error) {
+	query := repositories.AuditQuery{
+		Actor:     r.URL.Query().Get("actor"),
+		AccountID: r.URL.Query().Get("accountId"),
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		return query, err
+	}
+	query.Page = page
+
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		from, err := parseQueryTime("from", raw, false)
+		if err != nil {
+			return query, err
+		}
+		query.From = &from
+	}
+
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		to, err := parseQueryTime("to", raw, true)
+		if err != nil {
+			return query, err
+		}
+		query.To = &to
+	}
+
+	return query, query.Validate()
+}
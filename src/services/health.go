@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// HealthCheck is a dependency the readiness probe checks
+type HealthCheck struct {
+	Name string
+	// Required checks make the app unready when they fail; the others only
+	// show up in the report
+	Required bool
+	// Check returns an error when the dependency cannot serve requests
+	Check func(ctx context.Context) error
+}
+
+// DependencyStatus is the outcome of one HealthCheck
+type DependencyStatus struct {
+	Status    string  `json:"status"`
+	Required  bool    `json:"required"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// HealthReport is the body of the liveness and readiness probes
+type HealthReport struct {
+	Status       string                      `json:"status"`
+	Version      string                      `json:"version"`
+	Timestamp    time.Time                   `json:"timestamp"`
+	Dependencies map[string]DependencyStatus `json:"dependencies,omitempty"`
+}
+
+// Probe outcomes
+const (
+	statusUp       = "up"
+	statusDown     = "down"
+	statusReady    = "ready"
+	statusNotReady = "not_ready"
+	statusDraining = "draining"
+)
+
+type HealthHandler struct {
+	Checks []HealthCheck
+	// Timeout bounds each check
+	Timeout time.Duration
+	// Version is the build version reported by both probes
+	Version string
+	// Draining reports whether the app is shutting down; it may be nil
+	Draining func() bool
+}
+
+// Live handles GET /livez. It only shows that the process can serve HTTP, so
+// a failing dependency never gets the process restarted.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	h.send(w, http.StatusOK, HealthReport{
+		Status:    "alive",
+		Version:   h.Version,
+		Timestamp: time.Now(),
+	})
+}
+
+// Ready handles GET /readyz. It runs every check concurrently and returns 503
+// when a required one fails or the app is draining.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	report := HealthReport{
+		Status:       statusReady,
+		Version:      h.Version,
+		Timestamp:    time.Now(),
+		Dependencies: h.runChecks(r.Context()),
+	}
+
+	for name, dependency := range report.Dependencies {
+		if dependency.Required && dependency.Status != statusUp {
+			report.Status = statusNotReady
+			logrus.WithContext(r.Context()).WithField("dependency", name).Warn("Readiness check failed: ", dependency.Error)
+		}
+	}
+
+	if h.Draining != nil && h.Draining() {
+		report.Status = statusDraining
+	}
+
+	status := http.StatusOK
+	if report.Status != statusReady {
+		status = http.StatusServiceUnavailable
+	}
+	h.send(w, status, report)
+}
+
+func (h *HealthHandler) runChecks(ctx context.Context) map[string]DependencyStatus {
+	results := make(map[string]DependencyStatus, len(h.Checks))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.Checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, h.Timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(checkCtx)
+			result := DependencyStatus{
+				Status:    statusUp,
+				Required:  check.Required,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = statusDown
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[check.Name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+func (h *HealthHandler) send(w http.ResponseWriter, status int, report HealthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logrus.Error("Failed to encode health response: ", err)
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"finance_app/src/audit"
+	"finance_app/src/auth"
+	"finance_app/src/models"
+	"finance_app/src/repositories"
+	"finance_app/src/utils"
+	"finance_app/src/utils/types"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// authorize reports whether the caller was granted permission. When it was
+// not, the denial is recorded in trail and answered with 403. Routes check
+// the same permissions; this keeps handlers safe when they are mounted
+// elsewhere.
+func authorize(w http.ResponseWriter, r *http.Request, trail repositories.AuditRepository, permission auth.Permission) bool {
+	principal, _ := auth.FromContext(r.Context())
+	if principal.Can(permission) {
+		return true
+	}
+	audit.AccessDenied(r, trail, principal, permission)
+	utils.SendJSONResponse(w, http.StatusForbidden, types.APIResponse{
+		Success: false,
+		Error:   "Requires the " + string(permission) + " permission",
+	})
+	return false
+}
+
+// canAccess reports whether the caller may see and move money on account.
+// Staff and API keys may use every account, everyone else only the ones
+// they own.
+func canAccess(ctx context.Context, account *models.Accounts) bool {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	return principal.AllAccounts() || (principal.Subject != "" && account.Owner == principal.Subject)
+}
+
+// findAccessibleAccount looks up an account the caller may use. Accounts of
+// other callers are reported as not found, so their IDs cannot be probed.
+func findAccessibleAccount(ctx context.Context, accounts repositories.AccountRepository, id string) (*models.Accounts, error) {
+	account, err := accounts.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !canAccess(ctx, account) {
+		return nil, repositories.ErrAccountNotFound
+	}
+	return account, nil
+}
+
+// accountScope returns the IDs of the accounts the caller owns, and false
+// when the caller may see every account
+func accountScope(ctx context.Context, accounts repositories.AccountRepository) ([]primitive.ObjectID, bool, error) {
+	principal, _ := auth.FromContext(ctx)
+	if principal.AllAccounts() {
+		return nil, false, nil
+	}
+	if principal.Subject == "" {
+		return nil, true, nil
+	}
+
+	var ids []primitive.ObjectID
+	page := repositories.PageRequest{Limit: repositories.MaxPageSize}
+	for {
+		owned, next, err := accounts.GetByOwner(ctx, principal.Subject, page)
+		if err != nil {
+			return nil, true, err
+		}
+		for _, account := range owned {
+			ids = append(ids, account.ID)
+		}
+		if next == nil {
+			return ids, true, nil
+		}
+		page.After = next
+	}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"finance_app/src/repositories"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// parsePageRequest reads the limit and cursor query parameters of a list
+// endpoint. Both are optional; a missing limit means the default page size.
+func parsePageRequest(r *http.Request) (repositories.PageRequest, error) {
+	var page repositories.PageRequest
+	query := r.URL.Query()
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > repositories.MaxPageSize {
+			return page, fmt.Errorf("limit must be an integer between 1 and %d", repositories.MaxPageSize)
+		}
+		page.Limit = limit
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		cursor, err := repositories.DecodePageCursor(raw)
+		if err != nil {
+			return page, err
+		}
+		page.After = cursor
+	}
+
+	return page, nil
+}
+
+// encodeNextCursor returns the token for the next page, or "" on the last one
+func encodeNextCursor(next *repositories.PageCursor) string {
+	if next == nil {
+		return ""
+	}
+	return next.Encode()
+}
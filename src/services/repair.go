@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"finance_app/src/models"
+	"finance_app/src/repositories"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RepairReport summarises a running-balance repair
+type RepairReport struct {
+	Accounts     int `json:"accounts"`
+	Transactions int `json:"transactions"`
+	Updated      int `json:"updated"`
+}
+
+type BalanceRepairer struct {
+	TransactionsRepo repositories.TransactionRepository
+	AccountsRepo     repositories.AccountRepository
+	Transactor       repositories.Transactor
+}
+
+// RepairRunningBalances recomputes the Balance stored on every transaction.
+// Each account is replayed oldest first, starting from the opening balance
+// implied by its current balance minus the sum of its history, so the last
+// transaction always ends on the account's current balance.
+func (h *BalanceRepairer) RepairRunningBalances(ctx context.Context) (RepairReport, error) {
+	var report RepairReport
+
+	err := h.AccountsRepo.ForEachAccount(ctx, func(account *models.Accounts) error {
+		accountId := account.ID.Hex()
+
+		// Read the balance and the history from one snapshot
+		var transactions, updated int
+		err := h.Transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			transactions, updated = 0, 0
+
+			current, err := h.AccountsRepo.FindOne(ctx, accountId)
+			if err != nil {
+				return err
+			}
+
+			var total models.Money
+			err = h.TransactionsRepo.ForEachByAccountID(ctx, accountId, func(t *models.Transaction) error {
+				var err error
+				total, err = total.CheckedAdd(t.SignedAmount())
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			running := current.Balance.Sub(total)
+			return h.TransactionsRepo.ForEachByAccountID(ctx, accountId, func(t *models.Transaction) error {
+				transactions++
+				running = running.Add(t.SignedAmount())
+				if t.Balance == running {
+					return nil
+				}
+				updated++
+				return h.TransactionsRepo.SetBalance(ctx, t.ID, running)
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("failed to repair account %s: %w", accountId, err)
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"accountId":    accountId,
+			"transactions": transactions,
+			"updated":      updated,
+		}).Info("Repaired running balances")
+
+		report.Accounts++
+		report.Transactions += transactions
+		report.Updated += updated
+		return nil
+	})
+
+	return report, err
+}
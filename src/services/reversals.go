@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"finance_app/src/audit"
+	"finance_app/src/auth"
+	"finance_app/src/cache"
+	"finance_app/src/metrics"
+	"finance_app/src/models"
+	"finance_app/src/repositories"
+	"finance_app/src/tracing"
+	"finance_app/src/utils"
+	"finance_app/src/utils/types"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ReverseTransactionRequest is the optional body of a reversal
+type ReverseTransactionRequest struct {
+	// Amount gives back part of the transaction. Left out, whatever has not
+	// been reversed yet is given back.
+	Amount *models.Money `json:"amount,omitempty"`
+}
+
+// ReverseTransaction handles POST /api/v1/transactions/{id}/reverse. It gives
+// back all or part of a deposit, withdrawal or transfer with a REVERSAL
+// transaction on each account the original touched, and adds the amount to
+// what has been reversed of the original. Reversals add up to at most the
+// original amount, and reversals themselves cannot be reversed.
+func (h *TransactionHandler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r, h.AuditRepo, auth.PermissionReverseTransactions) {
+		return
+	}
+
+	// Status and balance checks must see the stored accounts
+	r = r.WithContext(cache.Bypass(r.Context()))
+	ctx := r.Context()
+
+	ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	w = ww
+	defer func() {
+		metrics.Transactions.WithLabelValues(string(models.Reversal), metrics.Outcome(ww.Status())).Inc()
+	}()
+
+	// The body is optional, so an empty one is a full reversal
+	var req ReverseTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	if req.Amount != nil && !req.Amount.IsPositive() {
+		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "amount must be greater than 0",
+		})
+		return
+	}
+
+	transactionID := chi.URLParam(r, "id")
+	trace.SpanFromContext(ctx).SetAttributes(
+		tracing.AttrTransactionID.String(transactionID),
+		tracing.AttrTransactionType.String(string(models.Reversal)),
+	)
+
+	original, err := h.TransactionsRepo.GetByID(ctx, transactionID)
+	if err == nil {
+		_, err = findAccessibleAccount(ctx, h.AccountsRepo, original.AccountId.Hex())
+	}
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			utils.SendJSONResponse(w, http.StatusNotFound, types.APIResponse{
+				Success: false,
+				Error:   "Transaction not found",
+			})
+		case strings.Contains(err.Error(), "invalid transaction ID"):
+			utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+				Success: false,
+				Error:   "Invalid transaction ID format",
+			})
+		default:
+			logrus.WithContext(r.Context()).Error("Failed to get transaction: ", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
+				Success: false,
+				Error:   "Failed to fetch transaction",
+			})
+		}
+		return
+	}
+
+	if original.TransactionType == models.Reversal {
+		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "a reversal cannot be reversed",
+		})
+		return
+	}
+
+	amount := original.ReversibleAmount()
+	if req.Amount != nil {
+		amount = *req.Amount
+	}
+	if reversible := original.ReversibleAmount(); !reversible.IsPositive() || reversible.LessThan(amount) {
+		utils.SendJSONResponse(w, http.StatusConflict, types.APIResponse{
+			Success: false,
+			Error:   (&repositories.ReversalError{TransactionID: transactionID, Requested: amount, Reversible: reversible}).Error(),
+		})
+		return
+	}
+
+	// A transfer is reversed on both sides, the requested side first
+	legs := []*models.Transaction{original}
+	if original.TransactionType == models.Transfer {
+		linkedId := ""
+		if original.LinkedTransactionId != nil {
+			linkedId = original.LinkedTransactionId.Hex()
+		}
+		linked, err := h.TransactionsRepo.GetByID(ctx, linkedId)
+		if err != nil {
+			logrus.WithContext(r.Context()).Error("Failed to get the other side of the transfer: ", err)
+			utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
+				Success: false,
+				Error:   "Failed to fetch transaction",
+			})
+			return
+		}
+		legs = append(legs, linked)
+	}
+
+	// Pre-assign IDs so the two sides of a transfer reversal can point at
+	// each other
+	reversalIds := make([]primitive.ObjectID, len(legs))
+	for i := range legs {
+		reversalIds[i] = primitive.NewObjectID()
+	}
+
+	// Every balance change, reversal record, reversed total, the journal entry
+	// and the audit event commit or roll back together
+	var reversals []*models.Transaction
+	var accounts []*models.Accounts
+	var reversed *models.Transaction
+	err = h.Transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		reversals, accounts = nil, nil
+
+		for i, leg := range legs {
+			updated, err := h.TransactionsRepo.AddReversal(ctx, leg.ID, amount)
+			if err != nil {
+				return err
+			}
+			if i == 0 {
+				reversed = updated
+			}
+
+			// Money goes back the way it came
+			accountId := leg.AccountId.Hex()
+			var account *models.Accounts
+			direction := models.TransferIn
+			if leg.SignedAmount().IsPositive() {
+				direction = models.TransferOut
+				account, err = h.AccountsRepo.Debit(ctx, accountId, amount)
+			} else {
+				account, err = h.AccountsRepo.Credit(ctx, accountId, amount)
+			}
+			if err != nil {
+				return err
+			}
+
+			reversal := &models.Transaction{
+				ID:                    reversalIds[i],
+				TransactionType:       models.Reversal,
+				Amount:                amount,
+				Balance:               account.Balance,
+				AccountId:             leg.AccountId,
+				Direction:             direction,
+				CounterpartyAccountId: leg.CounterpartyAccountId,
+				ReversalOf:            &legs[i].ID,
+			}
+			if len(legs) == 2 {
+				reversal.LinkedTransactionId = &reversalIds[1-i]
+			}
+
+			if err := h.TransactionsRepo.Create(ctx, reversal); err != nil {
+				return err
+			}
+
+			reversals = append(reversals, reversal)
+			accounts = append(accounts, account)
+		}
+
+		entry := reversalEntry(legs, amount)
+		entry.TransactionId = &reversals[0].ID
+
+		if err := h.LedgerRepo.Post(ctx, entry); err != nil {
+			return err
+		}
+
+		snapshots := make([]models.BalanceSnapshot, len(reversals))
+		for i, reversal := range reversals {
+			snapshots[i] = balanceSnapshot(accounts[i], reversal.SignedAmount())
+		}
+		event := audit.NewEvent(r, models.AuditTransactionReversed, snapshots...)
+		event.TransactionId = reversals[0].ID.Hex()
+		return recordAudit(ctx, h.AuditRepo, event)
+	})
+
+	if err != nil {
+		status := balanceChangeStatus(err)
+		var reversalErr *repositories.ReversalError
+		if errors.As(err, &reversalErr) {
+			status = http.StatusConflict
+		}
+
+		logrus.WithContext(r.Context()).Error("Failed to reverse transaction: ", err)
+		utils.SendJSONResponse(w, status, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := h.addReversals(ctx, reversed); err != nil {
+		logrus.WithContext(r.Context()).Error("Failed to fetch reversals: ", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   "Failed to fetch the reversed transaction",
+		})
+		return
+	}
+
+	data := map[string]interface{}{
+		"reversal":    reversals[0],
+		"transaction": reversed,
+		"account":     accounts[0],
+	}
+	if len(accounts) == 2 {
+		data["counterpartyAccount"] = accounts[1]
+	}
+
+	utils.SendJSONResponse(w, http.StatusCreated, types.APIResponse{
+		Success: true,
+		Data:    data,
+		Message: "Transaction reversed successfully",
+	})
+}
+
+// reversalEntry posts amount back against the ledger accounts the original
+// transaction moved it between
+func reversalEntry(legs []*models.Transaction, amount models.Money) *models.JournalEntry {
+	original := legs[0]
+	accountId := original.AccountId.Hex()
+
+	switch {
+	case original.TransactionType == models.Deposit:
+		return models.NewTransferEntry("deposit reversal", accountId, models.LedgerCashIn, amount)
+	case original.TransactionType == models.Withdraw:
+		return models.NewTransferEntry("withdrawal reversal", models.LedgerCashOut, accountId, amount)
+	case original.Direction == models.TransferOut:
+		return models.NewTransferEntry("transfer reversal", original.CounterpartyAccountId.Hex(), accountId, amount)
+	default:
+		return models.NewTransferEntry("transfer reversal", accountId, original.CounterpartyAccountId.Hex(), amount)
+	}
+}
+
+// addReversals fills in the reversals of transaction, which together with
+// ReversalOf on each of them is the transaction's reversal chain
+func (h *TransactionHandler) addReversals(ctx context.Context, transaction *models.Transaction) error {
+	if transaction.TransactionType == models.Reversal {
+		return nil
+	}
+
+	reversals, err := h.TransactionsRepo.GetReversals(ctx, transaction.ID)
+	if err != nil {
+		return err
+	}
+	transaction.Reversals = reversals
+	return nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"finance_app/src/models"
+	"finance_app/src/repositories"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// dateLayout is the date-only form accepted for from and to
+const dateLayout = "2006-01-02"
+
+// parseTransactionQuery reads the filter, sort and page query parameters of
+// the transaction list endpoints:
+//
+//	type       DEPOSIT, WITHDRAW or TRANSFER
+//	from, to   RFC 3339 timestamps or dates, both inclusive
+//	minAmount  lowest amount, inclusive
+//	maxAmount  highest amount, inclusive
+//	sort       createdAt (default) or amount
+//	order      desc (default) or asc
+//
+// plus limit and cursor as read by parsePageRequest
+func parseTransactionQuery(r *http.Request) (repositories.TransactionQuery, error) {
+	var query repositories.TransactionQuery
+	params := r.URL.Query()
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		return query, err
+	}
+	query.Page = page
+
+	if raw := params.Get("type"); raw != "" {
+		query.Type = models.TransactionType(strings.ToUpper(raw))
+	}
+
+	if raw := params.Get("from"); raw != "" {
+		from, err := parseQueryTime("from", raw, false)
+		if err != nil {
+			return query, err
+		}
+		query.From = &from
+	}
+
+	if raw := params.Get("to"); raw != "" {
+		to, err := parseQueryTime("to", raw, true)
+		if err != nil {
+			return query, err
+		}
+		query.To = &to
+	}
+
+	if raw := params.Get("minAmount"); raw != "" {
+		amount, err := models.ParseMoney(raw)
+		if err != nil {
+			return query, fmt.Errorf("invalid minAmount: %w", err)
+		}
+		query.MinAmount = &amount
+	}
+
+	if raw := params.Get("maxAmount"); raw != "" {
+		amount, err := models.ParseMoney(raw)
+		if err != nil {
+			return query, fmt.Errorf("invalid maxAmount: %w", err)
+		}
+		query.MaxAmount = &amount
+	}
+
+	if raw := params.Get("sort"); raw != "" {
+		query.SortBy = repositories.SortField(raw)
+	}
+
+	switch order := params.Get("order"); order {
+	case "", "desc":
+	case "asc":
+		query.Ascending = true
+	default:
+		return query, fmt.Errorf("order must be asc or desc, got %q", order)
+	}
+
+	return query, query.Validate()
+}
+
+// parseQueryTime parses an RFC 3339 timestamp or a date. A date stands for
+// its first millisecond, or its last one when endOfDay is set, so a date-only
+// range covers whole days.
+func parseQueryTime(name, raw string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+
+	day, err := time.Parse(dateLayout, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", name)
+	}
+
+	if endOfDay {
+		return day.AddDate(0, 0, 1).Add(-time.Millisecond), nil
+	}
+	return day, nil
+}
@@ -1,37 +1,87 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"finance_app/src/audit"
+	"finance_app/src/auth"
+	"finance_app/src/cache"
+	"finance_app/src/metrics"
 	"finance_app/src/models"
+	"finance_app/src/ratelimit"
 	"finance_app/src/repositories"
+	"finance_app/src/tracing"
 	"finance_app/src/utils"
 	"finance_app/src/utils/types"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Request structure for creating/updating transactions
 type CreateTransactionRequest struct {
-	TransactionType string  `json:"transactionType"`
-	Amount          float64 `json:"amount"`
-	AccountId       string  `json:"accountId"`
+	TransactionType string       `json:"transactionType"`
+	Amount          models.Money `json:"amount"`
+	AccountId       string       `json:"accountId"`
+	// ToAccountId is the destination account, required for TRANSFER only
+	ToAccountId string `json:"toAccountId,omitempty"`
 }
 
 type TransactionHandler struct {
-	TransactionsRepo repositories.TransactionMongoRepository
-	AccountsRepo     repositories.AccountsMongoRepository
+	TransactionsRepo repositories.TransactionRepository
+	AccountsRepo     repositories.AccountRepository
+	LedgerRepo       repositories.LedgerRepository
+	AuditRepo        repositories.AuditRepository
+	Transactor       repositories.Transactor
 }
 
-// GetAllTransactions handles GET /api/v1/transactions
+// GetAllTransactions handles GET /api/v1/transactions. Admins and API keys get
+// every transaction, everyone else the transactions of the accounts they own.
 func (h *TransactionHandler) GetAllTransactions(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r, h.AuditRepo, auth.PermissionReadTransactions) {
+		return
+	}
+
 	ctx := r.Context()
 
-	transactions, err := h.TransactionsRepo.GetAllTransactions(ctx)
+	query, err := parseTransactionQuery(r)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	owned, scoped, err := accountScope(ctx, h.AccountsRepo)
 	if err != nil {
-		logrus.Error("Failed to get transactions: ", err)
+		logrus.WithContext(r.Context()).Error("Failed to get the caller's accounts: ", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   "Failed to fetch transactions",
+		})
+		return
+	}
+	if scoped && len(owned) == 0 {
+		utils.SendJSONResponse(w, http.StatusOK, types.APIResponse{
+			Success: true,
+			Data:    []models.Transaction{},
+			Message: "Transactions fetched successfully",
+		})
+		return
+	}
+	query.AccountIDs = owned
+
+	transactions, next, err := h.TransactionsRepo.GetAllTransactions(ctx, query)
+	if err != nil {
+		logrus.WithContext(r.Context()).Error("Failed to get transactions: ", err)
 		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
 			Success: false,
 			Error:   "Failed to fetch transactions",
@@ -40,18 +90,32 @@ func (h *TransactionHandler) GetAllTransactions(w http.ResponseWriter, r *http.R
 	}
 
 	utils.SendJSONResponse(w, http.StatusOK, types.APIResponse{
-		Success: true,
-		Data:    transactions,
-		Message: "Transactions fetched successfully",
+		Success:    true,
+		Data:       transactions,
+		Message:    "Transactions fetched successfully",
+		NextCursor: encodeNextCursor(next),
 	})
 }
 
 // CreateTransaction handles POST /api/v1/transactions
 func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r, h.AuditRepo, auth.PermissionCreateTransactions) {
+		return
+	}
+
+	// Status and balance checks must see the stored account
+	r = r.WithContext(cache.Bypass(r.Context()))
 	ctx := r.Context()
+	var req CreateTransactionRequest
+
+	// Count the outcome once the response status is known
+	ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	w = ww
+	defer func() {
+		metrics.Transactions.WithLabelValues(transactionTypeLabel(req.TransactionType), metrics.Outcome(ww.Status())).Inc()
+	}()
 
 	// Parse request body
-	var req CreateTransactionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
 			Success: false,
@@ -69,7 +133,7 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	if req.Amount <= 0 {
+	if !req.Amount.IsPositive() {
 		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
 			Success: false,
 			Error:   "amount must be greater than 0",
@@ -77,8 +141,9 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Validate and parse account ID
-	account, err := h.AccountsRepo.FindOne(ctx, req.AccountId)
+	// Validate and parse account ID. Money only moves out of, or into,
+	// accounts the caller owns.
+	account, err := findAccessibleAccount(ctx, h.AccountsRepo, req.AccountId)
 	if err != nil {
 		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
 			Success: false,
@@ -87,13 +152,34 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	accountId := account.ID.Hex()
+	// Only now count the transaction against the account's window, so
+	// callers cannot use up the window of accounts that are not theirs
+	if !ratelimit.ChargeAccount(w, r, account.ID.Hex()) {
+		return
+	}
+
 	transactionType := models.TransactionType(strings.ToUpper(req.TransactionType))
+	trace.SpanFromContext(ctx).SetAttributes(
+		tracing.AttrAccountID.String(account.ID.Hex()),
+		tracing.AttrTransactionType.String(transactionTypeLabel(req.TransactionType)),
+	)
+
+	// Frozen and closed accounts take no new transactions
+	if status := account.CurrentStatus(); status != models.AccountActive {
+		countRejectedDebit(transactionType, &repositories.AccountStatusError{AccountID: account.ID.Hex(), Status: status})
+		utils.SendJSONResponse(w, http.StatusConflict, types.APIResponse{
+			Success: false,
+			Error:   (&repositories.AccountStatusError{AccountID: account.ID.Hex(), Status: status}).Error(),
+		})
+		return
+	}
+
+	accountId := account.ID.Hex()
 	switch transactionType {
-	case models.Deposit:
-		account.Balance += req.Amount
-	case models.Withdraw:
-		account.Balance -= req.Amount
+	case models.Deposit, models.Withdraw:
+	case models.Transfer:
+		h.createTransfer(w, r, req, account)
+		return
 	default:
 		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
 			Success: false,
@@ -102,48 +188,216 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	err = h.AccountsRepo.UpdateBalance(ctx, accountId, account.Balance)
+	// Balance change, transaction record, journal entry and audit event commit
+	// or roll back together
+	var updatedAccount *models.Accounts
+	var transaction *models.Transaction
+	err = h.Transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if transactionType == models.Withdraw {
+			updatedAccount, err = h.AccountsRepo.Debit(ctx, accountId, req.Amount)
+			countRejectedDebit(transactionType, err)
+		} else {
+			updatedAccount, err = h.AccountsRepo.Credit(ctx, accountId, req.Amount)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Create transaction model
+		transaction = &models.Transaction{
+			TransactionType: transactionType,
+			Amount:          req.Amount,
+			Balance:         updatedAccount.Balance,
+			AccountId:       account.ID,
+		}
+
+		// Save to database
+		if err := h.TransactionsRepo.Create(ctx, transaction); err != nil {
+			return err
+		}
+
+		// Deposits come in through cash in, withdrawals leave through cash out
+		entry := models.NewTransferEntry("deposit", models.LedgerCashIn, accountId, req.Amount)
+		if transactionType == models.Withdraw {
+			entry = models.NewTransferEntry("withdrawal", accountId, models.LedgerCashOut, req.Amount)
+		}
+		entry.TransactionId = &transaction.ID
+
+		if err := h.LedgerRepo.Post(ctx, entry); err != nil {
+			return err
+		}
+
+		moved := req.Amount
+		if transactionType == models.Withdraw {
+			moved = moved.Neg()
+		}
+		event := audit.NewEvent(r, models.AuditTransactionCreated, balanceSnapshot(updatedAccount, moved))
+		event.TransactionId = transaction.ID.Hex()
+		return recordAudit(ctx, h.AuditRepo, event)
+	})
 
 	if err != nil {
-		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
+		logrus.WithContext(r.Context()).Error("Failed to create transaction: ", err)
+		utils.SendJSONResponse(w, balanceChangeStatus(err), types.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
 		return
 	}
 
-	// Create transaction model
-	transaction := &models.Transaction{
-		TransactionType: models.TransactionType(strings.ToUpper(req.TransactionType)),
-		Amount:          req.Amount,
-		AccountId:       account.ID,
+	transactionsForTheUser, _, err := h.TransactionsRepo.GetByAccountID(ctx, accountId, repositories.TransactionQuery{})
+
+	if err != nil {
+		logrus.WithContext(r.Context()).Error("Failed to fetch transactions for the user: ", err)
+		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
+			Success: false,
+			Error:   "Failed to fetch transactions for the user",
+		})
+		return
 	}
 
-	// Save to database
-	if err := h.TransactionsRepo.Create(ctx, transaction); err != nil {
-		logrus.Error("Failed to create transaction: ", err)
+	utils.SendJSONResponse(w, http.StatusCreated, types.APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"transactions": transactionsForTheUser,
+			"account":      updatedAccount,
+		},
+		Message: "Transaction created successfully",
+	})
+}
+
+// createTransfer moves req.Amount from account to req.ToAccountId and records
+// one linked transaction on each side
+func (h *TransactionHandler) createTransfer(w http.ResponseWriter, r *http.Request, req CreateTransactionRequest, account *models.Accounts) {
+	ctx := r.Context()
+
+	if req.ToAccountId == "" {
 		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
 			Success: false,
-			Error:   err.Error(),
+			Error:   "destination account ID cannot be empty",
 		})
 		return
 	}
 
-	updatedAccount, err := h.AccountsRepo.FindOne(ctx, accountId)
-
+	destination, err := h.AccountsRepo.FindOne(ctx, req.ToAccountId)
 	if err != nil {
-		logrus.Error("Failed to fetch updated account: ", err)
-		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
+		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "destination " + err.Error(),
+		})
+		return
+	}
+
+	if status := destination.CurrentStatus(); status != models.AccountActive {
+		utils.SendJSONResponse(w, http.StatusConflict, types.APIResponse{
 			Success: false,
-			Error:   "Failed to fetch updated account",
+			Error:   "destination " + (&repositories.AccountStatusError{AccountID: destination.ID.Hex(), Status: status}).Error(),
 		})
 		return
 	}
 
-	transactionsForTheUser, err := h.TransactionsRepo.GetByAccountID(ctx, accountId)
+	if destination.ID == account.ID {
+		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   "cannot transfer to the same account",
+		})
+		return
+	}
+
+	sourceId := account.ID.Hex()
+	destinationId := destination.ID.Hex()
+
+	// Both balance changes, both records, the journal entry and the audit
+	// event commit or roll back together
+	var updatedAccount, updatedDestination *models.Accounts
+	outId := primitive.NewObjectID()
+	err = h.Transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		debit := func() (err error) {
+			updatedAccount, err = h.AccountsRepo.Debit(ctx, sourceId, req.Amount)
+			if err != nil {
+				countRejectedDebit(models.Transfer, err)
+			}
+			return err
+		}
+		credit := func() (err error) {
+			updatedDestination, err = h.AccountsRepo.Credit(ctx, destinationId, req.Amount)
+			if err != nil {
+				return fmt.Errorf("destination %w", err)
+			}
+			return nil
+		}
+
+		// PostgreSQL locks each account row as its balance changes. Changing
+		// them in ID order keeps two transfers between the same accounts in
+		// opposite directions from waiting on each other's lock.
+		changes := []func() error{debit, credit}
+		if destinationId < sourceId {
+			changes = []func() error{credit, debit}
+		}
+		for _, change := range changes {
+			if err := change(); err != nil {
+				return err
+			}
+		}
+
+		// Pre-assign IDs so each side can point at the other
+		inId := primitive.NewObjectID()
+
+		outgoing := &models.Transaction{
+			ID:                    outId,
+			TransactionType:       models.Transfer,
+			Amount:                req.Amount,
+			Balance:               updatedAccount.Balance,
+			AccountId:             account.ID,
+			Direction:             models.TransferOut,
+			CounterpartyAccountId: &destination.ID,
+			LinkedTransactionId:   &inId,
+		}
+
+		incoming := &models.Transaction{
+			ID:                    inId,
+			TransactionType:       models.Transfer,
+			Amount:                req.Amount,
+			Balance:               updatedDestination.Balance,
+			AccountId:             destination.ID,
+			Direction:             models.TransferIn,
+			CounterpartyAccountId: &account.ID,
+			LinkedTransactionId:   &outId,
+		}
+
+		for _, transaction := range []*models.Transaction{outgoing, incoming} {
+			if err := h.TransactionsRepo.Create(ctx, transaction); err != nil {
+				return err
+			}
+		}
+
+		entry := models.NewTransferEntry("transfer", sourceId, destinationId, req.Amount)
+		entry.TransactionId = &outId
+
+		if err := h.LedgerRepo.Post(ctx, entry); err != nil {
+			return err
+		}
+
+		event := audit.NewEvent(r, models.AuditTransactionCreated,
+			balanceSnapshot(updatedAccount, req.Amount.Neg()),
+			balanceSnapshot(updatedDestination, req.Amount))
+		event.TransactionId = outId.Hex()
+		return recordAudit(ctx, h.AuditRepo, event)
+	})
 
 	if err != nil {
-		logrus.Error("Failed to fetch transactions for the user: ", err)
+		logrus.WithContext(r.Context()).Error("Failed to create transfer: ", err)
+		utils.SendJSONResponse(w, balanceChangeStatus(err), types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	transactionsForTheUser, _, err := h.TransactionsRepo.GetByAccountID(ctx, sourceId, repositories.TransactionQuery{})
+	if err != nil {
+		logrus.WithContext(r.Context()).Error("Failed to fetch transactions for the user: ", err)
 		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
 			Success: false,
 			Error:   "Failed to fetch transactions for the user",
@@ -154,15 +408,67 @@ func (h *TransactionHandler) CreateTransaction(w http.ResponseWriter, r *http.Re
 	utils.SendJSONResponse(w, http.StatusCreated, types.APIResponse{
 		Success: true,
 		Data: map[string]interface{}{
-			"transactions": transactionsForTheUser,
-			"account":      updatedAccount,
+			"transactions":        transactionsForTheUser,
+			"account":             updatedAccount,
+			"counterpartyAccount": updatedDestination,
 		},
 		Message: "Transaction created successfully",
 	})
 }
 
-// GetTransactionByID handles GET /api/v1/transactions/{id}
+// balanceChangeStatus maps a failed balance change to an HTTP status: the
+// caller's fault for unknown accounts, insufficient funds or a balance that
+// would go out of range, a conflict when an account was frozen or closed in
+// the meantime, ours otherwise
+func balanceChangeStatus(err error) int {
+	var insufficient *repositories.InsufficientFundsError
+	var statusErr *repositories.AccountStatusError
+	switch {
+	case errors.As(err, &insufficient), errors.Is(err, repositories.ErrAccountNotFound),
+		errors.Is(err, models.ErrAmountOutOfRange):
+		return http.StatusBadRequest
+	case errors.As(err, &statusErr):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// countRejectedDebit counts a withdrawal or transfer refused because the
+// source account lacks the funds or is frozen or closed; other errors and
+// deposits are ignored
+func countRejectedDebit(transactionType models.TransactionType, err error) {
+	if transactionType != models.Withdraw && transactionType != models.Transfer {
+		return
+	}
+
+	var insufficient *repositories.InsufficientFundsError
+	var statusErr *repositories.AccountStatusError
+	switch {
+	case errors.As(err, &insufficient):
+		metrics.RejectedWithdrawals.WithLabelValues(string(transactionType), metrics.ReasonInsufficientFunds).Inc()
+	case errors.As(err, &statusErr):
+		metrics.RejectedWithdrawals.WithLabelValues(string(transactionType), metrics.ReasonAccountStatus).Inc()
+	}
+}
+
+// transactionTypeLabel keeps metric labels to the known transaction types
+func transactionTypeLabel(raw string) string {
+	switch transactionType := models.TransactionType(strings.ToUpper(raw)); transactionType {
+	case models.Deposit, models.Withdraw, models.Transfer:
+		return string(transactionType)
+	default:
+		return "INVALID"
+	}
+}
+
+// GetTransactionByID handles GET /api/v1/transactions/{id}, with the
+// transaction's reversals if it has any
 func (h *TransactionHandler) GetTransactionByID(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r, h.AuditRepo, auth.PermissionReadTransactions) {
+		return
+	}
+
 	ctx := r.Context()
 	transactionID := chi.URLParam(r, "id")
 
@@ -175,6 +481,12 @@ func (h *TransactionHandler) GetTransactionByID(w http.ResponseWriter, r *http.R
 	}
 
 	transaction, err := h.TransactionsRepo.GetByID(ctx, transactionID)
+	if err == nil {
+		_, err = findAccessibleAccount(ctx, h.AccountsRepo, transaction.AccountId.Hex())
+	}
+	if err == nil {
+		err = h.addReversals(ctx, transaction)
+	}
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			utils.SendJSONResponse(w, http.StatusNotFound, types.APIResponse{
@@ -183,7 +495,7 @@ func (h *TransactionHandler) GetTransactionByID(w http.ResponseWriter, r *http.R
 			})
 			return
 		}
-		logrus.Error("Failed to get transaction: ", err)
+		logrus.WithContext(r.Context()).Error("Failed to get transaction: ", err)
 		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
 			Success: false,
 			Error:   "Failed to fetch transaction",
@@ -200,6 +512,10 @@ func (h *TransactionHandler) GetTransactionByID(w http.ResponseWriter, r *http.R
 
 // GetTransactionsByAccountID handles GET /api/v1/transactions/account/{accountId}
 func (h *TransactionHandler) GetTransactionsByAccountID(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r, h.AuditRepo, auth.PermissionReadTransactions) {
+		return
+	}
+
 	ctx := r.Context()
 	accountID := chi.URLParam(r, "accountId")
 
@@ -211,7 +527,29 @@ func (h *TransactionHandler) GetTransactionsByAccountID(w http.ResponseWriter, r
 		return
 	}
 
-	transactions, err := h.TransactionsRepo.GetByAccountID(ctx, accountID)
+	query, err := parseTransactionQuery(r)
+	if err != nil {
+		utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	_, err = findAccessibleAccount(ctx, h.AccountsRepo, accountID)
+	if errors.Is(err, repositories.ErrAccountNotFound) {
+		utils.SendJSONResponse(w, http.StatusNotFound, types.APIResponse{
+			Success: false,
+			Error:   "Account not found",
+		})
+		return
+	}
+
+	var transactions []*models.Transaction
+	var next *repositories.PageCursor
+	if err == nil {
+		transactions, next, err = h.TransactionsRepo.GetByAccountID(ctx, accountID, query)
+	}
 	if err != nil {
 		if strings.Contains(err.Error(), "invalid account ID") {
 			utils.SendJSONResponse(w, http.StatusBadRequest, types.APIResponse{
@@ -220,7 +558,7 @@ func (h *TransactionHandler) GetTransactionsByAccountID(w http.ResponseWriter, r
 			})
 			return
 		}
-		logrus.Error("Failed to get transactions for account: ", err)
+		logrus.WithContext(r.Context()).Error("Failed to get transactions for account: ", err)
 		utils.SendJSONResponse(w, http.StatusInternalServerError, types.APIResponse{
 			Success: false,
 			Error:   "Failed to fetch transactions",
@@ -229,8 +567,9 @@ func (h *TransactionHandler) GetTransactionsByAccountID(w http.ResponseWriter, r
 	}
 
 	utils.SendJSONResponse(w, http.StatusOK, types.APIResponse{
-		Success: true,
-		Data:    transactions,
-		Message: "Transactions fetched successfully",
+		Success:    true,
+		Data:       transactions,
+		Message:    "Transactions fetched successfully",
+		NextCursor: encodeNextCursor(next),
 	})
 }
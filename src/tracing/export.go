@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Exporters selectable with tracing.exporter
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Options configures the tracer provider Setup installs
+type Options struct {
+	// Exporter is ExporterNone, ExporterStdout or ExporterOTLP
+	Exporter string
+	// Endpoint is the OTLP/HTTP collector base URL, such as
+	// http://localhost:4318
+	Endpoint    string
+	ServiceName string
+	// Stdout receives spans of the stdout exporter; os.Stdout when nil
+	Stdout io.Writer
+}
+
+// Setup installs the tracer provider for opts as the global one and returns a
+// function that flushes and stops its exporter. Spans are recorded even
+// without an exporter so trace IDs still reach the logs.
+func Setup(opts Options) (shutdown func(ctx context.Context) error, err error) {
+	serviceName := opts.ServiceName
+	if serviceName == "" {
+		serviceName = "finance_app"
+	}
+	providerOptions := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	}
+
+	switch opts.Exporter {
+	case ExporterNone, "":
+	case ExporterStdout:
+		out := opts.Stdout
+		if out == nil {
+			out = os.Stdout
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(out))
+		if err != nil {
+			return nil, err
+		}
+		// Write each span as it ends, so no collector or flush is needed to
+		// check locally
+		providerOptions = append(providerOptions, sdktrace.WithSyncer(exporter))
+	case ExporterOTLP:
+		exporter, err := otlptracehttp.New(context.Background(),
+			otlptracehttp.WithEndpointURL(strings.TrimSuffix(opts.Endpoint, "/")+"/v1/traces"),
+		)
+		if err != nil {
+			return nil, err
+		}
+		// Batch spans off the request path; they are dropped rather than
+		// blocking requests when the queue is full
+		providerOptions = append(providerOptions, sdktrace.WithBatcher(exporter))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected %s, %s or %s", opts.Exporter, ExporterNone, ExporterStdout, ExporterOTLP)
+	}
+
+	provider := sdktrace.NewTracerProvider(providerOptions...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logrus.Warn("Failed to export spans: ", err)
+	}))
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,178 @@
+package tracing
+
+import (
+	"context"
+	"finance_app/src/models"
+	"finance_app/src/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// end records err on span and ends it
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceAccounts starts a child span for every call to next. dbSystem names
+// the database, such as mongodb.
+func TraceAccounts(next repositories.AccountRepository, dbSystem string) repositories.AccountRepository {
+	return &accountsTracer{next: next, dbSystem: dbSystem}
+}
+
+type accountsTracer struct {
+	next     repositories.AccountRepository
+	dbSystem string
+}
+
+func (a *accountsTracer) start(ctx context.Context, method string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Start(ctx, "AccountRepository."+method, trace.SpanKindClient, append(attributes, attribute.String("db.system", a.dbSystem))...)
+}
+
+func (a *accountsTracer) FindOne(ctx context.Context, id string) (account *models.Accounts, err error) {
+	ctx, span := a.start(ctx, "FindOne", AttrAccountID.String(id))
+	defer func() { end(span, err) }()
+	return a.next.FindOne(ctx, id)
+}
+
+func (a *accountsTracer) Credit(ctx context.Context, id string, amount models.Money) (account *models.Accounts, err error) {
+	ctx, span := a.start(ctx, "Credit", AttrAccountID.String(id))
+	defer func() { end(span, err) }()
+	return a.next.Credit(ctx, id, amount)
+}
+
+func (a *accountsTracer) Debit(ctx context.Context, id string, amount models.Money) (account *models.Accounts, err error) {
+	ctx, span := a.start(ctx, "Debit", AttrAccountID.String(id))
+	defer func() { end(span, err) }()
+	return a.next.Debit(ctx, id, amount)
+}
+
+func (a *accountsTracer) GetAllAccounts(ctx context.Context, page repositories.PageRequest) (accounts []models.Accounts, next *repositories.PageCursor, err error) {
+	ctx, span := a.start(ctx, "GetAllAccounts")
+	defer func() { end(span, err) }()
+	return a.next.GetAllAccounts(ctx, page)
+}
+
+func (a *accountsTracer) GetByOwner(ctx context.Context, owner string, page repositories.PageRequest) (accounts []models.Accounts, next *repositories.PageCursor, err error) {
+	ctx, span := a.start(ctx, "GetByOwner")
+	defer func() { end(span, err) }()
+	return a.next.GetByOwner(ctx, owner, page)
+}
+
+func (a *accountsTracer) ForEachAccount(ctx context.Context, fn func(*models.Accounts) error) (err error) {
+	ctx, span := a.start(ctx, "ForEachAccount")
+	defer func() { end(span, err) }()
+	return a.next.ForEachAccount(ctx, fn)
+}
+
+func (a *accountsTracer) CreateAccount(ctx context.Context, account *models.Accounts) (err error) {
+	ctx, span := a.start(ctx, "CreateAccount")
+	defer func() {
+		span.SetAttributes(AttrAccountID.String(account.ID.Hex()))
+		end(span, err)
+	}()
+	return a.next.CreateAccount(ctx, account)
+}
+
+func (a *accountsTracer) UpdateDetails(ctx context.Context, id string, update models.AccountUpdate) (account *models.Accounts, err error) {
+	ctx, span := a.start(ctx, "UpdateDetails", AttrAccountID.String(id))
+	defer func() { end(span, err) }()
+	return a.next.UpdateDetails(ctx, id, update)
+}
+
+func (a *accountsTracer) UpdateStatus(ctx context.Context, id string, status models.AccountStatus) (account *models.Accounts, err error) {
+	ctx, span := a.start(ctx, "UpdateStatus", AttrAccountID.String(id), attribute.String("account.status", string(status)))
+	defer func() { end(span, err) }()
+	return a.next.UpdateStatus(ctx, id, status)
+}
+
+// TraceTransactions starts a child span for every call to next. dbSystem
+// names the database, such as mongodb.
+func TraceTransactions(next repositories.TransactionRepository, dbSystem string) repositories.TransactionRepository {
+	return &transactionsTracer{next: next, dbSystem: dbSystem}
+}
+
+type transactionsTracer struct {
+	next     repositories.TransactionRepository
+	dbSystem string
+}
+
+func (t *transactionsTracer) start(ctx context.Context, method string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Start(ctx, "TransactionRepository."+method, trace.SpanKindClient, append(attributes, attribute.String("db.system", t.dbSystem))...)
+}
+
+// queryAttributes describes the filters of query that identify what is read
+func queryAttributes(query repositories.TransactionQuery) []attribute.KeyValue {
+	if query.Type == "" {
+		return nil
+	}
+	return []attribute.KeyValue{AttrTransactionType.String(string(query.Type))}
+}
+
+func (t *transactionsTracer) Create(ctx context.Context, transaction *models.Transaction) (err error) {
+	ctx, span := t.start(ctx, "Create",
+		AttrAccountID.String(transaction.AccountId.Hex()),
+		AttrTransactionType.String(string(transaction.TransactionType)),
+	)
+	defer func() {
+		span.SetAttributes(AttrTransactionID.String(transaction.ID.Hex()))
+		end(span, err)
+	}()
+	return t.next.Create(ctx, transaction)
+}
+
+func (t *transactionsTracer) GetAllTransactions(ctx context.Context, query repositories.TransactionQuery) (transactions []models.Transaction, next *repositories.PageCursor, err error) {
+	ctx, span := t.start(ctx, "GetAllTransactions", queryAttributes(query)...)
+	defer func() { end(span, err) }()
+	return t.next.GetAllTransactions(ctx, query)
+}
+
+func (t *transactionsTracer) GetByID(ctx context.Context, id string) (transaction *models.Transaction, err error) {
+	ctx, span := t.start(ctx, "GetByID", AttrTransactionID.String(id))
+	defer func() {
+		if transaction != nil {
+			span.SetAttributes(
+				AttrAccountID.String(transaction.AccountId.Hex()),
+				AttrTransactionType.String(string(transaction.TransactionType)),
+			)
+		}
+		end(span, err)
+	}()
+	return t.next.GetByID(ctx, id)
+}
+
+func (t *transactionsTracer) GetByAccountID(ctx context.Context, accountID string, query repositories.TransactionQuery) (transactions []*models.Transaction, next *repositories.PageCursor, err error) {
+	ctx, span := t.start(ctx, "GetByAccountID", append(queryAttributes(query), AttrAccountID.String(accountID))...)
+	defer func() { end(span, err) }()
+	return t.next.GetByAccountID(ctx, accountID, query)
+}
+
+func (t *transactionsTracer) ForEachByAccountID(ctx context.Context, accountID string, fn func(*models.Transaction) error) (err error) {
+	ctx, span := t.start(ctx, "ForEachByAccountID", AttrAccountID.String(accountID))
+	defer func() { end(span, err) }()
+	return t.next.ForEachByAccountID(ctx, accountID, fn)
+}
+
+func (t *transactionsTracer) SetBalance(ctx context.Context, id primitive.ObjectID, balance models.Money) (err error) {
+	ctx, span := t.start(ctx, "SetBalance", AttrTransactionID.String(id.Hex()))
+	defer func() { end(span, err) }()
+	return t.next.SetBalance(ctx, id, balance)
+}
+
+func (t *transactionsTracer) AddReversal(ctx context.Context, id primitive.ObjectID, amount models.Money) (transaction *models.Transaction, err error) {
+	ctx, span := t.start(ctx, "AddReversal", AttrTransactionID.String(id.Hex()))
+	defer func() { end(span, err) }()
+	return t.next.AddReversal(ctx, id, amount)
+}
+
+func (t *transactionsTracer) GetReversals(ctx context.Context, id primitive.ObjectID) (reversals []models.Transaction, err error) {
+	ctx, span := t.start(ctx, "GetReversals", AttrTransactionID.String(id.Hex()))
+	defer func() { end(span, err) }()
+	return t.next.GetReversals(ctx, id)
+}
@@ -0,0 +1,31 @@
+// Package tracing records spans with OpenTelemetry and exports them to stdout
+// or an OTLP/HTTP collector.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer the app's spans come from
+const instrumentationName = "finance_app/src/tracing"
+
+// Span attributes shared by the handlers and repositories
+const (
+	AttrAccountID       = attribute.Key("account.id")
+	AttrTransactionID   = attribute.Key("transaction.id")
+	AttrTransactionType = attribute.Key("transaction.type")
+)
+
+// Start begins a span with the tracer provider installed by Setup. It is a
+// child of the current span in ctx, or of a remote parent the middleware
+// extracted, or the root of a new trace.
+func Start(ctx context.Context, name string, kind trace.SpanKind, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name,
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(attributes...),
+	)
+}
@@ -2,24 +2,26 @@ package utils
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
-	"os"
 	"time"
 
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func MongoConnect() (*mongo.Client, error) {
-	mongoURI := os.Getenv("MONGO_URI")
-	if mongoURI == "" {
-		return nil, fmt.Errorf("MONGO_URI environment variable is not set")
+// MongoConnect connects to uri and verifies the connection with a ping, both
+// within timeout
+func MongoConnect(uri string, timeout time.Duration) (*mongo.Client, error) {
+	if uri == "" {
+		return nil, fmt.Errorf("MongoDB URI is not set")
 	}
 
-	clientOptions := options.Client().ApplyURI(mongoURI)
+	clientOptions := options.Client().ApplyURI(uri)
 
 	// Create a context with timeout for connection
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	// Connect to MongoDB
@@ -37,3 +39,25 @@ func MongoConnect() (*mongo.Client, error) {
 
 	return client, nil
 }
+
+// PostgresConnect opens a pool for dsn and verifies it with a ping
+func PostgresConnect(dsn string) (*sql.DB, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("PostgreSQL DSN is not set")
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PostgreSQL pool: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping PostgreSQL: %w", err)
+	}
+
+	return db, nil
+}
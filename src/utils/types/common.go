@@ -6,4 +6,6 @@ type APIResponse struct {
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	// NextCursor is set on list responses that have another page
+	NextCursor string `json:"nextCursor,omitempty"`
 }
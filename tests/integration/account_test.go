@@ -4,21 +4,58 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"finance_app/src/config"
+	"finance_app/src/handlers"
 	"finance_app/src/models"
+	"finance_app/src/repositories"
+	"finance_app/src/routes"
 	"finance_app/src/utils/types"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// errStorageDown is what an unreachable database looks like to a repository
+var errStorageDown = errors.New("dial tcp 10.0.0.7:5432: connect: connection refused")
+
+// unavailableAccounts fails every account change like a storage outage
+type unavailableAccounts struct {
+	repositories.AccountRepository
+}
+
+func (unavailableAccounts) CreateAccount(ctx context.Context, account *models.Accounts) error {
+	return errStorageDown
+}
+
+func (unavailableAccounts) UpdateDetails(ctx context.Context, id string, update models.AccountUpdate) (*models.Accounts, error) {
+	return nil, errStorageDown
+}
+
+func (unavailableAccounts) UpdateStatus(ctx context.Context, id string, status models.AccountStatus) (*models.Accounts, error) {
+	return nil, errStorageDown
+}
+
+// unavailableRouter serves the API with account changes failing like a
+// storage outage
+func unavailableRouter(ts *TestSuite) chi.Router {
+	h := handlers.NewAppHandler(ts.Client, ts.Transactor, ts.TransactionRepository, unavailableAccounts{ts.AccountsRepository},
+		ts.Handler.IdempotencyRepository, ts.LedgerRepository, ts.Handler.APIKeyRepository, ts.Handler.AuditRepository)
+	router := chi.NewRouter()
+	routes.Routes(router, h, config.Default().Server)
+	return router
+}
+
 func TestAccountIntegration(t *testing.T) {
-	// Skip if MongoDB is not available
-	SkipIfNoMongo(t)
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
 
 	// Setup test suite
 	ts := SetupTestSuite(t)
@@ -108,8 +145,8 @@ func TestAccountIntegration(t *testing.T) {
 
 		// Create test accounts
 		accounts := []map[string]interface{}{
-			{"name": "John Doe", "email": "john@example.com", "initialBalance": 1000.0},
-			{"name": "Jane Smith", "email": "jane@example.com", "initialBalance": 2000.0},
+			{"name": "John Doe", "email": "john@example.com", "initialBalance": "1000.00"},
+			{"name": "Jane Smith", "email": "jane@example.com", "initialBalance": "2000.00"},
 		}
 
 		// Insert accounts directly into database
@@ -117,7 +154,7 @@ func TestAccountIntegration(t *testing.T) {
 			account := &models.Accounts{
 				Name:    accountData["name"].(string),
 				Email:   accountData["email"].(string),
-				Balance: accountData["initialBalance"].(float64),
+				Balance: models.MustParseMoney(accountData["initialBalance"].(string)),
 			}
 			err := ts.AccountsRepository.CreateAccount(context.Background(), account)
 			require.NoError(t, err)
@@ -152,7 +189,7 @@ func TestAccountIntegration(t *testing.T) {
 		account := &models.Accounts{
 			Name:    "John Doe",
 			Email:   "john@example.com",
-			Balance: 1000.0,
+			Balance: models.MustParseMoney("1000.00"),
 		}
 		err := ts.AccountsRepository.CreateAccount(context.Background(), account)
 		require.NoError(t, err)
@@ -227,4 +264,250 @@ func TestAccountIntegration(t *testing.T) {
 		assert.False(t, response.Success)
 		assert.Contains(t, response.Error, "Email is required")
 	})
+
+	t.Run("Create Account with Negative Balance", func(t *testing.T) {
+		// Clean up accounts collection before test
+		ts.CleanupCollections(t, "accounts")
+
+		jsonData, err := json.Marshal(map[string]interface{}{
+			"name":           "John Doe",
+			"email":          "john.doe@example.com",
+			"initialBalance": -100.0,
+		})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/api/v1/accounts", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		ts.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		accounts, _, err := ts.AccountsRepository.GetAllAccounts(context.Background(), repositories.PageRequest{})
+		require.NoError(t, err)
+		assert.Empty(t, accounts)
+	})
+
+	t.Run("Create Account Storage Failure", func(t *testing.T) {
+		// Clean up accounts collection before test
+		ts.CleanupCollections(t, "accounts")
+
+		jsonData, err := json.Marshal(map[string]interface{}{
+			"name":  "John Doe",
+			"email": "john.doe@example.com",
+		})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/api/v1/accounts", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		unavailableRouter(ts).ServeHTTP(w, req)
+
+		// Storage errors are server errors and their details stay in the logs
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+		var response types.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "Failed to create account", response.Error)
+		assert.Nil(t, response.Data)
+	})
+}
+
+func TestAccountLifecycleIntegration(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	// Helper function to send a request and decode the response
+	send := func(method, path string, body map[string]interface{}) (int, types.APIResponse) {
+		var reader *bytes.Buffer
+		if body != nil {
+			jsonData, err := json.Marshal(body)
+			require.NoError(t, err)
+			reader = bytes.NewBuffer(jsonData)
+		} else {
+			reader = bytes.NewBuffer(nil)
+		}
+
+		req := httptest.NewRequest(method, path, reader)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.Router.ServeHTTP(w, req)
+
+		var response types.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w.Code, response
+	}
+
+	// Helper function to create a test account
+	createTestAccount := func(name, email, balance string) string {
+		account := &models.Accounts{Name: name, Email: email, Balance: models.MustParseMoney(balance)}
+		require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+		return account.ID.Hex()
+	}
+
+	t.Run("Update Name and Email", func(t *testing.T) {
+		// Clean up accounts collection before test
+		ts.CleanupCollections(t, "accounts")
+
+		id := createTestAccount("John Doe", "john@example.com", "100.00")
+
+		status, response := send("PATCH", "/api/v1/accounts/"+id, map[string]interface{}{
+			"name":  "Johnny Doe",
+			"email": "johnny@example.com",
+		})
+		assert.Equal(t, http.StatusOK, status)
+		assert.True(t, response.Success)
+
+		account, err := ts.AccountsRepository.FindOne(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, "Johnny Doe", account.Name)
+		assert.Equal(t, "johnny@example.com", account.Email)
+		assert.Equal(t, models.AccountActive, account.Status)
+
+		// Only the fields that are sent change
+		status, _ = send("PATCH", "/api/v1/accounts/"+id, map[string]interface{}{"name": "John"})
+		assert.Equal(t, http.StatusOK, status)
+		account, err = ts.AccountsRepository.FindOne(context.Background(), id)
+		require.NoError(t, err)
+		assert.Equal(t, "johnny@example.com", account.Email)
+	})
+
+	t.Run("Update to an Email In Use", func(t *testing.T) {
+		// Clean up accounts collection before test
+		ts.CleanupCollections(t, "accounts")
+
+		createTestAccount("John Doe", "john@example.com", "100.00")
+		id := createTestAccount("Jane Smith", "jane@example.com", "100.00")
+
+		status, response := send("PATCH", "/api/v1/accounts/"+id, map[string]interface{}{"email": "john@example.com"})
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, response.Error, "account with this email already exists")
+
+		// Keeping its own email is fine
+		status, _ = send("PATCH", "/api/v1/accounts/"+id, map[string]interface{}{"email": "jane@example.com"})
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("Frozen Account Refuses Transactions", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions", "journal_entries")
+
+		id := createTestAccount("John Doe", "john@example.com", "100.00")
+		other := createTestAccount("Jane Smith", "jane@example.com", "100.00")
+
+		status, response := send("POST", "/api/v1/accounts/"+id+"/freeze", nil)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "FROZEN", response.Data.(map[string]interface{})["status"])
+
+		status, response = send("POST", "/api/v1/transactions", map[string]interface{}{
+			"transactionType": "DEPOSIT", "amount": 10.0, "accountId": id,
+		})
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Contains(t, response.Error, "frozen")
+
+		status, _ = send("POST", "/api/v1/transactions", map[string]interface{}{
+			"transactionType": "TRANSFER", "amount": 10.0, "accountId": other, "toAccountId": id,
+		})
+		assert.Equal(t, http.StatusConflict, status)
+
+		// The repository refuses too, for changes racing with a freeze
+		_, err := ts.AccountsRepository.Credit(context.Background(), id, models.MustParseMoney("1.00"))
+		assert.Error(t, err)
+
+		status, _ = send("POST", "/api/v1/accounts/"+id+"/unfreeze", nil)
+		assert.Equal(t, http.StatusOK, status)
+
+		status, _ = send("POST", "/api/v1/transactions", map[string]interface{}{
+			"transactionType": "DEPOSIT", "amount": 10.0, "accountId": id,
+		})
+		assert.Equal(t, http.StatusCreated, status)
+	})
+
+	t.Run("Close Only at Zero Balance", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions", "journal_entries")
+
+		id := createTestAccount("John Doe", "john@example.com", "100.00")
+
+		status, response := send("POST", "/api/v1/accounts/"+id+"/close", nil)
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Contains(t, response.Error, "balance must be zero")
+
+		status, _ = send("POST", "/api/v1/transactions", map[string]interface{}{
+			"transactionType": "WITHDRAW", "amount": 100.0, "accountId": id,
+		})
+		require.Equal(t, http.StatusCreated, status)
+
+		status, response = send("DELETE", "/api/v1/accounts/"+id, nil)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "CLOSED", response.Data.(map[string]interface{})["status"])
+
+		// The account and its history are kept
+		status, response = send("GET", "/api/v1/accounts/"+id, nil)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "CLOSED", response.Data.(map[string]interface{})["status"])
+
+		history, _, err := ts.TransactionRepository.GetByAccountID(context.Background(), id, repositories.TransactionQuery{})
+		require.NoError(t, err)
+		assert.Len(t, history, 1)
+
+		// A closed account stays closed and takes no changes
+		status, _ = send("POST", "/api/v1/transactions", map[string]interface{}{
+			"transactionType": "DEPOSIT", "amount": 10.0, "accountId": id,
+		})
+		assert.Equal(t, http.StatusConflict, status)
+
+		status, _ = send("POST", "/api/v1/accounts/"+id+"/unfreeze", nil)
+		assert.Equal(t, http.StatusConflict, status)
+
+		status, _ = send("PATCH", "/api/v1/accounts/"+id, map[string]interface{}{"name": "Someone Else"})
+		assert.Equal(t, http.StatusConflict, status)
+	})
+
+	t.Run("Unknown Account", func(t *testing.T) {
+		// Clean up accounts collection before test
+		ts.CleanupCollections(t, "accounts")
+
+		missing := fmt.Sprintf("/api/v1/accounts/%s", "507f1f77bcf86cd799439011")
+
+		status, _ := send("PATCH", missing, map[string]interface{}{"name": "Nobody"})
+		assert.Equal(t, http.StatusNotFound, status)
+
+		status, _ = send("POST", missing+"/freeze", nil)
+		assert.Equal(t, http.StatusNotFound, status)
+
+		status, _ = send("DELETE", "/api/v1/accounts/invalid-id", nil)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("Storage Failure", func(t *testing.T) {
+		// Clean up accounts collection before test
+		ts.CleanupCollections(t, "accounts")
+
+		id := createTestAccount("John Doe", "john@example.com", "100.00")
+		router := unavailableRouter(ts)
+
+		for path, want := range map[string]string{
+			"PATCH /api/v1/accounts/" + id:            "Failed to update account",
+			"POST /api/v1/accounts/" + id + "/freeze": "Failed to change account status",
+		} {
+			method, target, _ := strings.Cut(path, " ")
+			req := httptest.NewRequest(method, target, bytes.NewBufferString(`{"name":"Johnny Doe"}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// The driver's message stays in the logs
+			assert.Equal(t, http.StatusInternalServerError, w.Code, path)
+			var response types.APIResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, want, response.Error, path)
+		}
+	})
 }
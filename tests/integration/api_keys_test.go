@@ -0,0 +1,197 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"finance_app/src/auth"
+	"finance_app/src/config"
+	"finance_app/src/routes"
+	"finance_app/src/utils/types"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	verifier, err := auth.NewVerifier(auth.Options{HMACSecret: []byte(testHMACSecret)})
+	require.NoError(t, err)
+
+	ts.CleanupCollections(t, "accounts", "transactions", "journal_entries", "idempotency_keys", "api_keys")
+	ts.Handler.Auth = verifier
+	router := chi.NewRouter()
+	routes.Routes(router, ts.Handler, config.Default().Server)
+
+	admin := userToken(t, "ops", auth.RoleAdmin)
+
+	// send authenticates with token when it starts with "fin_" as an API key,
+	// otherwise as a bearer token
+	send := func(method, path, token string, body interface{}) (*httptest.ResponseRecorder, types.APIResponse) {
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		req := httptest.NewRequest(method, path, &payload)
+		req.Header.Set("Content-Type", "application/json")
+		if strings.HasPrefix(token, "fin_") {
+			req.Header.Set("X-API-Key", token)
+		} else if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response types.APIResponse
+		if w.Header().Get("Content-Type") == "application/json" {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		}
+		return w, response
+	}
+
+	createKey := func(body map[string]interface{}) (string, string) {
+		w, response := send("POST", "/api/v1/api-keys", admin, body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		data := response.Data.(map[string]interface{})
+		record := data["apiKey"].(map[string]interface{})
+		return record["id"].(string), data["key"].(string)
+	}
+
+	w, response := send("POST", "/api/v1/accounts", admin, map[string]interface{}{
+		"name": "Alice", "email": "alice@example.com", "initialBalance": "100.00",
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	accountID := response.Data.(map[string]interface{})["id"].(string)
+
+	t.Run("Admins Manage Keys", func(t *testing.T) {
+		w, _ := send("POST", "/api/v1/api-keys", userToken(t, "alice"), map[string]interface{}{
+			"name": "reports", "scopes": []string{auth.ScopeReadAccounts},
+		})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		tests := []struct {
+			name string
+			body map[string]interface{}
+		}{
+			{"missing name", map[string]interface{}{"scopes": []string{auth.ScopeReadAccounts}}},
+			{"missing scopes", map[string]interface{}{"name": "reports"}},
+			{"unknown scope", map[string]interface{}{"name": "reports", "scopes": []string{"delete:everything"}}},
+			{"expired", map[string]interface{}{
+				"name": "reports", "scopes": []string{auth.ScopeReadAccounts},
+				"expiresAt": time.Now().Add(-time.Hour),
+			}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w, _ := send("POST", "/api/v1/api-keys", admin, tt.body)
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			})
+		}
+
+		_, key := createKey(map[string]interface{}{"name": "listed", "scopes": []string{auth.ScopeReadAccounts}})
+
+		// The key is only shown when it is created
+		w, response := send("GET", "/api/v1/api-keys", admin, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), key)
+		listed := response.Data.([]interface{})
+		require.Len(t, listed, 1)
+		record := listed[0].(map[string]interface{})
+		assert.Equal(t, "listed", record["name"])
+		assert.Equal(t, "ops", record["createdBy"])
+		assert.True(t, strings.HasPrefix(key, record["prefix"].(string)))
+		assert.NotContains(t, record, "hash")
+	})
+
+	t.Run("Scopes Limit Endpoints", func(t *testing.T) {
+		_, reader := createKey(map[string]interface{}{"name": "reports", "scopes": []string{auth.ScopeReadAccounts}})
+		_, writer := createKey(map[string]interface{}{"name": "payments", "scopes": []string{auth.ScopeWriteTransactions}})
+
+		// API keys see every account
+		w, response := send("GET", "/api/v1/accounts", reader, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Len(t, response.Data, 1)
+		w, _ = send("GET", "/api/v1/transactions/account/"+accountID, reader, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		deposit := map[string]interface{}{"transactionType": "DEPOSIT", "amount": "5.00", "accountId": accountID}
+		w, _ = send("POST", "/api/v1/transactions", reader, deposit)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w, _ = send("POST", "/api/v1/transactions", writer, deposit)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		w, _ = send("GET", "/api/v1/accounts", writer, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w, _ = send("POST", "/api/v1/accounts/"+accountID+"/freeze", writer, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w, _ = send("GET", "/api/v1/api-keys", writer, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Admin Scope", func(t *testing.T) {
+		_, key := createKey(map[string]interface{}{"name": "ops", "scopes": []string{auth.ScopeAdmin}})
+
+		w, _ := send("GET", "/api/v1/accounts", key, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w, _ = send("GET", "/api/v1/ledger/invariants", key, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w, _ = send("GET", "/api/v1/api-keys", key, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Last Used Is Recorded", func(t *testing.T) {
+		id, key := createKey(map[string]interface{}{"name": "used", "scopes": []string{auth.ScopeReadAccounts}})
+
+		w, _ := send("GET", "/api/v1/accounts", key, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		_, response := send("GET", "/api/v1/api-keys", admin, nil)
+		for _, item := range response.Data.([]interface{}) {
+			record := item.(map[string]interface{})
+			if record["id"] == id {
+				assert.NotEmpty(t, record["last_used_at"])
+				return
+			}
+		}
+		t.Fatalf("API key %s not listed", id)
+	})
+
+	t.Run("Rejected Keys", func(t *testing.T) {
+		w, response := send("GET", "/api/v1/accounts", "fin_not-a-real-key", nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "Invalid or expired API key", response.Error)
+
+		id, key := createKey(map[string]interface{}{"name": "revoked", "scopes": []string{auth.ScopeReadAccounts}})
+		w, response = send("DELETE", "/api/v1/api-keys/"+id, admin, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, response.Data.(map[string]interface{})["revoked_at"])
+
+		w, _ = send("GET", "/api/v1/accounts", key, nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		_, key = createKey(map[string]interface{}{
+			"name": "short-lived", "scopes": []string{auth.ScopeReadAccounts},
+			"expiresAt": time.Now().Add(time.Second),
+		})
+		time.Sleep(1100 * time.Millisecond)
+		w, _ = send("GET", "/api/v1/accounts", key, nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w, _ = send("DELETE", "/api/v1/api-keys/"+"507f1f77bcf86cd799439011", admin, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w, _ = send("DELETE", "/api/v1/api-keys/not-an-id", admin, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
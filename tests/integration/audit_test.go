@@ -0,0 +1,385 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"finance_app/src/auth"
+	"finance_app/src/config"
+	"finance_app/src/handlers"
+	"finance_app/src/models"
+	"finance_app/src/repositories"
+	"finance_app/src/routes"
+	"finance_app/src/services"
+	"finance_app/src/utils/types"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tamperedAudit hands ForEachEvent callers events changed by tamper, as if
+// someone had edited the stored trail
+type tamperedAudit struct {
+	repositories.AuditRepository
+	tamper func(event *models.AuditEvent) (keep bool)
+}
+
+func (t *tamperedAudit) ForEachEvent(ctx context.Context, fn func(*models.AuditEvent) error) error {
+	return t.AuditRepository.ForEachEvent(ctx, func(event *models.AuditEvent) error {
+		if !t.tamper(event) {
+			return nil
+		}
+		return fn(event)
+	})
+}
+
+// unavailableAudit refuses every event, like a trail that stays busy
+type unavailableAudit struct {
+	repositories.AuditRepository
+}
+
+func (unavailableAudit) Append(ctx context.Context, event *models.AuditEvent) error {
+	return repositories.ErrAuditChainBusy
+}
+
+func TestAuditTrail(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	verifier, err := auth.NewVerifier(auth.Options{HMACSecret: []byte(testHMACSecret)})
+	require.NoError(t, err)
+
+	ts.CleanupCollections(t, "accounts", "transactions", "journal_entries", "idempotency_keys", "audit_events", "audit_outbox")
+	ts.Handler.Auth = verifier
+	router := chi.NewRouter()
+	routes.Routes(router, ts.Handler, config.Default().Server)
+
+	send := func(method, path, token string, body interface{}) (*httptest.ResponseRecorder, types.APIResponse) {
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		req := httptest.NewRequest(method, path, &payload)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response types.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w, response
+	}
+
+	events := func(query string) ([]models.AuditEvent, types.APIResponse) {
+		w, response := send("GET", "/api/v1/audit/events"+query, userToken(t, "auditor", auth.RoleAdmin), nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		data, err := json.Marshal(response.Data)
+		require.NoError(t, err)
+		var events []models.AuditEvent
+		require.NoError(t, json.Unmarshal(data, &events))
+		return events, response
+	}
+
+	createAccount := func(token, name string) string {
+		w, response := send("POST", "/api/v1/accounts", token, map[string]interface{}{
+			"name": name, "email": name + "@example.com",
+		})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		id := response.Data.(map[string]interface{})["id"].(string)
+
+		// Customers open at zero and fund the account with a deposit
+		w, _ = send("POST", "/api/v1/transactions", token, map[string]interface{}{
+			"transactionType": "DEPOSIT", "amount": "100.00", "accountId": id,
+		})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		return id
+	}
+
+	alice := userToken(t, "alice")
+	bob := userToken(t, "bob")
+	aliceAccount := createAccount(alice, "alice")
+	bobAccount := createAccount(bob, "bob")
+
+	w, _ := send("POST", "/api/v1/transactions", alice, map[string]interface{}{
+		"transactionType": "WITHDRAW", "amount": "30.00", "accountId": aliceAccount,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w, _ = send("POST", "/api/v1/transactions", alice, map[string]interface{}{
+		"transactionType": "TRANSFER", "amount": "20.00", "accountId": aliceAccount, "toAccountId": bobAccount,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w, _ = send("POST", "/api/v1/accounts/"+bobAccount+"/freeze", bob, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Refused changes are not events
+	w, _ = send("POST", "/api/v1/transactions", alice, map[string]interface{}{
+		"transactionType": "WITHDRAW", "amount": "1000.00", "accountId": aliceAccount,
+	})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	t.Run("Events Recorded", func(t *testing.T) {
+		all, _ := events("")
+		require.Len(t, all, 7)
+
+		// Newest first
+		actions := []string{}
+		for _, event := range all {
+			actions = append(actions, event.Action)
+		}
+		assert.Equal(t, []string{
+			models.AuditAccountFrozen,
+			models.AuditTransactionCreated,
+			models.AuditTransactionCreated,
+			models.AuditTransactionCreated,
+			models.AuditAccountCreated,
+			models.AuditTransactionCreated,
+			models.AuditAccountCreated,
+		}, actions)
+
+		withdrawal := all[2]
+		assert.Equal(t, "alice", withdrawal.Actor)
+		assert.NotEmpty(t, withdrawal.RequestID)
+		assert.Equal(t, "192.0.2.1", withdrawal.ClientIP)
+		assert.Equal(t, "POST /api/v1/transactions", withdrawal.Endpoint)
+		assert.NotEmpty(t, withdrawal.TransactionId)
+		require.Len(t, withdrawal.Balances, 1)
+		assert.Equal(t, aliceAccount, withdrawal.Balances[0].AccountId)
+		assert.Equal(t, "100.00", withdrawal.Balances[0].Before.String())
+		assert.Equal(t, "70.00", withdrawal.Balances[0].After.String())
+
+		transfer := all[1]
+		require.Len(t, transfer.Balances, 2)
+		assert.Equal(t, "50.00", transfer.Balances[0].After.String())
+		assert.Equal(t, bobAccount, transfer.Balances[1].AccountId)
+		assert.Equal(t, "120.00", transfer.Balances[1].After.String())
+
+		created := all[6]
+		assert.Nil(t, created.Balances[0].Before)
+		assert.Equal(t, "0.00", created.Balances[0].After.String())
+
+		frozen := all[0]
+		assert.Equal(t, "POST /api/v1/accounts/{id}/freeze", frozen.Endpoint)
+		assert.Equal(t, frozen.Balances[0].Before.String(), frozen.Balances[0].After.String())
+
+		// Each event points at the one before it
+		for i := 0; i < len(all)-1; i++ {
+			assert.Equal(t, all[i+1].Hash, all[i].PrevHash)
+			assert.Equal(t, all[i+1].Sequence+1, all[i].Sequence)
+		}
+		assert.Empty(t, all[6].PrevHash)
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		byActor, _ := events("?actor=bob")
+		assert.Len(t, byActor, 3)
+
+		byAccount, _ := events("?accountId=" + bobAccount)
+		assert.Len(t, byAccount, 4)
+
+		future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		later, _ := events("?from=" + future)
+		assert.Empty(t, later)
+		today, _ := events("?from=" + time.Now().UTC().Format("2006-01-02") + "&actor=alice")
+		assert.Len(t, today, 4)
+
+		page, response := events("?limit=2")
+		assert.Len(t, page, 2)
+		require.NotEmpty(t, response.NextCursor)
+		rest, _ := events("?limit=5&cursor=" + response.NextCursor)
+		assert.Len(t, rest, 5)
+
+		w, _ := send("GET", "/api/v1/audit/events?from=2026-02-01&to=2026-01-01", userToken(t, "auditor", auth.RoleAdmin), nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Admins Only", func(t *testing.T) {
+		for _, token := range []string{alice, userToken(t, "sam", auth.RoleSupport), userToken(t, "olga", auth.RoleOperator)} {
+			w, _ := send("GET", "/api/v1/audit/events", token, nil)
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			w, _ = send("GET", "/api/v1/audit/verify", token, nil)
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		}
+
+		// Every denial is an event in the trail
+		denied, _ := events("?actor=sam")
+		require.Len(t, denied, 2)
+		assert.Equal(t, models.AuditAccessDenied, denied[0].Action)
+		assert.Equal(t, string(auth.PermissionReadAudit), denied[0].Permission)
+		assert.Equal(t, "GET /api/v1/audit/verify", denied[0].Endpoint)
+		assert.Equal(t, "192.0.2.1", denied[0].ClientIP)
+		assert.Empty(t, denied[0].Balances)
+		assert.Equal(t, "GET /api/v1/audit/events", denied[1].Endpoint)
+	})
+
+	t.Run("Chain Verifies", func(t *testing.T) {
+		w, response := send("GET", "/api/v1/audit/verify", userToken(t, "auditor", auth.RoleAdmin), nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		report := response.Data.(map[string]interface{})
+		assert.Equal(t, true, report["intact"])
+		assert.Equal(t, 13.0, report["events"])
+		assert.Equal(t, 13.0, report["lastSequence"])
+	})
+
+	t.Run("Tampering Detected", func(t *testing.T) {
+		verify := func(tamper func(event *models.AuditEvent) bool) *services.AuditChainReport {
+			handler := &services.AuditHandler{AuditRepo: &tamperedAudit{ts.Handler.AuditRepository, tamper}}
+			report, err := handler.VerifyChain(context.Background())
+			require.NoError(t, err)
+			return report
+		}
+
+		edited := verify(func(event *models.AuditEvent) bool {
+			if event.Sequence == 3 {
+				event.Balances[0].After = models.MustParseMoney("700.00")
+			}
+			return true
+		})
+		assert.False(t, edited.Intact)
+		assert.Equal(t, int64(3), edited.BrokenAt)
+		assert.Equal(t, "hash does not match the event", edited.Reason)
+
+		// Recomputing the hash of an edited event breaks the link to it
+		rehashed := verify(func(event *models.AuditEvent) bool {
+			if event.Sequence == 3 {
+				event.Actor = "mallory"
+				event.Hash = event.ComputeHash()
+			}
+			return true
+		})
+		assert.False(t, rehashed.Intact)
+		assert.Equal(t, int64(4), rehashed.BrokenAt)
+
+		removed := verify(func(event *models.AuditEvent) bool {
+			return event.Sequence != 2
+		})
+		assert.False(t, removed.Intact)
+		assert.Equal(t, int64(3), removed.BrokenAt)
+		assert.Equal(t, "expected sequence 2", removed.Reason)
+		assert.Equal(t, 12, removed.Events)
+
+		// The stored trail is untouched
+		report := verify(func(*models.AuditEvent) bool { return true })
+		assert.True(t, report.Intact)
+	})
+}
+
+func TestAuditTrailTransactions(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	deposit := func(router http.Handler, accountID string) int {
+		body, err := json.Marshal(map[string]interface{}{
+			"transactionType": "DEPOSIT", "amount": "1.00", "accountId": accountID,
+		})
+		require.NoError(t, err)
+		req := httptest.NewRequest("POST", "/api/v1/transactions", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("Concurrent Changes Keep Every Event", func(t *testing.T) {
+		ts.CleanupCollections(t, "accounts", "transactions", "journal_entries", "audit_events", "audit_outbox")
+
+		account := &models.Accounts{Name: "John Doe", Email: "john@example.com"}
+		require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+
+		const deposits = 20
+		codes := make([]int, deposits)
+		var wg sync.WaitGroup
+		for i := 0; i < deposits; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				codes[i] = deposit(ts.Router, account.ID.Hex())
+			}(i)
+		}
+		wg.Wait()
+
+		for _, code := range codes {
+			assert.Equal(t, http.StatusCreated, code)
+		}
+
+		// One event per deposit, chained one after the other
+		handler := &services.AuditHandler{AuditRepo: ts.Handler.AuditRepository}
+		report, err := handler.VerifyChain(context.Background())
+		require.NoError(t, err)
+		assert.True(t, report.Intact)
+		assert.Equal(t, deposits, report.Events)
+		assert.Equal(t, int64(deposits), report.LastSequence)
+
+		updated, err := ts.AccountsRepository.FindOne(context.Background(), account.ID.Hex())
+		require.NoError(t, err)
+		assert.Equal(t, models.MustParseMoney("20.00"), updated.Balance)
+	})
+
+	t.Run("Queued Events Are Chained", func(t *testing.T) {
+		ts.CleanupCollections(t, "audit_events", "audit_outbox")
+		ctx := context.Background()
+
+		trail := ts.Handler.AuditRepository
+		require.NoError(t, trail.Append(ctx, &models.AuditEvent{Actor: "first", Action: models.AuditAccountCreated}))
+		err := ts.Transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			return trail.Append(ctx, &models.AuditEvent{Actor: "queued", Action: models.AuditAccountUpdated})
+		})
+		require.NoError(t, err)
+
+		// Chaining again finds nothing left to do
+		_, err = trail.ChainPending(ctx)
+		require.NoError(t, err)
+		chained, err := trail.ChainPending(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, chained)
+
+		var actors []string
+		require.NoError(t, trail.ForEachEvent(ctx, func(event *models.AuditEvent) error {
+			actors = append(actors, event.Actor)
+			return nil
+		}))
+		assert.Equal(t, []string{"first", "queued"}, actors)
+
+		handler := &services.AuditHandler{AuditRepo: trail}
+		report, err := handler.VerifyChain(ctx)
+		require.NoError(t, err)
+		assert.True(t, report.Intact)
+		assert.Equal(t, int64(2), report.LastSequence)
+	})
+
+	t.Run("Unrecorded Change Is Rolled Back", func(t *testing.T) {
+		ts.CleanupCollections(t, "accounts", "transactions", "journal_entries", "audit_events", "audit_outbox")
+
+		account := &models.Accounts{Name: "John Doe", Email: "john@example.com", Balance: models.MustParseMoney("100.00")}
+		require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+
+		h := handlers.NewAppHandler(ts.Client, ts.Transactor, ts.TransactionRepository, ts.AccountsRepository,
+			ts.Handler.IdempotencyRepository, ts.LedgerRepository, ts.Handler.APIKeyRepository, unavailableAudit{ts.Handler.AuditRepository})
+		router := chi.NewRouter()
+		routes.Routes(router, h, config.Default().Server)
+
+		assert.Equal(t, http.StatusInternalServerError, deposit(router, account.ID.Hex()))
+
+		updated, err := ts.AccountsRepository.FindOne(context.Background(), account.ID.Hex())
+		require.NoError(t, err)
+		assert.Equal(t, models.MustParseMoney("100.00"), updated.Balance)
+
+		history, _, err := ts.TransactionRepository.GetByAccountID(context.Background(), account.ID.Hex(), repositories.TransactionQuery{})
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+}
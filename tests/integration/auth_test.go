@@ -0,0 +1,558 @@
+package integration
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"finance_app/src/audit"
+	"finance_app/src/auth"
+	"finance_app/src/config"
+	"finance_app/src/routes"
+	"finance_app/src/utils/types"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHMACSecret = "0123456789abcdef0123456789abcdef"
+
+// signToken returns a compact JWT for claims, signed with HS256 using secret
+// or RS256 using key
+func signToken(t *testing.T, claims map[string]interface{}, secret []byte, key *rsa.PrivateKey) string {
+	alg := auth.AlgHS256
+	if key != nil {
+		alg = auth.AlgRS256
+	}
+
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(map[string]string{"alg": alg, "typ": "JWT"}) + "." + encode(claims)
+
+	var signature []byte
+	if key != nil {
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	} else {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// userToken is a valid HS256 token for subject with roles
+func userToken(t *testing.T, subject string, roles ...string) string {
+	return signToken(t, map[string]interface{}{
+		"sub":   subject,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": roles,
+	}, []byte(testHMACSecret), nil)
+}
+
+func TestJWTVerification(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "public.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	publicKey, err := auth.LoadRSAPublicKey(keyFile)
+	require.NoError(t, err)
+
+	verifier, err := auth.NewVerifier(auth.Options{
+		HMACSecret:   []byte(testHMACSecret),
+		RSAPublicKey: publicKey,
+		Issuer:       "https://issuer.example.com",
+		Audience:     "finance_app",
+	})
+	require.NoError(t, err)
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"sub":   "alice",
+			"iss":   "https://issuer.example.com",
+			"aud":   []string{"other", "finance_app"},
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"roles": []string{"admin"},
+		}
+	}
+
+	t.Run("HS256", func(t *testing.T) {
+		principal, err := verifier.Verify(signToken(t, valid(), []byte(testHMACSecret), nil))
+		require.NoError(t, err)
+		assert.Equal(t, "alice", principal.Subject)
+		assert.True(t, principal.IsAdmin())
+	})
+
+	t.Run("RS256", func(t *testing.T) {
+		claims := valid()
+		claims["aud"] = "finance_app"
+		principal, err := verifier.Verify(signToken(t, claims, nil, key))
+		require.NoError(t, err)
+		assert.Equal(t, "alice", principal.Subject)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		with := func(name string, value interface{}) map[string]interface{} {
+			claims := valid()
+			if value == nil {
+				delete(claims, name)
+			} else {
+				claims[name] = value
+			}
+			return claims
+		}
+
+		cases := map[string]string{
+			"wrong secret":    signToken(t, valid(), []byte("another secret that is long enough"), nil),
+			"wrong key":       signToken(t, valid(), nil, otherKey),
+			"expired":         signToken(t, with("exp", time.Now().Add(-time.Hour).Unix()), []byte(testHMACSecret), nil),
+			"not yet valid":   signToken(t, with("nbf", time.Now().Add(time.Hour).Unix()), []byte(testHMACSecret), nil),
+			"no exp":          signToken(t, with("exp", nil), []byte(testHMACSecret), nil),
+			"no sub":          signToken(t, with("sub", nil), []byte(testHMACSecret), nil),
+			"other issuer":    signToken(t, with("iss", "https://evil.example.com"), []byte(testHMACSecret), nil),
+			"other audience":  signToken(t, with("aud", "another_app"), []byte(testHMACSecret), nil),
+			"malformed":       "not.a.token",
+			"unsigned":        base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"alice"}`)) + ".",
+			"too many groups": signToken(t, valid(), []byte(testHMACSecret), nil) + ".extra",
+		}
+		for name, token := range cases {
+			_, err := verifier.Verify(token)
+			assert.ErrorIs(t, err, auth.ErrInvalidToken, name)
+		}
+	})
+
+	t.Run("Algorithm Needs Its Key", func(t *testing.T) {
+		hmacOnly, err := auth.NewVerifier(auth.Options{HMACSecret: []byte(testHMACSecret)})
+		require.NoError(t, err)
+		_, err = hmacOnly.Verify(signToken(t, valid(), nil, key))
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+
+		// The public key is no HS256 secret
+		rsaOnly, err := auth.NewVerifier(auth.Options{RSAPublicKey: publicKey})
+		require.NoError(t, err)
+		pemKey, err := os.ReadFile(keyFile)
+		require.NoError(t, err)
+		_, err = rsaOnly.Verify(signToken(t, valid(), pemKey, nil))
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+
+		_, err = auth.NewVerifier(auth.Options{})
+		assert.Error(t, err)
+	})
+}
+
+func TestAccountOwnership(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	verifier, err := auth.NewVerifier(auth.Options{HMACSecret: []byte(testHMACSecret)})
+	require.NoError(t, err)
+
+	ts.CleanupCollections(t, "accounts", "transactions", "journal_entries", "idempotency_keys")
+	ts.Handler.Auth = verifier
+	router := chi.NewRouter()
+	routes.Routes(router, ts.Handler, config.Default().Server)
+
+	alice := userToken(t, "alice")
+	bob := userToken(t, "bob")
+	admin := userToken(t, "ops", auth.RoleAdmin)
+
+	send := func(method, path, token string, body interface{}, headers ...string) (*httptest.ResponseRecorder, types.APIResponse) {
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		req := httptest.NewRequest(method, path, &payload)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response types.APIResponse
+		if w.Header().Get("Content-Type") == "application/json" {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		}
+		return w, response
+	}
+
+	createAccount := func(token, name, email string) string {
+		w, response := send("POST", "/api/v1/accounts", token, map[string]interface{}{
+			"name": name, "email": email,
+		})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		id := response.Data.(map[string]interface{})["id"].(string)
+
+		// Customers open at zero and fund the account with a deposit
+		w, _ = send("POST", "/api/v1/transactions", token, map[string]interface{}{
+			"transactionType": "DEPOSIT", "amount": "100.00", "accountId": id,
+		})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		return id
+	}
+
+	aliceAccount := createAccount(alice, "Alice", "alice@example.com")
+	bobAccount := createAccount(bob, "Bob", "bob@example.com")
+
+	t.Run("Token Required", func(t *testing.T) {
+		w, _ := send("GET", "/api/v1/accounts", "", nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+
+		w, _ = send("GET", "/api/v1/accounts", "garbage", nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), "invalid_token")
+
+		// Health checks stay open
+		w, _ = send("GET", "/api/v1/health", "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w, _ = send("GET", "/livez", "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Owners See Their Accounts", func(t *testing.T) {
+		_, response := send("GET", "/api/v1/accounts", alice, nil)
+		accounts := response.Data.([]interface{})
+		require.Len(t, accounts, 1)
+		assert.Equal(t, aliceAccount, accounts[0].(map[string]interface{})["id"])
+		assert.Equal(t, "alice", accounts[0].(map[string]interface{})["owner"])
+
+		w, _ := send("GET", "/api/v1/accounts/"+aliceAccount, alice, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// Someone else's account looks like a missing one
+		w, _ = send("GET", "/api/v1/accounts/"+bobAccount, alice, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w, _ = send("PATCH", "/api/v1/accounts/"+bobAccount, alice, map[string]string{"name": "Mallory"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w, _ = send("POST", "/api/v1/accounts/"+bobAccount+"/freeze", alice, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w, _ = send("GET", "/api/v1/transactions/account/"+bobAccount, alice, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Admins See Everything", func(t *testing.T) {
+		_, response := send("GET", "/api/v1/accounts", admin, nil)
+		assert.Len(t, response.Data, 2)
+
+		w, _ := send("GET", "/api/v1/accounts/"+bobAccount, admin, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w, _ = send("GET", "/api/v1/ledger/invariants", admin, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w, _ = send("GET", "/api/v1/ledger/invariants", alice, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Money Moves Only From Owned Accounts", func(t *testing.T) {
+		w, response := send("POST", "/api/v1/transactions", alice, map[string]interface{}{
+			"transactionType": "WITHDRAW", "amount": "10.00", "accountId": bobAccount,
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "account not found", response.Error)
+
+		// Paying into someone else's account is fine
+		w, response = send("POST", "/api/v1/transactions", alice, map[string]interface{}{
+			"transactionType": "TRANSFER", "amount": "25.00", "accountId": aliceAccount, "toAccountId": bobAccount,
+		})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		transactions := response.Data.(map[string]interface{})["transactions"].([]interface{})
+		outgoing := transactions[0].(map[string]interface{})["id"].(string)
+
+		w, _ = send("GET", "/api/v1/transactions/"+outgoing, alice, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w, _ = send("GET", "/api/v1/transactions/"+outgoing, bob, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		// Bob sees his deposit and only the incoming side of the transfer
+		_, response = send("GET", "/api/v1/transactions", bob, nil)
+		listed := response.Data.([]interface{})
+		require.Len(t, listed, 2)
+		assert.Equal(t, "IN", listed[0].(map[string]interface{})["direction"])
+
+		_, response = send("GET", "/api/v1/transactions", admin, nil)
+		assert.Len(t, response.Data, 4)
+
+		// Without accounts there is nothing to list
+		_, response = send("GET", "/api/v1/transactions", userToken(t, "carol"), nil)
+		assert.Empty(t, response.Data)
+	})
+
+	t.Run("Idempotency Keys Are Per Caller", func(t *testing.T) {
+		body := map[string]interface{}{"transactionType": "DEPOSIT", "amount": "5.00"}
+
+		body["accountId"] = aliceAccount
+		w, _ := send("POST", "/api/v1/transactions", alice, body, "Idempotency-Key", "shared-key")
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		// The same key from another caller is not a replay of Alice's request
+		body["accountId"] = bobAccount
+		w, _ = send("POST", "/api/v1/transactions", bob, body, "Idempotency-Key", "shared-key")
+		require.Equal(t, http.StatusCreated, w.Code)
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("Only Staff Open Accounts With Money", func(t *testing.T) {
+		carol := userToken(t, "carol")
+		w, _ := send("POST", "/api/v1/accounts", carol, map[string]interface{}{
+			"name": "Carol", "email": "carol@example.com", "initialBalance": "1000000.00",
+		})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w, _ = send("POST", "/api/v1/accounts", carol, map[string]interface{}{
+			"name": "Carol", "email": "carol@example.com", "initialBalance": "-50.00",
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, response := send("POST", "/api/v1/accounts", admin, map[string]interface{}{
+			"name": "Carol", "email": "carol@example.com", "initialBalance": "50.00",
+		})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, 50.0, response.Data.(map[string]interface{})["balance"])
+	})
+}
+
+// TestInitialBalanceNeedsStaff checks customers open accounts at zero and fund
+// them with a deposit, while staff and API keys may set a balance
+func TestInitialBalanceNeedsStaff(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	verifier, err := auth.NewVerifier(auth.Options{HMACSecret: []byte(testHMACSecret)})
+	require.NoError(t, err)
+
+	ts.CleanupCollections(t, "accounts", "transactions", "journal_entries", "idempotency_keys", "api_keys")
+	ts.Handler.Auth = verifier
+	router := chi.NewRouter()
+	routes.Routes(router, ts.Handler, config.Default().Server)
+
+	send := func(path string, body interface{}, header, credential string) (*httptest.ResponseRecorder, types.APIResponse) {
+		var payload bytes.Buffer
+		require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		req := httptest.NewRequest("POST", path, &payload)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(header, credential)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response types.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w, response
+	}
+
+	admin := "Bearer " + userToken(t, "ops", auth.RoleAdmin)
+	w, response := send("/api/v1/api-keys", map[string]interface{}{
+		"name": "onboarding", "scopes": []string{auth.ScopeAdmin},
+	}, "Authorization", admin)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	apiKey := response.Data.(map[string]interface{})["key"].(string)
+
+	funded := func(name, email string) map[string]interface{} {
+		return map[string]interface{}{"name": name, "email": email, "initialBalance": "250.00"}
+	}
+
+	t.Run("Customers Get 403", func(t *testing.T) {
+		carol := "Bearer " + userToken(t, "carol")
+		w, response := send("/api/v1/accounts", funded("Carol", "carol@example.com"), "Authorization", carol)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, "Only staff may open an account with an initial balance", response.Error)
+
+		// Opening at zero is still open to customers
+		w, response = send("/api/v1/accounts", map[string]interface{}{
+			"name": "Carol", "email": "carol@example.com",
+		}, "Authorization", carol)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, 0.0, response.Data.(map[string]interface{})["balance"])
+	})
+
+	t.Run("Admins Get 201", func(t *testing.T) {
+		w, response := send("/api/v1/accounts", funded("Dave", "dave@example.com"), "Authorization", admin)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, 250.0, response.Data.(map[string]interface{})["balance"])
+	})
+
+	t.Run("API Keys Get 201", func(t *testing.T) {
+		w, response := send("/api/v1/accounts", funded("Erin", "erin@example.com"), "X-API-Key", apiKey)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, 250.0, response.Data.(map[string]interface{})["balance"])
+	})
+}
+
+func TestStaffRoles(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	verifier, err := auth.NewVerifier(auth.Options{HMACSecret: []byte(testHMACSecret)})
+	require.NoError(t, err)
+
+	ts.CleanupCollections(t, "accounts", "transactions", "journal_entries", "idempotency_keys")
+	ts.Handler.Auth = verifier
+	router := chi.NewRouter()
+	routes.Routes(router, ts.Handler, config.Default().Server)
+
+	var audited bytes.Buffer
+	auditLogger := logrus.New()
+	auditLogger.SetFormatter(&logrus.JSONFormatter{})
+	auditLogger.SetOutput(&audited)
+	defer func(previous *logrus.Logger) { audit.Logger = previous }(audit.Logger)
+	audit.Logger = auditLogger
+
+	alice := userToken(t, "alice")
+	agent := userToken(t, "agent-7", auth.RoleSupport)
+	operator := userToken(t, "operator-3", auth.RoleOperator)
+
+	send := func(method, path, token string, body interface{}) (*httptest.ResponseRecorder, types.APIResponse) {
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		req := httptest.NewRequest(method, path, &payload)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response types.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w, response
+	}
+
+	w, response := send("POST", "/api/v1/accounts", alice, map[string]interface{}{
+		"name": "Alice", "email": "alice@example.com",
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	accountID := response.Data.(map[string]interface{})["id"].(string)
+	w, _ = send("POST", "/api/v1/transactions", alice, map[string]interface{}{
+		"transactionType": "DEPOSIT", "amount": "100.00", "accountId": accountID,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	deposit := map[string]interface{}{"transactionType": "DEPOSIT", "amount": "5.00", "accountId": accountID}
+
+	t.Run("Support Views But Cannot Move Money", func(t *testing.T) {
+		_, response := send("GET", "/api/v1/accounts", agent, nil)
+		assert.Len(t, response.Data, 1)
+		w, _ := send("GET", "/api/v1/accounts/"+accountID, agent, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w, _ = send("GET", "/api/v1/transactions/account/"+accountID, agent, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		audited.Reset()
+		w, response = send("POST", "/api/v1/transactions", agent, deposit)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, "Requires the transactions:create permission", response.Error)
+
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(audited.Bytes(), &entry))
+		assert.Equal(t, "access_denied", entry["audit"])
+		assert.Equal(t, "agent-7", entry["actor"])
+		assert.Equal(t, "transactions:create", entry["permission"])
+		assert.Equal(t, "/api/v1/transactions", entry["path"])
+
+		for _, path := range []string{"/freeze", "/close"} {
+			w, _ = send("POST", "/api/v1/accounts/"+accountID+path, agent, nil)
+			assert.Equal(t, http.StatusForbidden, w.Code, path)
+		}
+		w, _ = send("POST", "/api/v1/accounts", agent, map[string]interface{}{
+			"name": "Agent", "email": "agent@example.com",
+		})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Operators Freeze Accounts", func(t *testing.T) {
+		w, response := send("POST", "/api/v1/accounts/"+accountID+"/freeze", operator, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "FROZEN", response.Data.(map[string]interface{})["status"])
+		w, _ = send("POST", "/api/v1/accounts/"+accountID+"/unfreeze", operator, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w, _ = send("POST", "/api/v1/accounts/"+accountID+"/close", operator, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w, _ = send("PATCH", "/api/v1/accounts/"+accountID, operator, map[string]string{"name": "Mallory"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w, _ = send("POST", "/api/v1/transactions", operator, deposit)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w, _ = send("GET", "/api/v1/ledger/invariants", operator, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Only Staff Unfreeze Accounts", func(t *testing.T) {
+		w, _ := send("POST", "/api/v1/accounts/"+accountID+"/freeze", operator, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		// The owner cannot lift a freeze put on by an operator
+		w, response := send("POST", "/api/v1/accounts/"+accountID+"/unfreeze", alice, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, "Requires the accounts:unfreeze permission", response.Error)
+		_, response = send("GET", "/api/v1/accounts/"+accountID, alice, nil)
+		assert.Equal(t, "FROZEN", response.Data.(map[string]interface{})["status"])
+
+		w, _ = send("POST", "/api/v1/accounts/"+accountID+"/unfreeze", operator, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// Owners may still freeze their own account, but not undo it
+		w, _ = send("POST", "/api/v1/accounts/"+accountID+"/freeze", alice, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w, _ = send("POST", "/api/v1/accounts/"+accountID+"/unfreeze", alice, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w, _ = send("POST", "/api/v1/accounts/"+accountID+"/unfreeze", operator, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Handlers Check Permissions Too", func(t *testing.T) {
+		// Called without the route middleware
+		var payload bytes.Buffer
+		require.NoError(t, json.NewEncoder(&payload).Encode(deposit))
+		req := httptest.NewRequest("POST", "/api/v1/transactions", &payload)
+		principal := auth.Principal{Subject: "agent-7", Roles: []string{auth.RoleSupport}}
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		audited.Reset()
+		w := httptest.NewRecorder()
+		ts.Handler.TransactionService.CreateTransaction(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, audited.String(), `"audit":"access_denied"`)
+	})
+}
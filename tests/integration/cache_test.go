@@ -0,0 +1,189 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"finance_app/src/cache"
+	"finance_app/src/config"
+	"finance_app/src/handlers"
+	"finance_app/src/metrics"
+	"finance_app/src/models"
+	"finance_app/src/routes"
+	"finance_app/src/utils/types"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	lru := cache.NewLRU(2)
+
+	require.NoError(t, lru.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, lru.Set(ctx, "b", []byte("2"), time.Minute))
+	_, ok, _ := lru.Get(ctx, "a")
+	assert.True(t, ok)
+
+	// b is now the least recently used
+	require.NoError(t, lru.Set(ctx, "c", []byte("3"), time.Minute))
+	_, ok, _ = lru.Get(ctx, "b")
+	assert.False(t, ok)
+	value, ok, _ := lru.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(value))
+	assert.Equal(t, 2, lru.Len())
+
+	require.NoError(t, lru.Delete(ctx, "a", "missing"))
+	_, ok, _ = lru.Get(ctx, "a")
+	assert.False(t, ok)
+
+	require.NoError(t, lru.Set(ctx, "short", []byte("4"), 20*time.Millisecond))
+	time.Sleep(30 * time.Millisecond)
+	_, ok, _ = lru.Get(ctx, "short")
+	assert.False(t, ok)
+}
+
+func TestRedisCache(t *testing.T) {
+	ctx := context.Background()
+	store := cache.NewRedis(testRedis(t))
+	store.Prefix = "test:cache:" + strconv.FormatInt(time.Now().UnixNano(), 10) + ":"
+
+	_, ok, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, store.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, store.Set(ctx, "b", []byte("2"), time.Minute))
+	value, ok, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(value))
+
+	require.NoError(t, store.Delete(ctx, "a", "b", "missing"))
+	_, ok, err = store.Get(ctx, "b")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestAccountCache(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	// Cleanup replaces the in-memory repositories, so wrap them afterwards
+	ts.CleanupCollections(t, "accounts", "transactions", "journal_entries", "idempotency_keys")
+	store := cache.NewLRU(100)
+	cached := cache.CacheAccounts(ts.AccountsRepository, store, time.Minute)
+	transactor := cached.Transactor(ts.Transactor)
+	h := handlers.NewAppHandler(ts.Client, transactor, ts.TransactionRepository, cached,
+		ts.Handler.IdempotencyRepository, ts.LedgerRepository, ts.Handler.APIKeyRepository, ts.Handler.AuditRepository)
+	router := chi.NewRouter()
+	routes.Routes(router, h, config.Default().Server)
+
+	send := func(method, path string, body interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		req := httptest.NewRequest(method, path, &payload)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response types.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		data, _ := response.Data.(map[string]interface{})
+		return w, data
+	}
+
+	hits := func() float64 {
+		return testutil.ToFloat64(metrics.CacheRequests.WithLabelValues("accounts", metrics.CacheHit))
+	}
+	misses := func() float64 {
+		return testutil.ToFloat64(metrics.CacheRequests.WithLabelValues("accounts", metrics.CacheMiss))
+	}
+
+	w, account := send("POST", "/api/v1/accounts", map[string]interface{}{
+		"name": "Alice", "email": "alice@example.com", "initialBalance": "100.00",
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	id := account["id"].(string)
+	path := "/api/v1/accounts/" + id
+
+	t.Run("Read Through", func(t *testing.T) {
+		hitsBefore, missesBefore := hits(), misses()
+
+		w, _ := send("GET", path, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		w, account := send("GET", path, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Alice", account["name"])
+		assert.Equal(t, 100.0, account["balance"])
+
+		assert.Equal(t, missesBefore+1, misses())
+		assert.Equal(t, hitsBefore+1, hits())
+	})
+
+	t.Run("Money Moves Bypass The Cache", func(t *testing.T) {
+		hitsBefore, missesBefore := hits(), misses()
+
+		w, _ := send("POST", "/api/v1/transactions", map[string]interface{}{
+			"transactionType": "DEPOSIT", "amount": "25.00", "accountId": id,
+		})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, hitsBefore, hits())
+		assert.Equal(t, missesBefore, misses())
+
+		// The deposit dropped the cached balance
+		_, account := send("GET", path, nil)
+		assert.Equal(t, 125.0, account["balance"])
+		assert.Equal(t, missesBefore+1, misses())
+	})
+
+	t.Run("Updates Invalidate", func(t *testing.T) {
+		send("GET", path, nil)
+
+		w, _ := send("PATCH", path, map[string]string{"name": "Alice Smith"})
+		require.Equal(t, http.StatusOK, w.Code)
+		_, account := send("GET", path, nil)
+		assert.Equal(t, "Alice Smith", account["name"])
+
+		w, _ = send("POST", path+"/freeze", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		_, account = send("GET", path, nil)
+		assert.Equal(t, string(models.AccountFrozen), account["status"])
+
+		w, _ = send("POST", path+"/unfreeze", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Entries Dropped After Commit", func(t *testing.T) {
+		ctx := context.Background()
+		key := "account:" + id
+
+		err := transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			if _, err := cached.Credit(ctx, id, models.MustParseMoney("1.00")); err != nil {
+				return err
+			}
+			// As a concurrent reader would, cache the balance from before
+			// the commit
+			return store.Set(context.Background(), key, []byte("stale"), time.Minute)
+		})
+		require.NoError(t, err)
+
+		_, ok, _ := store.Get(ctx, key)
+		assert.False(t, ok)
+	})
+}
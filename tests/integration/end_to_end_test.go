@@ -15,8 +15,8 @@ import (
 )
 
 func TestEndToEndWorkflow(t *testing.T) {
-	// Skip if MongoDB is not available
-	SkipIfNoMongo(t)
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
 
 	// Setup test suite
 	ts := SetupTestSuite(t)
@@ -1,19 +1,23 @@
 package integration
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"finance_app/src/services"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestHealthEndpoint(t *testing.T) {
-	// Skip if MongoDB is not available
-	SkipIfNoMongo(t)
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
 
 	// Setup test suite
 	ts := SetupTestSuite(t)
@@ -48,4 +52,154 @@ func TestHealthEndpoint(t *testing.T) {
 	})
 }
 
+func TestGracefulDrain(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	lifecycle := ts.Handler.Lifecycle
+
+	t.Run("Waits For In-Flight Requests", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		handler := lifecycle.Track(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		}))
+
+		go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil))
+		<-started
+
+		done := make(chan struct{})
+		go func() {
+			lifecycle.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("Wait returned while a request was in flight")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Wait did not return after the request finished")
+		}
+	})
+
+	t.Run("Health Fails While Draining", func(t *testing.T) {
+		lifecycle.StartDraining()
 
+		req := httptest.NewRequest("GET", "/api/v1/health", nil)
+		w := httptest.NewRecorder()
+		ts.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "draining", response["status"])
+
+		req = httptest.NewRequest("GET", "/readyz", nil)
+		w = httptest.NewRecorder()
+		ts.Router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		// Liveness is unaffected, the process is still healthy
+		req = httptest.NewRequest("GET", "/livez", nil)
+		w = httptest.NewRecorder()
+		ts.Router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestProbes(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	health := ts.Handler.HealthService
+	backendChecks := health.Checks
+
+	probe := func(t *testing.T, path string) (int, services.HealthReport) {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		ts.Router.ServeHTTP(w, req)
+
+		var report services.HealthReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return w.Code, report
+	}
+
+	t.Run("Liveness", func(t *testing.T) {
+		code, report := probe(t, "/livez")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "alive", report.Status)
+		assert.NotEmpty(t, report.Version)
+		assert.Empty(t, report.Dependencies)
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		code, report := probe(t, "/readyz")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ready", report.Status)
+		assert.NotEmpty(t, report.Version)
+		for name, dependency := range report.Dependencies {
+			assert.Equal(t, "up", dependency.Status, name)
+		}
+		if ts.Client != nil {
+			assert.Contains(t, report.Dependencies, "mongo")
+		}
+	})
+
+	t.Run("Required Dependency Down", func(t *testing.T) {
+		health.Checks = append(backendChecks,
+			services.HealthCheck{Name: "cache", Check: func(context.Context) error { return errors.New("connection refused") }},
+			services.HealthCheck{Name: "database", Required: true, Check: func(ctx context.Context) error {
+				// Hangs until the check times out
+				<-ctx.Done()
+				return ctx.Err()
+			}},
+		)
+		health.Timeout = 50 * time.Millisecond
+		defer func() {
+			health.Checks = backendChecks
+			health.Timeout = 2 * time.Second
+		}()
+
+		code, report := probe(t, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "not_ready", report.Status)
+
+		database := report.Dependencies["database"]
+		assert.Equal(t, "down", database.Status)
+		assert.True(t, database.Required)
+		assert.NotEmpty(t, database.Error)
+		assert.GreaterOrEqual(t, database.LatencyMs, 50.0)
+
+		cache := report.Dependencies["cache"]
+		assert.Equal(t, "down", cache.Status)
+		assert.Equal(t, "connection refused", cache.Error)
+	})
+
+	t.Run("Optional Dependency Down", func(t *testing.T) {
+		health.Checks = append(backendChecks,
+			services.HealthCheck{Name: "cache", Check: func(context.Context) error { return errors.New("connection refused") }},
+		)
+		defer func() { health.Checks = backendChecks }()
+
+		code, report := probe(t, "/readyz")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ready", report.Status)
+		assert.Equal(t, "down", report.Dependencies["cache"].Status)
+	})
+}
@@ -0,0 +1,185 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"finance_app/src/models"
+	"finance_app/src/repositories"
+	"finance_app/src/routes"
+	"finance_app/src/utils/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyIntegration(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	// Helper function to POST a JSON body with an optional Idempotency-Key
+	post := func(path, key string, body map[string]interface{}) *httptest.ResponseRecorder {
+		jsonData, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", path, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		ts.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Retried Deposit Is Applied Once", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions", "idempotency_keys")
+
+		account := &models.Accounts{Name: "John Doe", Email: "john@example.com", Balance: models.MustParseMoney("1000.00")}
+		require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+
+		deposit := map[string]interface{}{
+			"transactionType": "DEPOSIT",
+			"amount":          500.0,
+			"accountId":       account.ID.Hex(),
+		}
+
+		first := post("/api/v1/transactions", "deposit-1", deposit)
+		assert.Equal(t, http.StatusCreated, first.Code)
+
+		// The retry gets the original response back
+		retry := post("/api/v1/transactions", "deposit-1", deposit)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+		assert.JSONEq(t, first.Body.String(), retry.Body.String())
+
+		// And the balance only moved once
+		updated, err := ts.AccountsRepository.FindOne(context.Background(), account.ID.Hex())
+		require.NoError(t, err)
+		assert.Equal(t, models.MustParseMoney("1500.00"), updated.Balance)
+
+		history, _, err := ts.TransactionRepository.GetByAccountID(context.Background(), account.ID.Hex(), repositories.TransactionQuery{})
+		require.NoError(t, err)
+		assert.Len(t, history, 1)
+	})
+
+	t.Run("Reused Key With Different Body", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions", "idempotency_keys")
+
+		account := &models.Accounts{Name: "John Doe", Email: "john@example.com", Balance: models.MustParseMoney("1000.00")}
+		require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+
+		first := post("/api/v1/transactions", "deposit-2", map[string]interface{}{
+			"transactionType": "DEPOSIT",
+			"amount":          500.0,
+			"accountId":       account.ID.Hex(),
+		})
+		assert.Equal(t, http.StatusCreated, first.Code)
+
+		conflict := post("/api/v1/transactions", "deposit-2", map[string]interface{}{
+			"transactionType": "DEPOSIT",
+			"amount":          600.0,
+			"accountId":       account.ID.Hex(),
+		})
+		assert.Equal(t, http.StatusConflict, conflict.Code)
+
+		var response types.APIResponse
+		require.NoError(t, json.Unmarshal(conflict.Body.Bytes(), &response))
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "already used with a different request")
+	})
+
+	t.Run("Retried Account Creation", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "idempotency_keys")
+
+		accountData := map[string]interface{}{
+			"name":           "Jane Smith",
+			"email":          "jane@example.com",
+			"initialBalance": 100.0,
+		}
+
+		first := post("/api/v1/accounts", "create-jane", accountData)
+		assert.Equal(t, http.StatusCreated, first.Code)
+
+		// Without the key this would fail with a duplicate email
+		retry := post("/api/v1/accounts", "create-jane", accountData)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.JSONEq(t, first.Body.String(), retry.Body.String())
+
+		// The same key on another endpoint is independent
+		other := post("/api/v1/transactions", "create-jane", map[string]interface{}{
+			"transactionType": "DEPOSIT",
+		})
+		assert.Equal(t, http.StatusBadRequest, other.Code)
+	})
+
+	t.Run("Oversized Body Is Rejected Without Reserving The Key", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions", "idempotency_keys")
+
+		account := &models.Accounts{Name: "John Doe", Email: "john@example.com", Balance: models.MustParseMoney("1000.00")}
+		require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+
+		oversized := post("/api/v1/transactions", "deposit-big", map[string]interface{}{
+			"transactionType": "DEPOSIT",
+			"amount":          500.0,
+			"accountId":       account.ID.Hex(),
+			"padding":         strings.Repeat("x", 1<<20),
+		})
+		assert.Equal(t, http.StatusRequestEntityTooLarge, oversized.Code)
+
+		// The key is still free for a request that fits
+		deposit := post("/api/v1/transactions", "deposit-big", map[string]interface{}{
+			"transactionType": "DEPOSIT",
+			"amount":          500.0,
+			"accountId":       account.ID.Hex(),
+		})
+		assert.Equal(t, http.StatusCreated, deposit.Code)
+		assert.Empty(t, deposit.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("Panicking Handler Releases The Key", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "idempotency_keys")
+
+		calls := 0
+		handler := routes.Idempotency(ts.Handler.IdempotencyRepository)(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls == 1 {
+					panic("handler failed")
+				}
+				w.WriteHeader(http.StatusCreated)
+			}),
+		)
+
+		send := func() *httptest.ResponseRecorder {
+			req := httptest.NewRequest("POST", "/api/v1/transactions", bytes.NewBufferString(`{}`))
+			req.Header.Set("Idempotency-Key", "panics-once")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w
+		}
+
+		// The panic still reaches Recoverer
+		assert.PanicsWithValue(t, "handler failed", func() { send() })
+
+		// The retry runs the handler instead of being told it is in progress
+		retry := send()
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, 2, calls)
+	})
+}
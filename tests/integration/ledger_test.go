@@ -0,0 +1,158 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"finance_app/src/models"
+	"finance_app/src/services"
+	"finance_app/src/utils/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedgerIntegration(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	// Helper function to POST a JSON body and return the response data
+	post := func(path string, body map[string]interface{}) map[string]interface{} {
+		jsonData, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", path, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.Router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		var response types.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		data, ok := response.Data.(map[string]interface{})
+		require.True(t, ok)
+		return data
+	}
+
+	// Helper function to run the invariant check over HTTP
+	checkInvariants := func() (int, map[string]interface{}) {
+		req := httptest.NewRequest("GET", "/api/v1/ledger/invariants", nil)
+		w := httptest.NewRecorder()
+		ts.Router.ServeHTTP(w, req)
+
+		var response types.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		report, ok := response.Data.(map[string]interface{})
+		require.True(t, ok)
+		return w.Code, report
+	}
+
+	t.Run("Every Movement Is Posted", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions", "journal_entries")
+
+		alice := post("/api/v1/accounts", map[string]interface{}{
+			"name": "Alice", "email": "alice@example.com", "initialBalance": 1000.0,
+		})["id"].(string)
+		bob := post("/api/v1/accounts", map[string]interface{}{
+			"name": "Bob", "email": "bob@example.com", "initialBalance": 0,
+		})["id"].(string)
+
+		post("/api/v1/transactions", map[string]interface{}{"transactionType": "DEPOSIT", "amount": 250.5, "accountId": alice})
+		post("/api/v1/transactions", map[string]interface{}{"transactionType": "WITHDRAW", "amount": 100.0, "accountId": alice})
+		post("/api/v1/transactions", map[string]interface{}{"transactionType": "TRANSFER", "amount": 300.0, "accountId": alice, "toAccountId": bob})
+
+		// Balances can be derived from the postings alone
+		for _, id := range []string{alice, bob} {
+			account, err := ts.AccountsRepository.FindOne(context.Background(), id)
+			require.NoError(t, err)
+			ledgerBalance, err := ts.LedgerRepository.Balance(context.Background(), id)
+			require.NoError(t, err)
+			assert.Equal(t, account.Balance, ledgerBalance)
+		}
+
+		status, report := checkInvariants()
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, true, report["holds"])
+		assert.Equal(t, 4.0, report["entries"]) // opening balance, deposit, withdrawal, transfer
+		assert.Equal(t, 1150.5, report["totalAccountBalances"])
+		assert.Equal(t, 1250.5, report["externalInflow"])
+		assert.Equal(t, 100.0, report["externalOutflow"])
+		assert.Equal(t, 1150.5, report["netExternalFlow"])
+	})
+
+	t.Run("Rejected Transaction Posts Nothing", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions", "journal_entries")
+
+		alice := post("/api/v1/accounts", map[string]interface{}{
+			"name": "Alice", "email": "alice@example.com", "initialBalance": 50.0,
+		})["id"].(string)
+
+		jsonData, err := json.Marshal(map[string]interface{}{"transactionType": "WITHDRAW", "amount": 75.0, "accountId": alice})
+		require.NoError(t, err)
+		req := httptest.NewRequest("POST", "/api/v1/transactions", bytes.NewBuffer(jsonData))
+		w := httptest.NewRecorder()
+		ts.Router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		status, report := checkInvariants()
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, 1.0, report["entries"])
+	})
+
+	t.Run("Unbalanced Entry Is Rejected", func(t *testing.T) {
+		entry := &models.JournalEntry{
+			Description: "broken",
+			Legs: []models.LedgerLeg{
+				{Account: models.LedgerCashIn, Amount: models.MustParseMoney("-10.00")},
+				{Account: "000000000000000000000001", Amount: models.MustParseMoney("9.99")},
+			},
+		}
+		err := ts.LedgerRepository.Post(context.Background(), entry)
+		assert.ErrorContains(t, err, "unbalanced")
+	})
+
+	t.Run("Balances Without Postings Are Reported And Backfilled", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions", "journal_entries")
+
+		// Written straight to the repository, as accounts were before the journal
+		legacy := &models.Accounts{Name: "Legacy", Email: "legacy@example.com", Balance: models.MustParseMoney("80.00")}
+		require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), legacy))
+
+		status, report := checkInvariants()
+		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.Equal(t, false, report["holds"])
+		mismatched, ok := report["mismatchedAccounts"].([]interface{})
+		require.True(t, ok)
+		require.Len(t, mismatched, 1)
+		assert.Equal(t, legacy.ID.Hex(), mismatched[0].(map[string]interface{})["accountId"])
+
+		ledger := &services.LedgerHandler{
+			LedgerRepo:   ts.LedgerRepository,
+			AccountsRepo: ts.AccountsRepository,
+			Transactor:   ts.Transactor,
+		}
+		backfill, err := ledger.BackfillOpeningBalances(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, backfill.Posted)
+
+		status, report = checkInvariants()
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, true, report["holds"])
+
+		// Running it again posts nothing
+		backfill, err = ledger.BackfillOpeningBalances(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 0, backfill.Posted)
+	})
+}
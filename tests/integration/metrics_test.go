@@ -0,0 +1,209 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"finance_app/src/auth"
+	"finance_app/src/config"
+	"finance_app/src/metrics"
+	"finance_app/src/models"
+	"finance_app/src/repositories"
+	"finance_app/src/routes"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsIntegration(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	post := func(t *testing.T, body map[string]interface{}) int {
+		jsonData, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/api/v1/transactions", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.Router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	scrape := func(t *testing.T) string {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		w := httptest.NewRecorder()
+		metrics.Handler().ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+		return w.Body.String()
+	}
+
+	t.Run("Transaction Outcomes", func(t *testing.T) {
+		ts.CleanupCollections(t, "accounts", "transactions")
+
+		account := &models.Accounts{Name: "Metered", Email: "metered@example.com"}
+		require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+
+		created := testutil.ToFloat64(metrics.Transactions.WithLabelValues("DEPOSIT", metrics.OutcomeCreated))
+		rejected := testutil.ToFloat64(metrics.Transactions.WithLabelValues("WITHDRAW", metrics.OutcomeRejected))
+		insufficient := testutil.ToFloat64(metrics.RejectedWithdrawals.WithLabelValues("WITHDRAW", metrics.ReasonInsufficientFunds))
+		invalid := testutil.ToFloat64(metrics.Transactions.WithLabelValues("INVALID", metrics.OutcomeRejected))
+
+		assert.Equal(t, http.StatusCreated, post(t, map[string]interface{}{
+			"transactionType": "deposit", "amount": "10.00", "accountId": account.ID.Hex(),
+		}))
+		assert.Equal(t, http.StatusBadRequest, post(t, map[string]interface{}{
+			"transactionType": "WITHDRAW", "amount": "25.00", "accountId": account.ID.Hex(),
+		}))
+		assert.Equal(t, http.StatusBadRequest, post(t, map[string]interface{}{
+			"transactionType": "REFUND", "amount": "1.00", "accountId": account.ID.Hex(),
+		}))
+
+		assert.Equal(t, created+1, testutil.ToFloat64(metrics.Transactions.WithLabelValues("DEPOSIT", metrics.OutcomeCreated)))
+		assert.Equal(t, rejected+1, testutil.ToFloat64(metrics.Transactions.WithLabelValues("WITHDRAW", metrics.OutcomeRejected)))
+		assert.Equal(t, insufficient+1, testutil.ToFloat64(metrics.RejectedWithdrawals.WithLabelValues("WITHDRAW", metrics.ReasonInsufficientFunds)))
+		assert.Equal(t, invalid+1, testutil.ToFloat64(metrics.Transactions.WithLabelValues("INVALID", metrics.OutcomeRejected)))
+
+		// Frozen accounts reject withdrawals before any balance change
+		_, err := ts.AccountsRepository.UpdateStatus(context.Background(), account.ID.Hex(), models.AccountFrozen)
+		require.NoError(t, err)
+		blocked := testutil.ToFloat64(metrics.RejectedWithdrawals.WithLabelValues("WITHDRAW", metrics.ReasonAccountStatus))
+
+		assert.Equal(t, http.StatusConflict, post(t, map[string]interface{}{
+			"transactionType": "WITHDRAW", "amount": "1.00", "accountId": account.ID.Hex(),
+		}))
+		assert.Equal(t, blocked+1, testutil.ToFloat64(metrics.RejectedWithdrawals.WithLabelValues("WITHDRAW", metrics.ReasonAccountStatus)))
+	})
+
+	t.Run("Requests By Route Pattern", func(t *testing.T) {
+		ts.CleanupCollections(t, "accounts", "transactions")
+
+		account := &models.Accounts{Name: "Routed", Email: "routed@example.com"}
+		require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+
+		before := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "/api/v1/accounts/{id}", "200"))
+		latencies := histogramCount(t, metrics.HTTPRequestDuration, "GET", "/api/v1/accounts/{id}")
+
+		req := httptest.NewRequest("GET", "/api/v1/accounts/"+account.ID.Hex(), nil)
+		w := httptest.NewRecorder()
+		ts.Router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		assert.Equal(t, before+1, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("GET", "/api/v1/accounts/{id}", "200")))
+		assert.Equal(t, latencies+1, histogramCount(t, metrics.HTTPRequestDuration, "GET", "/api/v1/accounts/{id}"))
+
+		body := scrape(t)
+		assert.Contains(t, body, `finance_http_requests_total{method="GET",route="/api/v1/accounts/{id}",status="200"}`)
+		assert.NotContains(t, body, account.ID.Hex())
+	})
+
+	t.Run("Repository Latency", func(t *testing.T) {
+		accounts := metrics.InstrumentAccounts(ts.AccountsRepository, "test")
+		before := histogramCount(t, metrics.RepositoryDuration, "test", "accounts", "GetAllAccounts")
+
+		_, _, err := accounts.GetAllAccounts(context.Background(), repositories.PageRequest{})
+		require.NoError(t, err)
+
+		assert.Equal(t, before+1, histogramCount(t, metrics.RepositoryDuration, "test", "accounts", "GetAllAccounts"))
+		assert.Contains(t, scrape(t), `finance_repository_operation_duration_seconds_count{backend="test",method="GetAllAccounts",repository="accounts"}`)
+	})
+
+	t.Run("Balance Under Management", func(t *testing.T) {
+		ts.CleanupCollections(t, "accounts", "transactions")
+
+		for i, amount := range []string{"100.25", "49.75"} {
+			account := &models.Accounts{Name: "Holder", Email: "holder" + strconv.Itoa(i) + "@example.com"}
+			require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+			_, err := ts.AccountsRepository.Credit(context.Background(), account.ID.Hex(), models.MustParseMoney(amount))
+			require.NoError(t, err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var polls atomic.Int32
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			metrics.Poll(ctx, metrics.BalanceUnderManagement, time.Hour, func(ctx context.Context) (float64, error) {
+				polls.Add(1)
+				total, err := ts.Handler.AccountService.TotalBalance(ctx)
+				if err != nil {
+					return 0, err
+				}
+				return strconv.ParseFloat(total.String(), 64)
+			})
+		}()
+		defer func() { cancel(); <-done }()
+
+		require.Eventually(t, func() bool {
+			return strings.Contains(scrape(t), "finance_balance_under_management 150\n")
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// Scrapes read the last value instead of summing balances again
+		scrape(t)
+		assert.Equal(t, int32(1), polls.Load())
+	})
+
+	t.Run("Not Served On The API Port With An Admin Port", func(t *testing.T) {
+		server := config.Default().Server
+		server.MetricsPort = 9090
+		router := chi.NewRouter()
+		routes.Routes(router, ts.Handler, server)
+
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Admins Only On The API Port", func(t *testing.T) {
+		verifier, err := auth.NewVerifier(auth.Options{HMACSecret: []byte(testHMACSecret)})
+		require.NoError(t, err)
+		ts.Handler.Auth = verifier
+		defer func() { ts.Handler.Auth = nil }()
+
+		router := chi.NewRouter()
+		routes.Routes(router, ts.Handler, config.Default().Server)
+
+		get := func(token string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		assert.Equal(t, http.StatusUnauthorized, get("").Code)
+		assert.Equal(t, http.StatusForbidden, get(userToken(t, "alice")).Code)
+
+		w := get(userToken(t, "ops", auth.RoleAdmin))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "finance_http_requests_total")
+	})
+}
+
+// histogramCount returns how many values the series of h with the given
+// label values holds
+func histogramCount(t *testing.T, h *prometheus.HistogramVec, values ...string) uint64 {
+	var metric dto.Metric
+	require.NoError(t, h.WithLabelValues(values...).(prometheus.Metric).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
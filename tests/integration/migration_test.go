@@ -0,0 +1,101 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"finance_app/src/models"
+	"finance_app/src/repositories"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestMongoMigrationIntegration(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+	if loadTestConfig().Backend != backendMongo {
+		t.Skip("Skipping test: MongoDB migrations only apply to the mongo backend")
+	}
+
+	// Setup test suite, which applies the migrations
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	ctx := context.Background()
+
+	t.Run("Migrations Are Recorded", func(t *testing.T) {
+		count, err := ts.Database.Collection("schema_migrations").CountDocuments(ctx, bson.M{})
+		require.NoError(t, err)
+		assert.Positive(t, count)
+
+		// A second run finds nothing left to apply
+		require.NoError(t, repositories.MigrateMongo(ctx, ts.Database))
+		again, err := ts.Database.Collection("schema_migrations").CountDocuments(ctx, bson.M{})
+		require.NoError(t, err)
+		assert.Equal(t, count, again)
+	})
+
+	t.Run("Email Is Unique", func(t *testing.T) {
+		ts.CleanupCollections(t, "accounts")
+
+		first := &models.Accounts{Name: "First", Email: "unique@example.com"}
+		require.NoError(t, ts.AccountsRepository.CreateAccount(ctx, first))
+
+		// Bypass the repository's lookup, as a concurrent insert would
+		_, err := ts.Database.Collection("accounts").InsertOne(ctx, models.Accounts{
+			Name:      "Second",
+			Email:     "unique@example.com",
+			CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+		})
+		assert.True(t, mongo.IsDuplicateKeyError(err), "expected a duplicate key error, got %v", err)
+	})
+
+	t.Run("Duplicate Emails Are Reported Before Indexing", func(t *testing.T) {
+		// A database from before the index, where two accounts share an email
+		db := ts.Client.Database(ts.Database.Name() + "_duplicates")
+		defer db.Drop(ctx)
+
+		first, second := primitive.NewObjectID(), primitive.NewObjectID()
+		for _, id := range []primitive.ObjectID{first, second} {
+			_, err := db.Collection("accounts").InsertOne(ctx, models.Accounts{
+				ID:        id,
+				Name:      "Shared",
+				Email:     "shared@example.com",
+				CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+			})
+			require.NoError(t, err)
+		}
+
+		err := repositories.MigrateMongo(ctx, db)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "shared@example.com")
+		assert.Contains(t, err.Error(), first.Hex())
+		assert.Contains(t, err.Error(), second.Hex())
+
+		// Nothing is recorded, so the migration runs again once fixed
+		count, err := db.Collection("schema_migrations").CountDocuments(ctx, bson.M{})
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+
+	t.Run("Validators Reject Malformed Documents", func(t *testing.T) {
+		ts.CleanupCollections(t, "accounts", "transactions")
+
+		_, err := ts.Database.Collection("accounts").InsertOne(ctx, bson.M{"name": "No Email", "balance": "lots"})
+		assert.Error(t, err)
+
+		_, err = ts.Database.Collection("transactions").InsertOne(ctx, bson.M{
+			"transactionType": "REFUND",
+			"amount":          models.MustParseMoney("1.00"),
+			"balance":         models.MustParseMoney("1.00"),
+			"accountId":       primitive.NewObjectID(),
+			"created_at":      primitive.NewDateTimeFromTime(time.Now()),
+		})
+		assert.Error(t, err)
+	})
+}
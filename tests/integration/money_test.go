@@ -0,0 +1,176 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance_app/src/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMoney(t *testing.T) {
+	t.Run("Repeated Deposits Do Not Drift", func(t *testing.T) {
+		balance := models.MustParseMoney("0")
+		for i := 0; i < 10; i++ {
+			balance = balance.Add(models.MustParseMoney("0.1"))
+		}
+		balance = balance.Add(models.MustParseMoney("0.2"))
+
+		assert.Equal(t, models.MustParseMoney("1.20"), balance)
+		assert.Equal(t, "1.20", balance.String())
+	})
+
+	t.Run("JSON Round Trip", func(t *testing.T) {
+		var account models.Accounts
+		err := json.Unmarshal([]byte(`{"name":"John Doe","balance":1234567.89}`), &account)
+		require.NoError(t, err)
+		assert.Equal(t, int64(123456789), account.Balance.Minor())
+
+		data, err := json.Marshal(account)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"balance":1234567.89`)
+
+		// Quoted strings and exponent notation are accepted as well
+		var amounts struct {
+			Quoted   models.Money `json:"quoted"`
+			Exponent models.Money `json:"exponent"`
+		}
+		err = json.Unmarshal([]byte(`{"quoted":"-0.50","exponent":1.5e2}`), &amounts)
+		require.NoError(t, err)
+		assert.Equal(t, models.MustParseMoney("-0.50"), amounts.Quoted)
+		assert.Equal(t, models.MustParseMoney("150"), amounts.Exponent)
+	})
+
+	t.Run("JSON Rejects Sub-Cent Amounts", func(t *testing.T) {
+		var m models.Money
+		err := json.Unmarshal([]byte(`100.505`), &m)
+		assert.Error(t, err)
+	})
+
+	t.Run("JSON Rejects Out Of Range Amounts", func(t *testing.T) {
+		for _, raw := range []string{
+			`1e20000000`,
+			`-1e2000000`,
+			`1e21`,
+			`92233720368547758.07`,
+			`"92233720368547758.07"`,
+			`1000000000000.01`,
+		} {
+			var m models.Money
+			err := json.Unmarshal([]byte(raw), &m)
+			assert.ErrorIs(t, err, models.ErrAmountOutOfRange, raw)
+		}
+
+		var m models.Money
+		require.NoError(t, json.Unmarshal([]byte(`1e12`), &m))
+		assert.Equal(t, models.MaxAmount, m)
+		require.NoError(t, json.Unmarshal([]byte(`25e-2`), &m))
+		assert.Equal(t, "0.25", m.String())
+		require.NoError(t, json.Unmarshal([]byte(`1.500E+1`), &m))
+		assert.Equal(t, "15.00", m.String())
+		assert.Error(t, json.Unmarshal([]byte(`1e-3`), &m))
+	})
+
+	t.Run("Checked Arithmetic Detects Overflow", func(t *testing.T) {
+		largest := models.NewMoneyFromMinor(9223372036854775807)
+
+		_, err := largest.CheckedAdd(models.MustParseMoney("0.01"))
+		assert.ErrorIs(t, err, models.ErrAmountOutOfRange)
+		_, err = largest.Neg().CheckedSub(models.MustParseMoney("0.02"))
+		assert.ErrorIs(t, err, models.ErrAmountOutOfRange)
+
+		sum, err := largest.CheckedAdd(models.MustParseMoney("-0.07"))
+		require.NoError(t, err)
+		assert.Equal(t, "92233720368547758.00", sum.String())
+	})
+
+	t.Run("BSON Stores Decimal128", func(t *testing.T) {
+		data, err := bson.Marshal(bson.M{"amount": models.MustParseMoney("10.05")})
+		require.NoError(t, err)
+
+		var raw bson.M
+		require.NoError(t, bson.Unmarshal(data, &raw))
+		dec, ok := raw["amount"].(primitive.Decimal128)
+		require.True(t, ok)
+		assert.Equal(t, "10.05", dec.String())
+
+		var decoded struct {
+			Amount models.Money `bson:"amount"`
+		}
+		require.NoError(t, bson.Unmarshal(data, &decoded))
+		assert.Equal(t, models.MustParseMoney("10.05"), decoded.Amount)
+	})
+
+	t.Run("BSON Reads Legacy Numbers", func(t *testing.T) {
+		legacy := []struct {
+			name  string
+			value interface{}
+			want  string
+		}{
+			{"double", 0.1 + 0.2, "0.30"},
+			{"int32", int32(15), "15.00"},
+			{"int64", int64(-7), "-7.00"},
+		}
+
+		for _, tc := range legacy {
+			data, err := bson.Marshal(bson.M{"balance": tc.value})
+			require.NoError(t, err)
+
+			var account models.Accounts
+			require.NoError(t, bson.Unmarshal(data, &account), tc.name)
+			assert.Equal(t, models.MustParseMoney(tc.want), account.Balance, tc.name)
+		}
+	})
+}
+
+func TestMoneyLimits(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	ts.CleanupCollections(t, "accounts", "transactions")
+
+	account := &models.Accounts{Name: "John Doe", Email: "john@example.com"}
+	require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Huge Exponents Fail Fast", func(t *testing.T) {
+		start := time.Now()
+		w := post("/api/v1/transactions",
+			`{"transactionType":"DEPOSIT","amount":1e20000000,"accountId":"`+account.ID.Hex()+`"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = post("/api/v1/accounts", `{"name":"Jane","email":"jane@example.com","initialBalance":1e2000000}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("Amounts Beyond The Limit Are Rejected", func(t *testing.T) {
+		w := post("/api/v1/transactions",
+			`{"transactionType":"DEPOSIT","amount":92233720368547758.07,"accountId":"`+account.ID.Hex()+`"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		stored, err := ts.AccountsRepository.FindOne(context.Background(), account.ID.Hex())
+		require.NoError(t, err)
+		assert.True(t, stored.Balance.IsZero())
+	})
+}
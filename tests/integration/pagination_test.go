@@ -0,0 +1,131 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"finance_app/src/models"
+	"finance_app/src/utils/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaginationIntegration(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	// getPage fetches url and decodes the list response
+	getPage := func(t *testing.T, url string) ([]interface{}, string) {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		ts.Router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response types.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.True(t, response.Success)
+
+		items, ok := response.Data.([]interface{})
+		require.True(t, ok)
+		return items, response.NextCursor
+	}
+
+	// walk follows nextCursor until the last page and returns the IDs seen
+	walk := func(t *testing.T, path string, limit int) []string {
+		var ids []string
+		url := fmt.Sprintf("%s?limit=%d", path, limit)
+		for pages := 0; ; pages++ {
+			require.Less(t, pages, 100, "pagination did not terminate")
+
+			items, next := getPage(t, url)
+			assert.LessOrEqual(t, len(items), limit)
+			for _, item := range items {
+				ids = append(ids, item.(map[string]interface{})["id"].(string))
+			}
+
+			if next == "" {
+				return ids
+			}
+			url = fmt.Sprintf("%s?limit=%d&cursor=%s", path, limit, next)
+		}
+	}
+
+	t.Run("Walk Accounts", func(t *testing.T) {
+		ts.CleanupCollections(t, "accounts", "transactions")
+
+		var created []string
+		for i := 0; i < 7; i++ {
+			account := &models.Accounts{
+				Name:  fmt.Sprintf("Account %d", i),
+				Email: fmt.Sprintf("page%d@example.com", i),
+			}
+			require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+			created = append(created, account.ID.Hex())
+		}
+
+		// Oldest first, every account exactly once
+		assert.Equal(t, created, walk(t, "/api/v1/accounts", 3))
+
+		// A page that holds everything has no next cursor
+		items, next := getPage(t, "/api/v1/accounts?limit=7")
+		assert.Len(t, items, 7)
+		assert.Empty(t, next)
+	})
+
+	t.Run("Walk Transactions", func(t *testing.T) {
+		ts.CleanupCollections(t, "accounts", "transactions")
+
+		account := &models.Accounts{Name: "Pager", Email: "pager@example.com"}
+		require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+		other := &models.Accounts{Name: "Other", Email: "other@example.com"}
+		require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), other))
+
+		var created []string
+		for i := 0; i < 5; i++ {
+			for _, owner := range []*models.Accounts{account, other} {
+				transaction := &models.Transaction{
+					TransactionType: models.Deposit,
+					Amount:          models.MustParseMoney("1.00"),
+					AccountId:       owner.ID,
+				}
+				require.NoError(t, ts.TransactionRepository.Create(context.Background(), transaction))
+				if owner == account {
+					created = append(created, transaction.ID.Hex())
+				}
+			}
+		}
+
+		// Newest first
+		for i, j := 0, len(created)-1; i < j; i, j = i+1, j-1 {
+			created[i], created[j] = created[j], created[i]
+		}
+
+		assert.Equal(t, created, walk(t, "/api/v1/transactions/account/"+account.ID.Hex(), 2))
+		assert.Len(t, walk(t, "/api/v1/transactions", 4), 10)
+	})
+
+	t.Run("Invalid Page Parameters", func(t *testing.T) {
+		for _, query := range []string{"limit=0", "limit=201", "limit=ten", "cursor=not-a-cursor", "cursor=AAAA"} {
+			for _, path := range []string{"/api/v1/accounts", "/api/v1/transactions"} {
+				req := httptest.NewRequest("GET", path+"?"+query, nil)
+				w := httptest.NewRecorder()
+				ts.Router.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusBadRequest, w.Code, "%s?%s", path, query)
+
+				var response types.APIResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.False(t, response.Success)
+			}
+		}
+	})
+}
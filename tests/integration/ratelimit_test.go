@@ -0,0 +1,345 @@
+package integration
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"finance_app/src/auth"
+	"finance_app/src/config"
+	"finance_app/src/models"
+	"finance_app/src/ratelimit"
+	"finance_app/src/routes"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlidingWindow(t *testing.T) {
+	limiter := ratelimit.NewMemory()
+	rule := ratelimit.Rule{Requests: 2, Window: 200 * time.Millisecond}
+	ctx := context.Background()
+
+	first, err := limiter.Allow(ctx, "client", rule)
+	require.NoError(t, err)
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 1, first.Remaining)
+
+	time.Sleep(100 * time.Millisecond)
+	second, _ := limiter.Allow(ctx, "client", rule)
+	assert.True(t, second.Allowed)
+	assert.Equal(t, 0, second.Remaining)
+
+	denied, _ := limiter.Allow(ctx, "client", rule)
+	assert.False(t, denied.Allowed)
+	assert.LessOrEqual(t, denied.Reset, 100*time.Millisecond)
+
+	// Other clients have windows of their own
+	other, _ := limiter.Allow(ctx, "other", rule)
+	assert.True(t, other.Allowed)
+
+	// The first request leaves the window, freeing one slot but not two
+	time.Sleep(denied.Reset + 10*time.Millisecond)
+	again, _ := limiter.Allow(ctx, "client", rule)
+	assert.True(t, again.Allowed)
+	denied, _ = limiter.Allow(ctx, "client", rule)
+	assert.False(t, denied.Allowed)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	verifier, err := auth.NewVerifier(auth.Options{HMACSecret: []byte(testHMACSecret)})
+	require.NoError(t, err)
+
+	ts.CleanupCollections(t, "accounts", "transactions", "journal_entries", "idempotency_keys")
+	ts.Handler.Auth = verifier
+	ts.Handler.RateLimiter = ratelimit.NewMemory()
+	ts.Handler.RateLimits = ratelimit.Policy{
+		Default: ratelimit.Rule{Requests: 3, Window: time.Minute},
+		Routes: map[string]ratelimit.Rule{
+			"POST /api/v1/transactions": {Requests: 1, Window: time.Minute},
+		},
+	}
+	router := chi.NewRouter()
+	routes.Routes(router, ts.Handler, config.Default().Server)
+
+	send := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"transactionType":"DEPOSIT","amount":"1.00"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	alice := userToken(t, "alice")
+	bob := userToken(t, "bob")
+
+	t.Run("Headers And 429", func(t *testing.T) {
+		w := send("GET", "/api/v1/accounts", alice)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "3", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+		assert.Equal(t, "3;w=60", w.Header().Get("RateLimit-Policy"))
+
+		// Routes without a rule of their own share the default window
+		send("GET", "/api/v1/transactions", alice)
+		send("GET", "/api/v1/accounts/507f1f77bcf86cd799439011", alice)
+		w = send("GET", "/api/v1/accounts", alice)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+		retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+		require.NoError(t, err)
+		assert.Greater(t, retryAfter, 0)
+
+		// Bob is counted on his own
+		w = send("GET", "/api/v1/accounts", bob)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Tighter Transaction Limit", func(t *testing.T) {
+		w := send("POST", "/api/v1/transactions", bob)
+		assert.NotEqual(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+		w = send("POST", "/api/v1/transactions", bob)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+		// The default window still has room
+		w = send("GET", "/api/v1/accounts", bob)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Open Endpoints Are Not Limited", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			w := send("GET", "/api/v1/health", "")
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+		}
+	})
+
+	t.Run("Failed Authentication Is Limited Per IP", func(t *testing.T) {
+		ts.Handler.RateLimiter = ratelimit.NewMemory()
+		ts.Handler.RateLimits.PerIP = ratelimit.Rule{Requests: 3, Window: time.Minute}
+		router := chi.NewRouter()
+		routes.Routes(router, ts.Handler, config.Default().Server)
+
+		send := func(token, remoteAddr string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("GET", "/api/v1/accounts", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.RemoteAddr = remoteAddr
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		for i := 0; i < 3; i++ {
+			w := send("not-a-token", "203.0.113.9:4000")
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+
+		// Guessing stops once the address has used its window, even with a
+		// valid token
+		w := send("not-a-token", "203.0.113.9:4001")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+		w = send(userToken(t, "alice"), "203.0.113.9:4002")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+		// Other addresses are counted on their own
+		w = send(userToken(t, "alice"), "198.51.100.4:4000")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Client IP From Trusted Proxies Only", func(t *testing.T) {
+		ts.Handler.RateLimiter = ratelimit.NewMemory()
+		ts.Handler.RateLimits.PerIP = ratelimit.Rule{Requests: 1, Window: time.Minute}
+		server := config.Default().Server
+		server.TrustedProxies = []string{"10.0.0.0/8"}
+		router := chi.NewRouter()
+		routes.Routes(router, ts.Handler, server)
+
+		send := func(remoteAddr, forwardedFor string) int {
+			req := httptest.NewRequest("GET", "/api/v1/accounts", nil)
+			req.RemoteAddr = remoteAddr
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		// Behind the proxy each client is counted on its own
+		assert.Equal(t, http.StatusUnauthorized, send("10.1.2.3:5000", "203.0.113.7"))
+		assert.Equal(t, http.StatusUnauthorized, send("10.1.2.3:5000", "198.51.100.9"))
+		assert.Equal(t, http.StatusTooManyRequests, send("10.1.2.3:5000", "203.0.113.7"))
+
+		// Addresses the client added in front of its own are ignored
+		assert.Equal(t, http.StatusTooManyRequests, send("10.9.9.9:5000", "192.0.2.200, 203.0.113.7, 10.1.2.3"))
+
+		// Anyone else is counted by their own address, whatever they claim
+		assert.Equal(t, http.StatusUnauthorized, send("192.0.2.50:5000", "203.0.113.99"))
+		assert.Equal(t, http.StatusTooManyRequests, send("192.0.2.50:5000", "203.0.113.98"))
+	})
+
+	t.Run("Transactions Limited Per Account", func(t *testing.T) {
+		ts.Handler.RateLimiter = ratelimit.NewMemory()
+		ts.Handler.RateLimits.PerIP = ratelimit.Rule{}
+		ts.Handler.RateLimits.Routes = nil
+		ts.Handler.RateLimits.Default = ratelimit.Rule{Requests: 10, Window: time.Minute}
+		ts.Handler.RateLimits.PerAccount = ratelimit.Rule{Requests: 2, Window: time.Minute}
+		router := chi.NewRouter()
+		routes.Routes(router, ts.Handler, config.Default().Server)
+
+		send := func(token, accountID string) *httptest.ResponseRecorder {
+			body := `{"transactionType":"DEPOSIT","amount":"1.00","accountId":"` + accountID + `"}`
+			req := httptest.NewRequest("POST", "/api/v1/transactions", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+		openAccount := func(owner, email string) string {
+			account := &models.Accounts{Name: owner, Email: email, Owner: owner}
+			require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+			return account.ID.Hex()
+		}
+
+		bob := userToken(t, "bob")
+		admin := userToken(t, "ops", auth.RoleAdmin)
+
+		// Each client is under its own limit, but together they fill the
+		// account's window
+		account := openAccount("bob", "bob.shared@example.com")
+		assert.Equal(t, http.StatusCreated, send(bob, account).Code)
+		assert.Equal(t, http.StatusCreated, send(admin, account).Code)
+		w := send(bob, account)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+
+		// Other accounts have windows of their own
+		assert.Equal(t, http.StatusCreated, send(bob, openAccount("bob", "bob.other@example.com")).Code)
+
+		// Requests naming an account the caller may not use are refused
+		// before they count, so nobody can use up the window of an account
+		// that is not theirs
+		account = openAccount("bob", "bob.targeted@example.com")
+		mallory := userToken(t, "mallory")
+		for i := 0; i < 5; i++ {
+			w := send(mallory, account)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), "account not found")
+		}
+		assert.Equal(t, http.StatusCreated, send(bob, account).Code)
+		assert.Equal(t, http.StatusCreated, send(bob, account).Code)
+		assert.Equal(t, http.StatusTooManyRequests, send(bob, account).Code)
+	})
+
+	t.Run("Rate Limited Requests Are Not Replayed", func(t *testing.T) {
+		ts.Handler.RateLimiter = ratelimit.NewMemory()
+		ts.Handler.RateLimits.PerAccount = ratelimit.Rule{Requests: 1, Window: time.Minute}
+		router := chi.NewRouter()
+		routes.Routes(router, ts.Handler, config.Default().Server)
+
+		account := &models.Accounts{Name: "Bob", Email: "bob.replayed@example.com", Owner: "bob"}
+		require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+		bob := userToken(t, "bob")
+
+		send := func(key string) *httptest.ResponseRecorder {
+			body := `{"transactionType":"DEPOSIT","amount":"1.00","accountId":"` + account.ID.Hex() + `"}`
+			req := httptest.NewRequest("POST", "/api/v1/transactions", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+bob)
+			req.Header.Set("Idempotency-Key", key)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		require.Equal(t, http.StatusCreated, send("first").Code)
+		require.Equal(t, http.StatusTooManyRequests, send("second").Code)
+
+		// Once the window frees the key is used for real
+		ts.Handler.RateLimiter = ratelimit.NewMemory()
+		router = chi.NewRouter()
+		routes.Routes(router, ts.Handler, config.Default().Server)
+		w := send("second")
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+	})
+}
+
+func TestRateLimitFallback(t *testing.T) {
+	// Nothing listens on a port just released
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: addr, DialTimeout: 100 * time.Millisecond, ContextTimeoutEnabled: true})
+	defer client.Close()
+	limiter := &ratelimit.Fallback{
+		Primary:   ratelimit.NewRedis(client),
+		Secondary: ratelimit.NewMemory(),
+	}
+
+	rule := ratelimit.Rule{Requests: 1, Window: time.Minute}
+	result, err := limiter.Allow(context.Background(), "client", rule)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+	result, err = limiter.Allow(context.Background(), "client", rule)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
+
+// testRedis returns a client for TEST_REDIS_URL, or for an in-process
+// miniredis when it is not set
+func testRedis(t *testing.T) *redis.Client {
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		url = "redis://" + miniredis.RunT(t).Addr()
+	}
+	opts, err := redis.ParseURL(url)
+	require.NoError(t, err)
+	opts.ContextTimeoutEnabled = true
+	client := redis.NewClient(opts)
+	t.Cleanup(func() { client.Close() })
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("Skipping test: Redis not available at %s: %v", url, err)
+	}
+	return client
+}
+
+func TestRedisRateLimiter(t *testing.T) {
+	limiter := ratelimit.NewRedis(testRedis(t))
+	limiter.Prefix = "test:ratelimit:" + strconv.FormatInt(time.Now().UnixNano(), 10) + ":"
+	rule := ratelimit.Rule{Requests: 2, Window: time.Minute}
+	ctx := context.Background()
+
+	for i, allowed := range []bool{true, true, false} {
+		result, err := limiter.Allow(ctx, "client", rule)
+		require.NoError(t, err)
+		assert.Equal(t, allowed, result.Allowed, "request %d", i)
+		assert.LessOrEqual(t, result.Reset, time.Minute)
+	}
+
+	// Each key has a window of its own
+	result, err := limiter.Allow(ctx, "other", rule)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
@@ -0,0 +1,304 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"finance_app/src/auth"
+	"finance_app/src/config"
+	"finance_app/src/models"
+	"finance_app/src/routes"
+	"finance_app/src/utils/types"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionReversal(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	verifier, err := auth.NewVerifier(auth.Options{HMACSecret: []byte(testHMACSecret)})
+	require.NoError(t, err)
+
+	ts.CleanupCollections(t, "accounts", "transactions", "journal_entries", "idempotency_keys", "audit_events", "audit_outbox")
+	ts.Handler.Auth = verifier
+	router := chi.NewRouter()
+	routes.Routes(router, ts.Handler, config.Default().Server)
+
+	send := func(method, path, token string, body interface{}) (*httptest.ResponseRecorder, types.APIResponse) {
+		var payload bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&payload).Encode(body))
+		}
+		req := httptest.NewRequest(method, path, &payload)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response types.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return w, response
+	}
+
+	decode := func(data interface{}, into interface{}) {
+		raw, err := json.Marshal(data)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(raw, into))
+	}
+
+	admin := userToken(t, "admin", auth.RoleAdmin)
+	alice := userToken(t, "alice")
+	bob := userToken(t, "bob")
+
+	createAccount := func(token, name string) string {
+		w, response := send("POST", "/api/v1/accounts", token, map[string]interface{}{
+			"name": name, "email": name + "@example.com",
+		})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		id := response.Data.(map[string]interface{})["id"].(string)
+
+		// Customers open at zero and fund the account with a deposit
+		w, _ = send("POST", "/api/v1/transactions", token, map[string]interface{}{
+			"transactionType": "DEPOSIT", "amount": "100.00", "accountId": id,
+		})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		return id
+	}
+
+	// createTransaction returns the newest transaction of the caller's account
+	createTransaction := func(token string, body map[string]interface{}) models.Transaction {
+		w, response := send("POST", "/api/v1/transactions", token, body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var data struct {
+			Transactions []models.Transaction `json:"transactions"`
+		}
+		decode(response.Data, &data)
+		return data.Transactions[0]
+	}
+
+	balance := func(token, accountId string) string {
+		w, response := send("GET", "/api/v1/accounts/"+accountId, token, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var account models.Accounts
+		decode(response.Data, &account)
+		return account.Balance.String()
+	}
+
+	type reversalResult struct {
+		Reversal            models.Transaction `json:"reversal"`
+		Transaction         models.Transaction `json:"transaction"`
+		Account             models.Accounts    `json:"account"`
+		CounterpartyAccount *models.Accounts   `json:"counterpartyAccount"`
+	}
+
+	reverse := func(transactionId string, body interface{}) (*httptest.ResponseRecorder, reversalResult) {
+		w, response := send("POST", "/api/v1/transactions/"+transactionId+"/reverse", admin, body)
+		var result reversalResult
+		if w.Code == http.StatusCreated {
+			decode(response.Data, &result)
+		}
+		return w, result
+	}
+
+	aliceAccount := createAccount(alice, "alice")
+	bobAccount := createAccount(bob, "bob")
+
+	t.Run("Staff Only", func(t *testing.T) {
+		deposit := createTransaction(alice, map[string]interface{}{
+			"transactionType": "DEPOSIT", "amount": "10.00", "accountId": aliceAccount,
+		})
+
+		for _, token := range []string{alice, userToken(t, "olga", auth.RoleOperator)} {
+			w, _ := send("POST", "/api/v1/transactions/"+deposit.ID.Hex()+"/reverse", token, nil)
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		}
+
+		w, _ := reverse(deposit.ID.Hex(), nil)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, "100.00", balance(alice, aliceAccount))
+	})
+
+	t.Run("Full Reversal", func(t *testing.T) {
+		withdrawal := createTransaction(alice, map[string]interface{}{
+			"transactionType": "WITHDRAW", "amount": "30.00", "accountId": aliceAccount,
+		})
+		require.Equal(t, "70.00", balance(alice, aliceAccount))
+
+		w, result := reverse(withdrawal.ID.Hex(), nil)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, "100.00", result.Account.Balance.String())
+		assert.Equal(t, "100.00", balance(alice, aliceAccount))
+
+		assert.Equal(t, models.Reversal, result.Reversal.TransactionType)
+		assert.Equal(t, models.TransferIn, result.Reversal.Direction)
+		assert.Equal(t, "30.00", result.Reversal.Amount.String())
+		assert.Equal(t, "100.00", result.Reversal.Balance.String())
+		require.NotNil(t, result.Reversal.ReversalOf)
+		assert.Equal(t, withdrawal.ID, *result.Reversal.ReversalOf)
+
+		assert.Equal(t, models.FullyReversed, result.Transaction.ReversalStatus)
+		assert.Equal(t, "30.00", result.Transaction.ReversedAmount.String())
+		require.Len(t, result.Transaction.Reversals, 1)
+		assert.Equal(t, result.Reversal.ID, result.Transaction.Reversals[0].ID)
+
+		// A second reversal finds nothing left to give back
+		w, _ = reverse(withdrawal.ID.Hex(), nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		w, _ = reverse(withdrawal.ID.Hex(), map[string]interface{}{"amount": "1.00"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, "100.00", balance(alice, aliceAccount))
+
+		// Reversals cannot be reversed
+		w, _ = reverse(result.Reversal.ID.Hex(), nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Partial Refunds", func(t *testing.T) {
+		deposit := createTransaction(alice, map[string]interface{}{
+			"transactionType": "DEPOSIT", "amount": "50.00", "accountId": aliceAccount,
+		})
+
+		w, result := reverse(deposit.ID.Hex(), map[string]interface{}{"amount": "20.00"})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, models.TransferOut, result.Reversal.Direction)
+		assert.Equal(t, models.PartiallyReversed, result.Transaction.ReversalStatus)
+		assert.Equal(t, "20.00", result.Transaction.ReversedAmount.String())
+		assert.Equal(t, "130.00", balance(alice, aliceAccount))
+
+		// More than is left is refused and changes nothing
+		w, _ = reverse(deposit.ID.Hex(), map[string]interface{}{"amount": "30.01"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, "130.00", balance(alice, aliceAccount))
+
+		w, _ = reverse(deposit.ID.Hex(), map[string]interface{}{"amount": "-5.00"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// Without an amount the rest is given back
+		w, result = reverse(deposit.ID.Hex(), nil)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, "30.00", result.Reversal.Amount.String())
+		assert.Equal(t, models.FullyReversed, result.Transaction.ReversalStatus)
+		assert.Equal(t, "100.00", balance(alice, aliceAccount))
+
+		w, response := send("GET", "/api/v1/transactions/"+deposit.ID.Hex(), alice, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var chain models.Transaction
+		decode(response.Data, &chain)
+		assert.Equal(t, "50.00", chain.ReversedAmount.String())
+		require.Len(t, chain.Reversals, 2)
+		assert.Equal(t, "20.00", chain.Reversals[0].Amount.String())
+		assert.Equal(t, "30.00", chain.Reversals[1].Amount.String())
+		assert.Equal(t, "100.00", chain.Reversals[1].Balance.String())
+
+		// The reversal points back at what it gave back
+		w, response = send("GET", "/api/v1/transactions/"+chain.Reversals[0].ID.Hex(), alice, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var reversal models.Transaction
+		decode(response.Data, &reversal)
+		require.NotNil(t, reversal.ReversalOf)
+		assert.Equal(t, deposit.ID, *reversal.ReversalOf)
+		assert.Empty(t, reversal.Reversals)
+	})
+
+	t.Run("Insufficient Funds", func(t *testing.T) {
+		deposit := createTransaction(alice, map[string]interface{}{
+			"transactionType": "DEPOSIT", "amount": "40.00", "accountId": aliceAccount,
+		})
+		createTransaction(alice, map[string]interface{}{
+			"transactionType": "WITHDRAW", "amount": "130.00", "accountId": aliceAccount,
+		})
+
+		// The deposit was spent, so it cannot be taken back
+		w, _ := reverse(deposit.ID.Hex(), nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, response := send("GET", "/api/v1/transactions/"+deposit.ID.Hex(), alice, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var unchanged models.Transaction
+		decode(response.Data, &unchanged)
+		assert.Empty(t, unchanged.ReversalStatus)
+		assert.True(t, unchanged.ReversedAmount.IsZero())
+		assert.Empty(t, unchanged.Reversals)
+
+		createTransaction(alice, map[string]interface{}{
+			"transactionType": "DEPOSIT", "amount": "90.00", "accountId": aliceAccount,
+		})
+		require.Equal(t, "100.00", balance(alice, aliceAccount))
+	})
+
+	t.Run("Transfer Reversal", func(t *testing.T) {
+		outgoing := createTransaction(alice, map[string]interface{}{
+			"transactionType": "TRANSFER", "amount": "20.00", "accountId": aliceAccount, "toAccountId": bobAccount,
+		})
+		require.NotNil(t, outgoing.LinkedTransactionId)
+		incoming := outgoing.LinkedTransactionId.Hex()
+
+		// Reversing either side reverses both
+		w, result := reverse(incoming, map[string]interface{}{"amount": "5.00"})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, bobAccount, result.Account.ID.Hex())
+		assert.Equal(t, "115.00", result.Account.Balance.String())
+		require.NotNil(t, result.CounterpartyAccount)
+		assert.Equal(t, "85.00", result.CounterpartyAccount.Balance.String())
+		assert.Equal(t, models.TransferOut, result.Reversal.Direction)
+		require.NotNil(t, result.Reversal.LinkedTransactionId)
+
+		w, response := send("GET", "/api/v1/transactions/"+outgoing.ID.Hex(), alice, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var source models.Transaction
+		decode(response.Data, &source)
+		assert.Equal(t, models.PartiallyReversed, source.ReversalStatus)
+		require.Len(t, source.Reversals, 1)
+		assert.Equal(t, models.TransferIn, source.Reversals[0].Direction)
+		assert.Equal(t, *result.Reversal.LinkedTransactionId, source.Reversals[0].ID)
+		assert.Equal(t, result.Reversal.ID, *source.Reversals[0].LinkedTransactionId)
+
+		// Both sides share one reversed total
+		w, _ = reverse(outgoing.ID.Hex(), map[string]interface{}{"amount": "15.01"})
+		assert.Equal(t, http.StatusConflict, w.Code)
+		w, _ = reverse(outgoing.ID.Hex(), nil)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, "100.00", balance(alice, aliceAccount))
+		assert.Equal(t, "100.00", balance(bob, bobAccount))
+		w, _ = reverse(incoming, nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Unknown Transaction", func(t *testing.T) {
+		w, _ := reverse("507f1f77bcf86cd799439011", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w, _ = reverse("not-an-id", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Ledger And Audit", func(t *testing.T) {
+		w, response := send("GET", "/api/v1/ledger/invariants", admin, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.True(t, response.Success)
+
+		w, response = send("GET", "/api/v1/audit/events?actor=admin", admin, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var events []models.AuditEvent
+		decode(response.Data, &events)
+		require.NotEmpty(t, events)
+		for _, event := range events {
+			assert.Equal(t, models.AuditTransactionReversed, event.Action)
+			assert.Equal(t, "POST /api/v1/transactions/{id}/reverse", event.Endpoint)
+		}
+
+		// The last one is the transfer reversal, touching both accounts
+		require.Len(t, events[0].Balances, 2)
+		assert.Equal(t, "100.00", events[0].Balances[0].After.String())
+		assert.Equal(t, "100.00", events[0].Balances[1].After.String())
+	})
+}
@@ -0,0 +1,301 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"finance_app/src/config"
+	"finance_app/src/handlers"
+	"finance_app/src/models"
+	"finance_app/src/routes"
+	"finance_app/src/tracing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// exportedSpan is the part of a span written by the stdout exporter the tests
+// look at
+type exportedSpan struct {
+	Name        string
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	Parent struct {
+		SpanID string
+	}
+	SpanKind   int
+	Attributes []struct {
+		Key   string
+		Value struct {
+			Value interface{}
+		}
+	}
+	Status struct {
+		Code string
+	}
+}
+
+func (s exportedSpan) attribute(key attribute.Key) interface{} {
+	for _, kv := range s.Attributes {
+		if kv.Key == string(key) {
+			return kv.Value.Value
+		}
+	}
+	return nil
+}
+
+// decodeSpans reads spans from the stdout exporter, one JSON object each
+func decodeSpans(t *testing.T, r io.Reader) []exportedSpan {
+	var spans []exportedSpan
+	decoder := json.NewDecoder(r)
+	for decoder.More() {
+		var span exportedSpan
+		require.NoError(t, decoder.Decode(&span))
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// setupTracing installs the tracer provider for opts until the test ends
+func setupTracing(t *testing.T, opts tracing.Options) {
+	shutdown, err := tracing.Setup(opts)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		shutdown(context.Background())
+		tracing.Setup(tracing.Options{})
+	})
+}
+
+func TestTracingIntegration(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	var exported bytes.Buffer
+	setupTracing(t, tracing.Options{Exporter: tracing.ExporterStdout, Stdout: &exported})
+
+	// The suite's repositories are not traced, so wrap them as storage does.
+	// Cleanup replaces the in-memory repositories, so build it afterwards.
+	tracedRouter := func() chi.Router {
+		h := handlers.NewAppHandler(ts.Client, ts.Transactor,
+			tracing.TraceTransactions(ts.TransactionRepository, "test"),
+			tracing.TraceAccounts(ts.AccountsRepository, "test"),
+			ts.Handler.IdempotencyRepository, ts.LedgerRepository, ts.Handler.APIKeyRepository, ts.Handler.AuditRepository)
+		router := chi.NewRouter()
+		routes.Routes(router, h, config.Default().Server)
+		return router
+	}
+
+	t.Run("Request And Repository Spans", func(t *testing.T) {
+		ts.CleanupCollections(t, "accounts", "transactions")
+		router := tracedRouter()
+		account := &models.Accounts{Name: "Traced", Email: "traced@example.com"}
+		require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+		exported.Reset()
+
+		body, err := json.Marshal(map[string]interface{}{
+			"transactionType": "DEPOSIT",
+			"amount":          "12.50",
+			"accountId":       account.ID.Hex(),
+		})
+		require.NoError(t, err)
+
+		const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		req := httptest.NewRequest("POST", "/api/v1/transactions", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		spans := decodeSpans(t, &exported)
+		byName := map[string]exportedSpan{}
+		for _, span := range spans {
+			assert.Equal(t, traceID, span.SpanContext.TraceID, span.Name)
+			byName[span.Name] = span
+		}
+
+		// The server span continues the incoming trace and is named after the route
+		server, ok := byName["/api/v1/transactions"]
+		require.True(t, ok, "no server span in %v", spans)
+		assert.Equal(t, int(trace.SpanKindServer), server.SpanKind)
+		assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID)
+		assert.Equal(t, "POST", server.attribute("http.request.method"))
+		assert.Equal(t, float64(201), server.attribute("http.response.status_code"))
+		assert.Equal(t, account.ID.Hex(), server.attribute(tracing.AttrAccountID))
+		assert.Equal(t, "DEPOSIT", server.attribute(tracing.AttrTransactionType))
+
+		for _, name := range []string{"AccountRepository.FindOne", "AccountRepository.Credit", "TransactionRepository.Create"} {
+			span, ok := byName[name]
+			require.True(t, ok, "no %s span", name)
+			assert.Equal(t, server.SpanContext.SpanID, span.Parent.SpanID, name)
+			assert.Equal(t, int(trace.SpanKindClient), span.SpanKind, name)
+			assert.Equal(t, account.ID.Hex(), span.attribute(tracing.AttrAccountID), name)
+		}
+		assert.Equal(t, "DEPOSIT", byName["TransactionRepository.Create"].attribute(tracing.AttrTransactionType))
+	})
+
+	t.Run("Failed Repository Call", func(t *testing.T) {
+		ts.CleanupCollections(t, "accounts", "transactions")
+		router := tracedRouter()
+		exported.Reset()
+
+		req := httptest.NewRequest("GET", "/api/v1/accounts/64b7f0c2a1b2c3d4e5f60718", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.NotEqual(t, http.StatusOK, w.Code)
+
+		var found bool
+		for _, span := range decodeSpans(t, &exported) {
+			if span.Name == "AccountRepository.FindOne" {
+				found = true
+				assert.Equal(t, "Error", span.Status.Code)
+			}
+			if span.Name == "/api/v1/accounts/{id}" {
+				// A new trace, since no traceparent was sent
+				assert.Equal(t, trace.SpanID{}.String(), span.Parent.SpanID)
+			}
+		}
+		assert.True(t, found)
+	})
+}
+
+func TestTraceLogFields(t *testing.T) {
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetOutput(&out)
+	logger.AddHook(tracing.LogHook{})
+	setupTracing(t, tracing.Options{})
+
+	var logged map[string]interface{}
+	handler := middleware.RequestID(tracing.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.WithContext(r.Context()).Info("inside handler")
+	})))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.NoError(t, json.Unmarshal(out.Bytes(), &logged))
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", logged["trace_id"])
+	assert.Len(t, logged["span_id"], 16)
+	assert.NotEmpty(t, logged["request_id"])
+
+	// Entries without a context are left alone
+	out.Reset()
+	logger.Info("no context")
+	require.NoError(t, json.Unmarshal(out.Bytes(), &logged))
+	assert.NotContains(t, out.String(), "trace_id")
+}
+
+func TestTraceparent(t *testing.T) {
+	setupTracing(t, tracing.Options{})
+
+	var got trace.SpanContext
+	handler := tracing.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = trace.SpanContextFromContext(r.Context())
+	}))
+	serve := func(header string) trace.SpanContext {
+		got = trace.SpanContext{}
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("traceparent", header)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		return got
+	}
+
+	sc := serve("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+	assert.NotEqual(t, "00f067aa0ba902b7", sc.SpanID().String())
+	assert.True(t, sc.IsSampled())
+
+	// Malformed headers start a new trace
+	for _, header := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		sc := serve(header)
+		assert.True(t, sc.IsValid(), header)
+		assert.NotEqual(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String(), header)
+	}
+}
+
+func TestOTLPExport(t *testing.T) {
+	var mu sync.Mutex
+	var received []*tracepb.ResourceSpans
+	var paths []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		var request coltracepb.ExportTraceServiceRequest
+		require.NoError(t, proto.Unmarshal(body, &request))
+
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+		received = append(received, request.ResourceSpans...)
+	}))
+	defer collector.Close()
+
+	shutdown, err := tracing.Setup(tracing.Options{Exporter: tracing.ExporterOTLP, Endpoint: collector.URL, ServiceName: "finance_test"})
+	require.NoError(t, err)
+	t.Cleanup(func() { tracing.Setup(tracing.Options{}) })
+
+	ctx, parent := tracing.Start(context.Background(), "parent", trace.SpanKindInternal)
+	_, child := tracing.Start(ctx, "child", trace.SpanKindClient, tracing.AttrAccountID.String("abc"))
+	child.End()
+	parent.End()
+
+	// Shutdown flushes the batch without waiting for the interval
+	require.NoError(t, shutdown(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"/v1/traces"}, paths)
+	require.Len(t, received, 1)
+
+	var serviceName string
+	for _, kv := range received[0].Resource.Attributes {
+		if kv.Key == "service.name" {
+			serviceName = kv.Value.GetStringValue()
+		}
+	}
+	assert.Equal(t, "finance_test", serviceName)
+
+	require.Len(t, received[0].ScopeSpans, 1)
+	spans := received[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, spans[1].SpanId, spans[0].ParentSpanId)
+	require.Len(t, spans[0].Attributes, 1)
+	assert.Equal(t, string(tracing.AttrAccountID), spans[0].Attributes[0].Key)
+	assert.Equal(t, "abc", spans[0].Attributes[0].Value.GetStringValue())
+
+	_, err = tracing.Setup(tracing.Options{Exporter: "zipkin"})
+	assert.Error(t, err)
+}
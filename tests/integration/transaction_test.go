@@ -7,9 +7,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"finance_app/src/models"
+	"finance_app/src/repositories"
+	"finance_app/src/services"
 	"finance_app/src/utils/types"
 
 	"github.com/stretchr/testify/assert"
@@ -18,19 +22,19 @@ import (
 )
 
 func TestTransactionIntegration(t *testing.T) {
-	// Skip if MongoDB is not available
-	SkipIfNoMongo(t)
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
 
 	// Setup test suite
 	ts := SetupTestSuite(t)
 	defer ts.CleanupTestSuite(t)
 
 	// Helper function to create a test account
-	createTestAccount := func(name, email string, balance float64) *models.Accounts {
+	createTestAccount := func(name, email, balance string) *models.Accounts {
 		account := &models.Accounts{
 			Name:    name,
 			Email:   email,
-			Balance: balance,
+			Balance: models.MustParseMoney(balance),
 		}
 		err := ts.AccountsRepository.CreateAccount(context.Background(), account)
 		require.NoError(t, err)
@@ -42,7 +46,7 @@ func TestTransactionIntegration(t *testing.T) {
 		ts.CleanupCollections(t, "accounts", "transactions")
 
 		// Create test account
-		account := createTestAccount("John Doe", "john@example.com", 1000.0)
+		account := createTestAccount("John Doe", "john@example.com", "1000.00")
 		accountID := account.ID.Hex()
 
 		// Test data
@@ -92,6 +96,7 @@ func TestTransactionIntegration(t *testing.T) {
 		require.True(t, ok)
 		assert.Equal(t, "DEPOSIT", transaction["transactionType"])
 		assert.Equal(t, 500.0, transaction["amount"])
+		assert.Equal(t, 1500.0, transaction["balance"]) // balance after the deposit
 	})
 
 	t.Run("Create Withdraw Transaction", func(t *testing.T) {
@@ -99,7 +104,7 @@ func TestTransactionIntegration(t *testing.T) {
 		ts.CleanupCollections(t, "accounts", "transactions")
 
 		// Create test account
-		account := createTestAccount("John Doe", "john@example.com", 1000.0)
+		account := createTestAccount("John Doe", "john@example.com", "1000.00")
 		accountID := account.ID.Hex()
 
 		// Test data
@@ -172,7 +177,7 @@ func TestTransactionIntegration(t *testing.T) {
 		ts.CleanupCollections(t, "accounts", "transactions")
 
 		// Create test account
-		account := createTestAccount("John Doe", "john@example.com", 1000.0)
+		account := createTestAccount("John Doe", "john@example.com", "1000.00")
 		accountID := account.ID.Hex()
 
 		// Test with invalid transaction type
@@ -201,23 +206,382 @@ func TestTransactionIntegration(t *testing.T) {
 		assert.Contains(t, response.Error, "Invalid transaction type")
 	})
 
+	t.Run("Create Withdraw Transaction with Insufficient Funds", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions")
+
+		account := createTestAccount("John Doe", "john@example.com", "100.00")
+
+		transactionData := map[string]interface{}{
+			"transactionType": "WITHDRAW",
+			"amount":          150.0,
+			"accountId":       account.ID.Hex(),
+		}
+
+		jsonData, err := json.Marshal(transactionData)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/api/v1/transactions", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		ts.Router.ServeHTTP(w, req)
+
+		// Should be rejected without touching the balance or the history
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response types.APIResponse
+		err = json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "insufficient funds")
+
+		unchanged, err := ts.AccountsRepository.FindOne(context.Background(), account.ID.Hex())
+		require.NoError(t, err)
+		assert.Equal(t, models.MustParseMoney("100.00"), unchanged.Balance)
+
+		history, _, err := ts.TransactionRepository.GetByAccountID(context.Background(), account.ID.Hex(), repositories.TransactionQuery{})
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+
+	t.Run("Concurrent Withdrawals Never Overdraw", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions")
+
+		account := createTestAccount("John Doe", "john@example.com", "500.00")
+
+		transactionData := map[string]interface{}{
+			"transactionType": "WITHDRAW",
+			"amount":          100.0,
+			"accountId":       account.ID.Hex(),
+		}
+
+		jsonData, err := json.Marshal(transactionData)
+		require.NoError(t, err)
+
+		// Fire more withdrawals than the balance can cover, all at once
+		const attempts = 10
+		codes := make(chan int, attempts)
+		var wg sync.WaitGroup
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := httptest.NewRequest("POST", "/api/v1/transactions", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				ts.Router.ServeHTTP(w, req)
+				codes <- w.Code
+			}()
+		}
+		wg.Wait()
+		close(codes)
+
+		succeeded := 0
+		for code := range codes {
+			if code == http.StatusCreated {
+				succeeded++
+			} else {
+				assert.Equal(t, http.StatusBadRequest, code)
+			}
+		}
+
+		// Exactly five withdrawals fit and none of them were lost
+		assert.Equal(t, 5, succeeded)
+
+		final, err := ts.AccountsRepository.FindOne(context.Background(), account.ID.Hex())
+		require.NoError(t, err)
+		assert.True(t, final.Balance.IsZero())
+
+		history, _, err := ts.TransactionRepository.GetByAccountID(context.Background(), account.ID.Hex(), repositories.TransactionQuery{})
+		require.NoError(t, err)
+		assert.Len(t, history, 5)
+	})
+
+	t.Run("Concurrent Opposite Transfers Complete", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions")
+
+		alice := createTestAccount("Alice", "alice@example.com", "1000.00")
+		bob := createTestAccount("Bob", "bob@example.com", "1000.00")
+
+		transfer := func(from, to *models.Accounts) []byte {
+			jsonData, err := json.Marshal(map[string]interface{}{
+				"transactionType": "TRANSFER",
+				"amount":          10.0,
+				"accountId":       from.ID.Hex(),
+				"toAccountId":     to.ID.Hex(),
+			})
+			require.NoError(t, err)
+			return jsonData
+		}
+		bodies := [][]byte{transfer(alice, bob), transfer(bob, alice)}
+
+		// Transfers both ways between the same accounts, all at once, lock
+		// the two accounts in opposite orders unless the order is fixed
+		const attempts = 20
+		codes := make(chan int, attempts)
+		var wg sync.WaitGroup
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func(body []byte) {
+				defer wg.Done()
+				req := httptest.NewRequest("POST", "/api/v1/transactions", bytes.NewBuffer(body))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				ts.Router.ServeHTTP(w, req)
+				codes <- w.Code
+			}(bodies[i%2])
+		}
+		wg.Wait()
+		close(codes)
+
+		for code := range codes {
+			assert.Equal(t, http.StatusCreated, code)
+		}
+
+		// As many went each way, so both balances end where they started
+		for _, account := range []*models.Accounts{alice, bob} {
+			final, err := ts.AccountsRepository.FindOne(context.Background(), account.ID.Hex())
+			require.NoError(t, err)
+			assert.Equal(t, "1000.00", final.Balance.String())
+
+			history, _, err := ts.TransactionRepository.GetByAccountID(context.Background(), account.ID.Hex(), repositories.TransactionQuery{})
+			require.NoError(t, err)
+			assert.Len(t, history, attempts)
+		}
+	})
+
+	t.Run("Failed Transaction Rolls Back Every Write", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions")
+
+		account := createTestAccount("John Doe", "john@example.com", "1000.00")
+		accountID := account.ID.Hex()
+
+		err := ts.Transactor.WithTransaction(context.Background(), func(ctx context.Context) error {
+			if _, err := ts.AccountsRepository.Credit(ctx, accountID, models.MustParseMoney("500.00")); err != nil {
+				return err
+			}
+			if err := ts.TransactionRepository.Create(ctx, &models.Transaction{
+				TransactionType: models.Deposit,
+				Amount:          models.MustParseMoney("500.00"),
+				AccountId:       account.ID,
+			}); err != nil {
+				return err
+			}
+			// A later step fails, so both writes above must be undone
+			_, err := ts.AccountsRepository.Debit(ctx, accountID, models.MustParseMoney("5000.00"))
+			return err
+		})
+
+		var insufficient *repositories.InsufficientFundsError
+		require.ErrorAs(t, err, &insufficient)
+
+		unchanged, err := ts.AccountsRepository.FindOne(context.Background(), accountID)
+		require.NoError(t, err)
+		assert.Equal(t, models.MustParseMoney("1000.00"), unchanged.Balance)
+
+		history, _, err := ts.TransactionRepository.GetByAccountID(context.Background(), accountID, repositories.TransactionQuery{})
+		require.NoError(t, err)
+		assert.Empty(t, history)
+	})
+
+	t.Run("Create Transfer Transaction", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions")
+
+		// Create source and destination accounts
+		source := createTestAccount("John Doe", "john@example.com", "1000.00")
+		destination := createTestAccount("Jane Smith", "jane@example.com", "200.00")
+
+		transactionData := map[string]interface{}{
+			"transactionType": "TRANSFER",
+			"amount":          250.0,
+			"accountId":       source.ID.Hex(),
+			"toAccountId":     destination.ID.Hex(),
+		}
+
+		jsonData, err := json.Marshal(transactionData)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/api/v1/transactions", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		ts.Router.ServeHTTP(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var response types.APIResponse
+		err = json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		assert.True(t, response.Success)
+
+		// Verify both balances moved
+		data, ok := response.Data.(map[string]interface{})
+		require.True(t, ok)
+		accountData, ok := data["account"].(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, 750.0, accountData["balance"]) // 1000 - 250
+		counterpartyData, ok := data["counterpartyAccount"].(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, 450.0, counterpartyData["balance"]) // 200 + 250
+
+		// Verify the source side records the counterparty
+		transactions, ok := data["transactions"].([]interface{})
+		require.True(t, ok)
+		require.Len(t, transactions, 1)
+		outgoing, ok := transactions[0].(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, "TRANSFER", outgoing["transactionType"])
+		assert.Equal(t, "OUT", outgoing["direction"])
+		assert.Equal(t, destination.ID.Hex(), outgoing["counterpartyAccountId"])
+
+		// Verify the destination side has the linked record
+		incomingList, _, err := ts.TransactionRepository.GetByAccountID(context.Background(), destination.ID.Hex(), repositories.TransactionQuery{})
+		require.NoError(t, err)
+		require.Len(t, incomingList, 1)
+		incoming := incomingList[0]
+		assert.Equal(t, models.TransferIn, incoming.Direction)
+		assert.Equal(t, source.ID, *incoming.CounterpartyAccountId)
+		assert.Equal(t, outgoing["id"], incoming.LinkedTransactionId.Hex())
+		assert.Equal(t, outgoing["linkedTransactionId"], incoming.ID.Hex())
+
+		// Each side records its own post-transfer balance
+		assert.Equal(t, 750.0, outgoing["balance"])
+		assert.Equal(t, models.MustParseMoney("450.00"), incoming.Balance)
+	})
+
+	t.Run("Create Transfer with Insufficient Funds", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions")
+
+		source := createTestAccount("John Doe", "john@example.com", "100.00")
+		destination := createTestAccount("Jane Smith", "jane@example.com", "0.00")
+
+		transactionData := map[string]interface{}{
+			"transactionType": "TRANSFER",
+			"amount":          150.0,
+			"accountId":       source.ID.Hex(),
+			"toAccountId":     destination.ID.Hex(),
+		}
+
+		jsonData, err := json.Marshal(transactionData)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/api/v1/transactions", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		ts.Router.ServeHTTP(w, req)
+
+		// Should return error and leave both balances alone
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response types.APIResponse
+		err = json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "insufficient funds")
+
+		unchanged, err := ts.AccountsRepository.FindOne(context.Background(), destination.ID.Hex())
+		require.NoError(t, err)
+		assert.Equal(t, models.MustParseMoney("0.00"), unchanged.Balance)
+	})
+
+	t.Run("Create Transfer to Same Account", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions")
+
+		account := createTestAccount("John Doe", "john@example.com", "1000.00")
+
+		transactionData := map[string]interface{}{
+			"transactionType": "TRANSFER",
+			"amount":          100.0,
+			"accountId":       account.ID.Hex(),
+			"toAccountId":     account.ID.Hex(),
+		}
+
+		jsonData, err := json.Marshal(transactionData)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest("POST", "/api/v1/transactions", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		ts.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response types.APIResponse
+		err = json.Unmarshal(w.Body.Bytes(), &response)
+		require.NoError(t, err)
+		assert.False(t, response.Success)
+		assert.Contains(t, response.Error, "cannot transfer to the same account")
+	})
+
+	t.Run("Repair Running Balances", func(t *testing.T) {
+		// Clean up collections before test
+		ts.CleanupCollections(t, "accounts", "transactions")
+
+		// History written without running balances, current balance 1300
+		account := createTestAccount("John Doe", "john@example.com", "1300.00")
+		history := []*models.Transaction{
+			{TransactionType: models.Deposit, Amount: models.MustParseMoney("500.00"), AccountId: account.ID},
+			{TransactionType: models.Withdraw, Amount: models.MustParseMoney("200.00"), AccountId: account.ID},
+		}
+		for _, transaction := range history {
+			err := ts.TransactionRepository.Create(context.Background(), transaction)
+			require.NoError(t, err)
+			time.Sleep(5 * time.Millisecond) // distinct created_at values
+		}
+
+		repairer := &services.BalanceRepairer{
+			TransactionsRepo: ts.TransactionRepository,
+			AccountsRepo:     ts.AccountsRepository,
+			Transactor:       ts.Transactor,
+		}
+		report, err := repairer.RepairRunningBalances(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Accounts)
+		assert.Equal(t, 2, report.Transactions)
+		assert.Equal(t, 2, report.Updated)
+
+		// Opening balance 1000, then +500 and -200
+		deposit, err := ts.TransactionRepository.GetByID(context.Background(), history[0].ID.Hex())
+		require.NoError(t, err)
+		assert.Equal(t, models.MustParseMoney("1500.00"), deposit.Balance)
+
+		withdrawal, err := ts.TransactionRepository.GetByID(context.Background(), history[1].ID.Hex())
+		require.NoError(t, err)
+		assert.Equal(t, models.MustParseMoney("1300.00"), withdrawal.Balance)
+
+		// Running it again finds nothing to change
+		report, err = repairer.RepairRunningBalances(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.Updated)
+	})
+
 	t.Run("Get All Transactions", func(t *testing.T) {
 		// Clean up collections before test
 		ts.CleanupCollections(t, "accounts", "transactions")
 
 		// Create test account
-		account := createTestAccount("John Doe", "john@example.com", 1000.0)
+		account := createTestAccount("John Doe", "john@example.com", "1000.00")
 
 		// Create test transactions
 		transactions := []*models.Transaction{
 			{
 				TransactionType: models.Deposit,
-				Amount:          500.0,
+				Amount:          models.MustParseMoney("500.00"),
 				AccountId:       account.ID,
 			},
 			{
 				TransactionType: models.Withdraw,
-				Amount:          200.0,
+				Amount:          models.MustParseMoney("200.00"),
 				AccountId:       account.ID,
 			},
 		}
@@ -253,10 +617,10 @@ func TestTransactionIntegration(t *testing.T) {
 		ts.CleanupCollections(t, "accounts", "transactions")
 
 		// Create test account and transaction
-		account := createTestAccount("John Doe", "john@example.com", 1000.0)
+		account := createTestAccount("John Doe", "john@example.com", "1000.00")
 		transaction := &models.Transaction{
 			TransactionType: models.Deposit,
-			Amount:          500.0,
+			Amount:          models.MustParseMoney("500.00"),
 			AccountId:       account.ID,
 		}
 		err := ts.TransactionRepository.Create(context.Background(), transaction)
@@ -291,19 +655,19 @@ func TestTransactionIntegration(t *testing.T) {
 		ts.CleanupCollections(t, "accounts", "transactions")
 
 		// Create test account
-		account := createTestAccount("John Doe", "john@example.com", 1000.0)
+		account := createTestAccount("John Doe", "john@example.com", "1000.00")
 		accountID := account.ID.Hex()
 
 		// Create test transactions
 		transactions := []*models.Transaction{
 			{
 				TransactionType: models.Deposit,
-				Amount:          500.0,
+				Amount:          models.MustParseMoney("500.00"),
 				AccountId:       account.ID,
 			},
 			{
 				TransactionType: models.Withdraw,
-				Amount:          200.0,
+				Amount:          models.MustParseMoney("200.00"),
 				AccountId:       account.ID,
 			},
 		}
@@ -380,3 +744,149 @@ func TestTransactionIntegration(t *testing.T) {
 		assert.Contains(t, response.Error, "account ID cannot be empty")
 	})
 }
+
+func TestTransactionFilterIntegration(t *testing.T) {
+	// Skip if the storage backend is not available
+	SkipIfNoBackend(t)
+
+	// Setup test suite
+	ts := SetupTestSuite(t)
+	defer ts.CleanupTestSuite(t)
+
+	ts.CleanupCollections(t, "accounts", "transactions")
+
+	account := &models.Accounts{Name: "Filter", Email: "filter@example.com"}
+	require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), account))
+	other := &models.Accounts{Name: "Other", Email: "filter-other@example.com"}
+	require.NoError(t, ts.AccountsRepository.CreateAccount(context.Background(), other))
+
+	// Seed a mix of types and amounts on both accounts
+	seed := []struct {
+		owner           *models.Accounts
+		transactionType models.TransactionType
+		amount          string
+	}{
+		{account, models.Deposit, "100.00"},
+		{account, models.Withdraw, "25.50"},
+		{account, models.Deposit, "10.00"},
+		{account, models.Deposit, "250.00"},
+		{other, models.Deposit, "75.00"},
+		{account, models.Withdraw, "75.00"},
+	}
+	ids := map[string]string{}
+	for _, s := range seed {
+		transaction := &models.Transaction{
+			TransactionType: s.transactionType,
+			Amount:          models.MustParseMoney(s.amount),
+			AccountId:       s.owner.ID,
+		}
+		require.NoError(t, ts.TransactionRepository.Create(context.Background(), transaction))
+		ids[transaction.ID.Hex()] = s.amount
+	}
+
+	// list returns the amounts of the listed transactions in response order
+	list := func(t *testing.T, url string) []string {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		ts.Router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response types.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+		amounts := []string{}
+		for _, item := range response.Data.([]interface{}) {
+			amounts = append(amounts, ids[item.(map[string]interface{})["id"].(string)])
+		}
+		return amounts
+	}
+
+	accountPath := "/api/v1/transactions/account/" + account.ID.Hex()
+
+	t.Run("Filter by Type", func(t *testing.T) {
+		assert.Equal(t, []string{"75.00", "25.50"}, list(t, accountPath+"?type=WITHDRAW"))
+		assert.Equal(t, []string{"250.00", "10.00", "100.00"}, list(t, accountPath+"?type=deposit"))
+		assert.Len(t, list(t, "/api/v1/transactions?type=DEPOSIT"), 4)
+		assert.Empty(t, list(t, "/api/v1/transactions?type=TRANSFER"))
+	})
+
+	t.Run("Filter by Amount", func(t *testing.T) {
+		assert.Equal(t, []string{"75.00", "25.50", "100.00"}, list(t, accountPath+"?minAmount=25.50&maxAmount=100"))
+		assert.Equal(t, []string{"75.00", "75.00"}, list(t, "/api/v1/transactions?minAmount=75&maxAmount=75"))
+	})
+
+	t.Run("Filter by Date", func(t *testing.T) {
+		now := time.Now().UTC()
+		from := now.Add(-time.Hour).Format(time.RFC3339)
+		to := now.Add(time.Hour).Format(time.RFC3339)
+		assert.Len(t, list(t, accountPath+"?from="+from+"&to="+to), 5)
+
+		today := now.Format("2006-01-02")
+		assert.Len(t, list(t, accountPath+"?from="+today+"&to="+today), 5)
+
+		yesterday := now.AddDate(0, 0, -1).Format("2006-01-02")
+		assert.Empty(t, list(t, accountPath+"?to="+yesterday))
+	})
+
+	t.Run("Sort by Amount", func(t *testing.T) {
+		assert.Equal(t, []string{"10.00", "25.50", "75.00", "100.00", "250.00"}, list(t, accountPath+"?sort=amount&order=asc"))
+		assert.Equal(t, []string{"250.00", "100.00", "75.00", "25.50", "10.00"}, list(t, accountPath+"?sort=amount"))
+	})
+
+	t.Run("Page Through Sorted Results", func(t *testing.T) {
+		var amounts []string
+		url := accountPath + "?sort=amount&order=asc&limit=2"
+		for {
+			req := httptest.NewRequest("GET", url, nil)
+			w := httptest.NewRecorder()
+			ts.Router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var response types.APIResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			for _, item := range response.Data.([]interface{}) {
+				amounts = append(amounts, ids[item.(map[string]interface{})["id"].(string)])
+			}
+
+			if response.NextCursor == "" {
+				break
+			}
+			url = accountPath + "?sort=amount&order=asc&limit=2&cursor=" + response.NextCursor
+
+			// A cursor only continues the order it was issued for
+			req = httptest.NewRequest("GET", accountPath+"?limit=2&cursor="+response.NextCursor, nil)
+			w = httptest.NewRecorder()
+			ts.Router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		}
+		assert.Equal(t, []string{"10.00", "25.50", "75.00", "100.00", "250.00"}, amounts)
+	})
+
+	t.Run("Invalid Filters", func(t *testing.T) {
+		for _, query := range []string{
+			"type=REFUND",
+			"from=yesterday",
+			"to=2024-13-01",
+			"from=2024-02-01&to=2024-01-01",
+			"minAmount=abc",
+			"minAmount=-5",
+			"minAmount=10&maxAmount=5",
+			"maxAmount=1.234",
+			"sort=balance",
+			"order=sideways",
+		} {
+			for _, path := range []string{"/api/v1/transactions", accountPath} {
+				req := httptest.NewRequest("GET", path+"?"+query, nil)
+				w := httptest.NewRecorder()
+				ts.Router.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusBadRequest, w.Code, "%s?%s", path, query)
+
+				var response types.APIResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.False(t, response.Success)
+				assert.NotEmpty(t, response.Error)
+			}
+		}
+	})
+}